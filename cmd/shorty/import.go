@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"shorty/internal/storage"
+	"shorty/internal/storage/transfer"
+	"strings"
+)
+
+// importCommand is the name of the subcommand that imports links from a file
+const importCommand = "import"
+
+// Importer saves imported links
+type Importer interface {
+	ImportLinks(links []storage.Link, policy storage.ConflictPolicy) (storage.ImportResult, error)
+}
+
+// runImport imports the links of a CSV or NDJSON file into a workspace, "-" reads standard input.
+// The format is taken from the file extension unless set with -format
+func runImport(args []string, importer Importer, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet(importCommand, flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	formatName := flags.String("format", "", "input format: csv or ndjson")
+	conflict := flags.String("on-conflict", string(storage.ConflictFail), "taken aliases: skip, overwrite or fail")
+	workspace := flags.Int64("workspace", storage.DefaultWorkspace, "id of the workspace the links are imported into")
+	flags.Usage = func() {
+		fmt.Fprintf(stdout, "usage: shorty %s [-format csv|ndjson] [-on-conflict skip|overwrite|fail] [-workspace id] <file|->\n", importCommand)
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+
+		return errors.New("expected exactly one input file")
+	}
+	path := flags.Arg(0)
+
+	if *formatName == "" {
+		*formatName = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	format, err := transfer.ParseFormat(*formatName)
+	if err != nil {
+		return fmt.Errorf("%w, set -format", err)
+	}
+
+	policy, err := transfer.ParseConflictPolicy(*conflict)
+	if err != nil {
+		return err
+	}
+
+	input := stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("open input: %w", err)
+		}
+		defer file.Close()
+
+		input = file
+	}
+
+	links, err := transfer.ReadAll(input, format)
+	if err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+
+	for i := range links {
+		links[i].WorkspaceID = *workspace
+	}
+
+	result, err := importer.ImportLinks(links, policy)
+	if err != nil {
+		return fmt.Errorf("import links: %w", err)
+	}
+
+	fmt.Fprintf(stdout, "created: %d, overwritten: %d, skipped: %d\n", result.Created, result.Overwritten, result.Skipped)
+
+	return nil
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"os"
 	"shorty/internal/config"
+	"shorty/internal/liveness"
+	"shorty/internal/pkg/geoip"
 	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/urlpolicy"
 	"shorty/internal/server"
 	"shorty/internal/storage/sqlite"
 )
@@ -24,7 +28,46 @@ func main() {
 		os.Exit(1)
 	}
 
-	router := server.SetupRouter(storage, *cfg, log)
+	policy, err := setupDestinationPolicy(cfg.DestinationPolicy, log)
+	if err != nil {
+		log.Error("failed to init destination policy", slo.Err(err))
+		os.Exit(1)
+	}
+	opts := []server.Option{server.WithDestinationPolicy(policy)}
+
+	if len(os.Args) > 1 && os.Args[1] == importCommand {
+		//imported links pass the same checks as links imported through the api
+		importer := server.NewImporter(storage, *cfg, log, opts...)
+		err = runImport(os.Args[2:], importer, os.Stdin, os.Stdout)
+		if err != nil {
+			log.Error("failed to import links", slo.Err(err))
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if cfg.GeoIP.DatabasePath != "" {
+		geo, err := geoip.Open(cfg.GeoIP.DatabasePath)
+		if err != nil {
+			log.Error("failed to init geoip", slo.Err(err))
+			os.Exit(1)
+		}
+		defer geo.Close()
+
+		opts = append(opts, server.WithGeoIP(geo))
+	}
+
+	if cfg.Liveness.Enabled {
+		checker := liveness.New(storage, nil, liveness.Options{
+			Interval:    cfg.Liveness.Interval,
+			Concurrency: cfg.Liveness.Concurrency,
+			Timeout:     cfg.Liveness.Timeout,
+		}, log)
+		go checker.Run(context.Background())
+	}
+
+	router := server.SetupRouter(storage, *cfg, log, opts...)
 	log.Info("starting server", slog.String("address", cfg.HTTPServer.Address))
 
 	//TODO: add graceful shutdown
@@ -50,6 +93,29 @@ func setupLogger(env string) *slog.Logger {
 	return log
 }
 
+// setupDestinationPolicy builds the policy of link targets and starts watching the blocklist file
+func setupDestinationPolicy(cfg config.DestinationPolicy, log *slog.Logger) (*urlpolicy.Policy, error) {
+	opts := urlpolicy.Options{
+		Schemes:        cfg.AllowedSchemes,
+		AllowedDomains: cfg.AllowedDomains,
+		DeniedDomains:  cfg.DeniedDomains,
+	}
+
+	if cfg.BlocklistPath != "" {
+		blocklist, err := urlpolicy.LoadBlocklist(cfg.BlocklistPath)
+		if err != nil {
+			return nil, err
+		}
+
+		go blocklist.Watch(context.Background(), cfg.BlocklistReloadInterval, func(err error) {
+			log.Error("failed to reload blocklist", slog.String("path", cfg.BlocklistPath), slo.Err(err))
+		})
+		opts.Blocklist = blocklist
+	}
+
+	return urlpolicy.New(opts), nil
+}
+
 func startHTTPServer(cfg *config.Config, router http.Handler) *http.Server {
 	return &http.Server{
 		Addr:         cfg.HTTPServer.Address,
@@ -11,7 +11,19 @@ import (
 type Config struct {
 	Environment string `yaml:"env"`
 	StoragePath string `yaml:"storage_path"`
-	HTTPServer  `yaml:"http_server"`
+	//BaseURL is the public prefix short links are served under, e.g. "https://sho.rt/v1"
+	BaseURL string `yaml:"base_url" env:"BASE_URL"`
+
+	HTTPServer        `yaml:"http_server"`
+	Lockout           `yaml:"password_lockout"`
+	GeoIP             `yaml:"geoip"`
+	Idempotency       `yaml:"idempotency"`
+	URLNormalization  `yaml:"url_normalization"`
+	DestinationPolicy `yaml:"destination_policy"`
+	RedirectChains    `yaml:"redirect_chains"`
+	Liveness          `yaml:"liveness"`
+	Preview           `yaml:"preview"`
+	UserQuota         `yaml:"user_quota"`
 }
 
 type HTTPServer struct {
@@ -20,6 +32,68 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout"`
 	User        string        `yaml:"user"`
 	Password    string        `yaml:"password" env:"HTTP_SERVER_PASSWORD"`
+	//CredentialsTTL is how long verified credentials of workspace users are remembered,
+	//a changed password or quota takes effect after it. 0 verifies the password on every request
+	CredentialsTTL time.Duration `yaml:"credentials_ttl" env-default:"1m"`
+}
+
+// Lockout limits password attempts of a protected link
+type Lockout struct {
+	MaxAttempts int           `yaml:"max_attempts" env-default:"5"`
+	Window      time.Duration `yaml:"window" env-default:"15m"`
+	Duration    time.Duration `yaml:"duration" env-default:"15m"`
+}
+
+// GeoIP enables country lookups in a local MaxMind-format database, an empty path disables them
+type GeoIP struct {
+	DatabasePath string `yaml:"database_path" env:"GEOIP_DATABASE_PATH"`
+}
+
+// Idempotency keeps responses of link creation requests with an Idempotency-Key for the window
+type Idempotency struct {
+	Window time.Duration `yaml:"window" env-default:"24h"`
+}
+
+// URLNormalization configures the canonical form of target urls
+type URLNormalization struct {
+	StripTrackingParams bool `yaml:"strip_tracking_params" env-default:"false"`
+}
+
+// DestinationPolicy restricts link targets, domain patterns are exact hosts, "*.example.com" or "*"
+type DestinationPolicy struct {
+	AllowedSchemes []string `yaml:"allowed_schemes" env-default:"http,https"`
+	AllowedDomains []string `yaml:"allowed_domains"`
+	DeniedDomains  []string `yaml:"denied_domains"`
+	//BlocklistPath is a file of denied domain patterns, one per line, reloaded when it changes
+	BlocklistPath           string        `yaml:"blocklist_path" env:"DESTINATION_BLOCKLIST_PATH"`
+	BlocklistReloadInterval time.Duration `yaml:"blocklist_reload_interval" env-default:"30s"`
+}
+
+// RedirectChains limits links to other short links of the service, an empty base url disables the checks
+type RedirectChains struct {
+	//MaxDepth is the number of short links a redirect may pass through, 0 forbids targets on the base url
+	MaxDepth int `yaml:"max_depth" env-default:"3"`
+}
+
+// Liveness enables background HEAD requests to link urls that flag links with failing targets
+type Liveness struct {
+	Enabled     bool          `yaml:"enabled" env-default:"false"`
+	Interval    time.Duration `yaml:"interval" env-default:"1h"`
+	Concurrency int           `yaml:"concurrency" env-default:"4"`
+	Timeout     time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+// Preview shows visitors the destination of a link before the redirect
+type Preview struct {
+	//AllLinks previews every link, otherwise only links created with the preview flag
+	AllLinks bool `yaml:"all_links" env-default:"false"`
+}
+
+// UserQuota caps the links every api user creates, 0 leaves a cap open
+type UserQuota struct {
+	//DailyLinks is reset at midnight UTC
+	DailyLinks int64 `yaml:"daily_links" env-default:"0"`
+	TotalLinks int64 `yaml:"total_links" env-default:"0"`
 }
 
 func InitConfig() *Config {
@@ -0,0 +1,198 @@
+package liveness
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/storage"
+	"sync"
+	"time"
+)
+
+// Store is the storage of the links to be checked
+type Store interface {
+	ListWorkspaces() ([]storage.Workspace, error)
+	EachLink(workspaceID int64, fn func(link storage.Link) error) error
+	SaveLinkHealth(linkID int64, health storage.Health) error
+}
+
+// Options of the checker
+type Options struct {
+	// Interval is the pause between two passes over all links
+	Interval time.Duration
+	// Concurrency is the number of links checked at once
+	Concurrency int
+	// Timeout limits a single check, including redirects
+	Timeout time.Duration
+}
+
+// Checker requests link urls in the background and records whether they respond
+type Checker struct {
+	store  Store
+	client *http.Client
+	opts   Options
+	log    *slog.Logger
+}
+
+// New returns a checker that uses the client for requests, http.DefaultClient if it is nil
+func New(store Store, client *http.Client, opts Options, log *slog.Logger) *Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+
+	return &Checker{
+		store:  store,
+		client: client,
+		opts:   opts,
+		log:    log,
+	}
+}
+
+// Run checks all links every interval until the context is done, the first pass starts immediately
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := c.CheckAll(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			c.log.Error("failed to check links", slo.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll checks the url of every link of every workspace once and saves the results
+func (c *Checker) CheckAll(ctx context.Context) error {
+	workspaces, err := c.store.ListWorkspaces()
+	if err != nil {
+		return err
+	}
+
+	//the default workspace is not stored
+	ids := []int64{storage.DefaultWorkspace}
+	for _, workspace := range workspaces {
+		ids = append(ids, workspace.ID)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		broken int
+		mu     sync.Mutex
+	)
+	slots := make(chan struct{}, c.opts.Concurrency)
+
+	check := func(link storage.Link) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case slots <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			health := c.Check(ctx, link.URL)
+			if ctx.Err() != nil {
+				return
+			}
+
+			if health.Broken() {
+				c.log.Info("link target is broken", slog.String("alias", link.Alias), slog.String("url", link.URL),
+					slog.Int("status", health.Status), slog.String("error", health.Error))
+				mu.Lock()
+				broken++
+				mu.Unlock()
+			}
+
+			err := c.store.SaveLinkHealth(link.ID, health)
+			if err != nil {
+				c.log.Error("failed to save link health", slog.String("alias", link.Alias), slo.Err(err))
+			}
+		}()
+
+		return nil
+	}
+
+	for _, id := range ids {
+		err = c.store.EachLink(id, check)
+		if err != nil {
+			break
+		}
+	}
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+
+	c.log.Info("links checked", slog.Int("broken", broken))
+
+	return nil
+}
+
+// Check requests the target with HEAD, servers that do not support HEAD are requested with GET
+func (c *Checker) Check(ctx context.Context, target string) storage.Health {
+	if c.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	status, err := c.request(ctx, http.MethodHead, target)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.request(ctx, http.MethodGet, target)
+	}
+
+	health := storage.Health{
+		Status:    status,
+		Latency:   time.Since(start),
+		CheckedAt: time.Now(),
+	}
+
+	if err != nil {
+		health.Error = err.Error()
+	}
+
+	return health
+}
+
+func (c *Checker) request(ctx context.Context, method string, target string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "shorty-liveness/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	//the body of a GET is not needed, a small part is drained to let the connection be reused
+	_, _ = io.CopyN(io.Discard, resp.Body, 4096)
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,156 @@
+package liveness
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/storage"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	workspaces []storage.Workspace
+	links      []storage.Link
+
+	mu     sync.Mutex
+	health map[int64]storage.Health
+}
+
+func (s *fakeStore) ListWorkspaces() ([]storage.Workspace, error) {
+	return s.workspaces, nil
+}
+
+func (s *fakeStore) EachLink(workspaceID int64, fn func(link storage.Link) error) error {
+	for _, link := range s.links {
+		if link.WorkspaceID != workspaceID {
+			continue
+		}
+
+		err := fn(link)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *fakeStore) SaveLinkHealth(linkID int64, health storage.Health) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.health == nil {
+		s.health = make(map[int64]storage.Health)
+	}
+	s.health[linkID] = health
+
+	return nil
+}
+
+func TestCheckAll(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/head-not-allowed":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			http.Redirect(w, r, "/gone", http.StatusMovedPermanently)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	store := &fakeStore{
+		workspaces: []storage.Workspace{{ID: 1, Name: "marketing"}},
+		links: []storage.Link{
+			{ID: 1, Alias: "okkkk", URL: srv.URL + "/ok"},
+			{ID: 2, Alias: "headx", URL: srv.URL + "/head-not-allowed"},
+			{ID: 3, Alias: "moved", URL: srv.URL + "/moved"},
+			{ID: 4, Alias: "missi", URL: srv.URL + "/missing"},
+			{ID: 5, Alias: "slowx", URL: srv.URL + "/slow"},
+			{ID: 6, Alias: "refus", URL: "http://127.0.0.1:1/"},
+			{ID: 7, WorkspaceID: 1, Alias: "mrktg", URL: srv.URL + "/missing"},
+		},
+	}
+
+	checker := New(store, srv.Client(), Options{Concurrency: 2, Timeout: 100 * time.Millisecond}, slog.Default())
+	require.NoError(t, checker.CheckAll(context.Background()))
+	require.Len(t, store.health, 7)
+
+	tests := map[int64]struct {
+		status int
+		broken bool
+	}{
+		1: {status: http.StatusOK},
+		2: {status: http.StatusOK},
+		3: {status: http.StatusNotFound, broken: true},
+		4: {status: http.StatusNotFound, broken: true},
+		5: {broken: true},
+		6: {broken: true},
+		7: {status: http.StatusNotFound, broken: true},
+	}
+
+	for id, tc := range tests {
+		health := store.health[id]
+		assert.Equal(t, tc.status, health.Status, "link %d", id)
+		assert.Equal(t, tc.broken, health.Broken(), "link %d", id)
+		assert.False(t, health.CheckedAt.IsZero(), "link %d", id)
+	}
+	assert.GreaterOrEqual(t, store.health[5].Latency, 100*time.Millisecond)
+}
+
+func TestCheckAll_Concurrency(t *testing.T) {
+	const concurrency = 3
+
+	var inFlight, maxInFlight atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			current := maxInFlight.Load()
+			if n <= current || maxInFlight.CompareAndSwap(current, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	store := &fakeStore{}
+	for i := int64(1); i <= 12; i++ {
+		store.links = append(store.links, storage.Link{ID: i, URL: srv.URL})
+	}
+
+	checker := New(store, srv.Client(), Options{Concurrency: concurrency}, slog.Default())
+	require.NoError(t, checker.CheckAll(context.Background()))
+
+	assert.Len(t, store.health, 12)
+	assert.LessOrEqual(t, maxInFlight.Load(), int64(concurrency))
+}
+
+func TestCheckAll_Canceled(t *testing.T) {
+	store := &fakeStore{links: []storage.Link{{ID: 1, URL: "http://127.0.0.1:1/"}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	checker := New(store, nil, Options{Concurrency: 1}, slog.Default())
+	assert.ErrorIs(t, checker.CheckAll(ctx), context.Canceled)
+	assert.Empty(t, store.health)
+}
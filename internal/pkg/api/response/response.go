@@ -9,6 +9,8 @@ import (
 type Response struct {
 	Status string `json:"status"`
 	Error  string `json:"error,omitempty"`
+	//Code identifies errors clients are expected to handle, it is empty for other errors
+	Code string `json:"code,omitempty"`
 }
 
 const (
@@ -16,6 +18,10 @@ const (
 	StatusError = "error"
 )
 
+const (
+	CodeUserQuotaExceeded = "user_quota_exceeded"
+)
+
 func OK() Response {
 	return Response{Status: StatusOk}
 }
@@ -27,6 +33,14 @@ func Error(msg string) Response {
 	}
 }
 
+func ErrorCode(code string, msg string) Response {
+	return Response{
+		Status: StatusError,
+		Error:  msg,
+		Code:   code,
+	}
+}
+
 func ValidationError(errs validator.ValidationErrors) Response {
 	var errMsgs []string
 
@@ -0,0 +1,102 @@
+package credcache
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"sync"
+	"time"
+)
+
+// Cache remembers credentials that were verified recently together with what they resolved to,
+// so the password hash of a user is not checked on every request.
+// Entries are kept per user name with a digest of the password, the password itself is not kept
+type Cache[V any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]entry[V]
+}
+
+type entry[V any] struct {
+	digest    [sha256.Size]byte
+	value     V
+	expiresAt time.Time
+}
+
+// New creates a cache that keeps credentials for ttl, it keeps nothing if ttl is not positive
+func New[V any](ttl time.Duration) *Cache[V] {
+	return &Cache[V]{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]entry[V]),
+	}
+}
+
+// Get returns the value of verified credentials, false if they were not verified, expired or were invalidated
+func (c *Cache[V]) Get(name string, password string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	e, ok := c.entries[name]
+	if !ok {
+		return zero, false
+	}
+
+	if !c.now().Before(e.expiresAt) {
+		delete(c.entries, name)
+
+		return zero, false
+	}
+
+	d := digest(name, password)
+	if subtle.ConstantTimeCompare(e.digest[:], d[:]) != 1 {
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+// Put remembers verified credentials, they replace the credentials remembered for the name
+func (c *Cache[V]) Put(name string, password string, value V) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.prune(now)
+	c.entries[name] = entry[V]{digest: digest(name, password), value: value, expiresAt: now.Add(c.ttl)}
+}
+
+// Invalidate forgets the credentials of the name, the next request with them is verified again
+func (c *Cache[V]) Invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, name)
+}
+
+// Flush forgets all credentials
+func (c *Cache[V]) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+}
+
+// prune drops expired entries
+func (c *Cache[V]) prune(now time.Time) {
+	for name, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, name)
+		}
+	}
+}
+
+// digest separates the name from the password, names can not contain a colon in basic auth
+func digest(name string, password string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(name + ":" + password))
+}
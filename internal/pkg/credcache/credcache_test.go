@@ -0,0 +1,54 @@
+package credcache
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := New[int64](time.Minute)
+	c.now = func() time.Time { return now }
+
+	_, ok := c.Get("alice", "secret")
+	assert.False(t, ok, "credentials are not cached before they are verified")
+
+	c.Put("alice", "secret", 7)
+	value, ok := c.Get("alice", "secret")
+	assert.True(t, ok)
+	assert.Equal(t, int64(7), value)
+
+	_, ok = c.Get("alice", "other")
+	assert.False(t, ok, "another password is not verified")
+	_, ok = c.Get("alice:secret", "")
+	assert.False(t, ok, "the name is separated from the password")
+
+	now = now.Add(time.Minute)
+	_, ok = c.Get("alice", "secret")
+	assert.False(t, ok, "credentials expire")
+}
+
+func TestCache_Disabled(t *testing.T) {
+	c := New[int64](0)
+
+	c.Put("alice", "secret", 7)
+	_, ok := c.Get("alice", "secret")
+	assert.False(t, ok)
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	c := New[int64](time.Minute)
+	c.Put("alice", "secret", 7)
+	c.Put("bob", "hunter22", 8)
+
+	c.Invalidate("alice")
+	_, ok := c.Get("alice", "secret")
+	assert.False(t, ok, "invalidated credentials are verified again")
+	_, ok = c.Get("bob", "hunter22")
+	assert.True(t, ok, "other users stay cached")
+
+	c.Flush()
+	_, ok = c.Get("bob", "hunter22")
+	assert.False(t, ok)
+}
@@ -0,0 +1,45 @@
+package geoip
+
+import (
+	"fmt"
+	"github.com/oschwald/maxminddb-golang"
+	"net"
+)
+
+// Reader looks up countries in a local MaxMind-format database, e.g. GeoLite2-Country.mmdb.
+// It never goes to the network.
+type Reader struct {
+	db *maxminddb.Reader
+}
+
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// Open memory-maps the database file
+func Open(path string) (*Reader, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open geoip database %s: %w", path, err)
+	}
+
+	return &Reader{db: db}, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the ip country, or an empty string if it is unknown
+func (r *Reader) Country(ip net.IP) (string, error) {
+	var rec record
+
+	err := r.db.Lookup(ip, &rec)
+	if err != nil {
+		return "", fmt.Errorf("lookup %s: %w", ip, err)
+	}
+
+	return rec.Country.ISOCode, nil
+}
+
+func (r *Reader) Close() error {
+	return r.db.Close()
+}
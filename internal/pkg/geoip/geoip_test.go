@@ -0,0 +1,151 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReader_Country(t *testing.T) {
+	path := writeTestDatabase(t, map[string]string{
+		"81.2.69.0/24":   "GB",
+		"2.125.160.0/20": "DE",
+	})
+
+	reader, err := Open(path)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	tests := map[string]struct {
+		ip       string
+		expected string
+	}{
+		"First network":  {ip: "81.2.69.142", expected: "GB"},
+		"Second network": {ip: "2.125.170.1", expected: "DE"},
+		"Unknown":        {ip: "10.0.0.1", expected: ""},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			country, err := reader.Country(net.ParseIP(tc.ip))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, country)
+		})
+	}
+}
+
+func TestOpen_MissingFile(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "missing.mmdb"))
+	assert.Error(t, err)
+}
+
+// writeTestDatabase writes a minimal IPv4 MaxMind DB that maps networks to country iso codes
+func writeTestDatabase(t *testing.T, networks map[string]string) string {
+	t.Helper()
+
+	type node struct{ children [2]int } //child node index, or -1 for empty, or -(2+data index) for data
+
+	nodes := []node{{children: [2]int{-1, -1}}}
+	var data [][]byte
+
+	for cidr, country := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+
+		ones, _ := network.Mask.Size()
+		ip := network.IP.To4()
+
+		data = append(data, encodeMap(map[string][]byte{
+			"country": encodeMap(map[string][]byte{"iso_code": encodeString(country)}),
+		}))
+
+		current := 0
+		for bit := 0; bit < ones; bit++ {
+			side := int(ip[bit/8]>>(7-bit%8)) & 1
+			if bit == ones-1 {
+				nodes[current].children[side] = -(2 + len(data) - 1)
+				break
+			}
+
+			if nodes[current].children[side] < 0 {
+				nodes = append(nodes, node{children: [2]int{-1, -1}})
+				nodes[current].children[side] = len(nodes) - 1
+			}
+			current = nodes[current].children[side]
+		}
+	}
+
+	nodeCount := len(nodes)
+	offsets := make([]int, len(data))
+	var dataSection bytes.Buffer
+	for i, d := range data {
+		offsets[i] = dataSection.Len()
+		dataSection.Write(d)
+	}
+
+	var file bytes.Buffer
+	for _, n := range nodes {
+		for _, child := range n.children {
+			var value int
+			switch {
+			case child == -1:
+				value = nodeCount
+			case child < -1:
+				value = nodeCount + 16 + offsets[-child-2]
+			default:
+				value = child
+			}
+			file.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+
+	file.Write(make([]byte, 16))
+	file.Write(dataSection.Bytes())
+	file.WriteString("\xAB\xCD\xEFMaxMind.com")
+	file.Write(encodeMap(map[string][]byte{
+		"node_count":                  encodeUint(6, uint64(nodeCount)),
+		"record_size":                 encodeUint(5, 24),
+		"ip_version":                  encodeUint(5, 4),
+		"database_type":               encodeString("Test-Country"),
+		"binary_format_major_version": encodeUint(5, 2),
+		"binary_format_minor_version": encodeUint(5, 0),
+		"build_epoch":                 encodeUint(9, 0),
+	}))
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	require.NoError(t, os.WriteFile(path, file.Bytes(), 0o600))
+
+	return path
+}
+
+func encodeString(s string) []byte {
+	return append([]byte{2<<5 | byte(len(s))}, s...)
+}
+
+func encodeMap(m map[string][]byte) []byte {
+	out := []byte{7<<5 | byte(len(m))}
+	for key, value := range m {
+		out = append(out, encodeString(key)...)
+		out = append(out, value...)
+	}
+
+	return out
+}
+
+// encodeUint encodes uint16 (5), uint32 (6) and uint64 (9, extended type) values
+func encodeUint(kind byte, value uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], value)
+	payload := bytes.TrimLeft(buf[:], "\x00")
+
+	if kind > 7 {
+		return append([]byte{byte(len(payload)), kind - 7}, payload...)
+	}
+
+	return append([]byte{kind<<5 | byte(len(payload))}, payload...)
+}
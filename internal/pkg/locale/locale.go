@@ -0,0 +1,41 @@
+package locale
+
+import (
+	"golang.org/x/text/language"
+)
+
+// Canonical returns the canonical form of a BCP 47 language tag, e.g. "en-us" becomes "en-US"
+func Canonical(tag string) (string, error) {
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return "", err
+	}
+
+	return parsed.String(), nil
+}
+
+// Match negotiates the Accept-Language header against the supported canonical tags.
+// It returns false if the header is empty, invalid or none of the tags is acceptable.
+func Match(acceptLanguage string, supported []string) (string, bool) {
+	if acceptLanguage == "" || len(supported) == 0 {
+		return "", false
+	}
+
+	accepted, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(accepted) == 0 {
+		return "", false
+	}
+
+	tags := make([]language.Tag, 0, len(supported))
+	for _, tag := range supported {
+		tags = append(tags, language.Make(tag))
+	}
+
+	//the matcher falls back to the first tag with confidence No, which is not a match for us
+	_, index, confidence := language.NewMatcher(tags).Match(accepted...)
+	if confidence == language.No {
+		return "", false
+	}
+
+	return supported[index], true
+}
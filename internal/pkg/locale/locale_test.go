@@ -0,0 +1,50 @@
+package locale
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	tag, err := Canonical("en-us")
+	require.NoError(t, err)
+	assert.Equal(t, "en-US", tag)
+
+	tag, err = Canonical("DE")
+	require.NoError(t, err)
+	assert.Equal(t, "de", tag)
+
+	_, err = Canonical("not a tag")
+	assert.Error(t, err)
+}
+
+func TestMatch(t *testing.T) {
+	supported := []string{"en", "de", "pt-BR", "zh-Hant"}
+
+	tests := map[string]struct {
+		header   string
+		expected string
+		matched  bool
+	}{
+		"Exact":              {header: "de", expected: "de", matched: true},
+		"Region of base":     {header: "de-AT,de;q=0.9", expected: "de", matched: true},
+		"Quality order":      {header: "fr;q=0.9,de;q=0.5,en;q=0.8", expected: "en", matched: true},
+		"Region":             {header: "pt-BR", expected: "pt-BR", matched: true},
+		"Script":             {header: "zh-TW", expected: "zh-Hant", matched: true},
+		"No acceptable tag":  {header: "ja", matched: false},
+		"Empty header":       {header: "", matched: false},
+		"Invalid header":     {header: "=;;", matched: false},
+		"Wildcard only":      {header: "*", matched: false},
+		"Excluded language":  {header: "de;q=0", matched: false},
+		"Case insensitivity": {header: "PT-br", expected: "pt-BR", matched: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tag, ok := Match(tc.header, supported)
+			assert.Equal(t, tc.matched, ok)
+			assert.Equal(t, tc.expected, tag)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// Lockout counts failed attempts per key and locks the key
+// once too many attempts failed within the window
+type Lockout struct {
+	mu          sync.Mutex
+	maxAttempts int
+	window      time.Duration
+	duration    time.Duration
+	now         func() time.Time
+	entries     map[string]*entry
+}
+
+type entry struct {
+	failures    int
+	firstFailed time.Time
+	lockedUntil time.Time
+}
+
+// New creates a lockout, it never locks a key if maxAttempts is not positive
+func New(maxAttempts int, window time.Duration, duration time.Duration) *Lockout {
+	return &Lockout{
+		maxAttempts: maxAttempts,
+		window:      window,
+		duration:    duration,
+		now:         time.Now,
+		entries:     make(map[string]*entry),
+	}
+}
+
+// Locked reports whether the key is locked and for how long
+func (l *Lockout) Locked(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return 0, false
+	}
+
+	remaining := e.lockedUntil.Sub(l.now())
+	if remaining <= 0 {
+		return 0, false
+	}
+
+	return remaining, true
+}
+
+// Attempt checks the lock of the key and records an attempt in one step, so concurrent attempts
+// cannot pass the check before any of them is counted. The attempt counts as failed until Reset is called.
+// It returns false and how long the key stays locked if the attempt is not allowed
+func (l *Lockout) Attempt(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if e, ok := l.entries[key]; ok {
+		if remaining := e.lockedUntil.Sub(now); remaining > 0 {
+			return remaining, false
+		}
+	}
+
+	if l.maxAttempts > 0 {
+		l.fail(key, now)
+	}
+
+	return 0, true
+}
+
+// Fail records a failed attempt for the key
+func (l *Lockout) Fail(key string) {
+	if l.maxAttempts <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.fail(key, l.now())
+}
+
+// fail records a failed attempt, the caller holds the mutex
+func (l *Lockout) fail(key string, now time.Time) {
+	l.prune(now)
+
+	e, ok := l.entries[key]
+	if !ok || now.Sub(e.firstFailed) > l.window {
+		e = &entry{firstFailed: now}
+		l.entries[key] = e
+	}
+
+	e.failures++
+	if e.failures >= l.maxAttempts {
+		e.lockedUntil = now.Add(l.duration)
+		e.failures = 0
+		e.firstFailed = now
+	}
+}
+
+// Reset forgets failed attempts of the key
+func (l *Lockout) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+}
+
+// prune drops entries that are neither locked nor inside their window
+func (l *Lockout) prune(now time.Time) {
+	for key, e := range l.entries {
+		if now.After(e.lockedUntil) && now.Sub(e.firstFailed) > l.window {
+			delete(l.entries, key)
+		}
+	}
+}
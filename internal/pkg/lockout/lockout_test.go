@@ -0,0 +1,110 @@
+package lockout
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLockout(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(3, time.Minute, 10*time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Fail("alias")
+	l.Fail("alias")
+	_, locked := l.Locked("alias")
+	assert.False(t, locked, "two failures must not lock")
+
+	l.Fail("alias")
+	remaining, locked := l.Locked("alias")
+	assert.True(t, locked, "third failure must lock")
+	assert.Equal(t, 10*time.Minute, remaining)
+
+	_, locked = l.Locked("other")
+	assert.False(t, locked, "lockout is per key")
+
+	now = now.Add(10*time.Minute + time.Second)
+	_, locked = l.Locked("alias")
+	assert.False(t, locked, "lock expires")
+}
+
+func TestLockout_Window(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute, time.Hour)
+	l.now = func() time.Time { return now }
+
+	l.Fail("alias")
+	now = now.Add(2 * time.Minute)
+	l.Fail("alias")
+	_, locked := l.Locked("alias")
+	assert.False(t, locked, "failures outside of the window are forgotten")
+
+	l.Reset("alias")
+	l.Fail("alias")
+	_, locked = l.Locked("alias")
+	assert.False(t, locked, "reset forgets failures")
+}
+
+func TestLockout_Attempt(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(3, time.Minute, 10*time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		_, ok := l.Attempt("alias")
+		assert.True(t, ok, "attempt %d must be allowed", i+1)
+	}
+
+	remaining, ok := l.Attempt("alias")
+	assert.False(t, ok, "attempts are counted before their result is known")
+	assert.Equal(t, 10*time.Minute, remaining)
+
+	now = now.Add(10*time.Minute + time.Second)
+	_, ok = l.Attempt("alias")
+	assert.True(t, ok, "lock expires")
+	l.Reset("alias")
+
+	_, ok = l.Attempt("alias")
+	assert.True(t, ok)
+	l.Reset("alias")
+	_, ok = l.Attempt("alias")
+	assert.True(t, ok)
+	_, locked := l.Locked("alias")
+	assert.False(t, locked, "successful attempts are reset")
+}
+
+func TestLockout_ConcurrentAttempts(t *testing.T) {
+	l := New(3, time.Minute, time.Hour)
+
+	var (
+		wg      sync.WaitGroup
+		allowed atomic.Int32
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := l.Attempt("alias"); ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), allowed.Load())
+}
+
+func TestLockout_Disabled(t *testing.T) {
+	l := New(0, time.Minute, time.Hour)
+	for i := 0; i < 100; i++ {
+		l.Fail("alias")
+		_, ok := l.Attempt("alias")
+		assert.True(t, ok)
+	}
+
+	_, locked := l.Locked("alias")
+	assert.False(t, locked)
+}
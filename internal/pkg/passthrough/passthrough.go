@@ -0,0 +1,111 @@
+package passthrough
+
+import (
+	"net/url"
+	"strings"
+)
+
+// MergeQuery merges the raw query of an incoming request into the target url.
+//
+// Rules:
+//   - parameters of the incoming query override target parameters with the same (decoded) key;
+//   - target parameters that are not present in the incoming query are kept in their original order;
+//   - incoming parameters are appended after the kept target parameters in their original order;
+//   - repeated keys in the incoming query are all forwarded;
+//   - empty pairs ("a=1&&b=2", a trailing "?") are dropped;
+//   - pairs are copied as is, so the original percent-encoding of both sides is preserved.
+func MergeQuery(target *url.URL, rawQuery string) {
+	incoming := splitQuery(rawQuery)
+	if len(incoming) == 0 {
+		return
+	}
+
+	overridden := make(map[string]struct{}, len(incoming))
+	for _, pair := range incoming {
+		overridden[queryKey(pair)] = struct{}{}
+	}
+
+	merged := make([]string, 0, len(incoming))
+	for _, pair := range splitQuery(target.RawQuery) {
+		if _, ok := overridden[queryKey(pair)]; !ok {
+			merged = append(merged, pair)
+		}
+	}
+	merged = append(merged, incoming...)
+
+	target.RawQuery = strings.Join(merged, "&")
+	target.ForceQuery = false
+}
+
+// AppendPath appends an escaped sub-path to the target url path.
+//
+// Rules:
+//   - exactly one slash separates the target path and the sub-path;
+//   - empty, "." and ".." segments of the sub-path are dropped, so it cannot climb above the target path;
+//   - a trailing slash of the sub-path is kept;
+//   - percent-encoding of the sub-path is preserved.
+func AppendPath(target *url.URL, escapedSubPath string) error {
+	var segments []string
+	for _, segment := range strings.Split(escapedSubPath, "/") {
+		decoded, err := url.PathUnescape(segment)
+		if err != nil {
+			return err
+		}
+
+		if decoded == "" || decoded == "." || decoded == ".." {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+
+	if len(segments) == 0 {
+		return nil
+	}
+
+	joined := strings.TrimSuffix(target.EscapedPath(), "/") + "/" + strings.Join(segments, "/")
+	if strings.HasSuffix(escapedSubPath, "/") {
+		joined += "/"
+	}
+
+	path, err := url.PathUnescape(joined)
+	if err != nil {
+		return err
+	}
+
+	target.Path = path
+	target.RawPath = joined
+
+	return nil
+}
+
+func splitQuery(rawQuery string) []string {
+	var pairs []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair != "" {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	return pairs
+}
+
+func queryKey(pair string) string {
+	key, _, _ := strings.Cut(pair, "=")
+	if decoded, err := url.QueryUnescape(key); err == nil {
+		return decoded
+	}
+
+	return key
+}
+
+// DropParam removes all parameters with the given (decoded) key from a raw query
+func DropParam(rawQuery string, key string) string {
+	var kept []string
+	for _, pair := range splitQuery(rawQuery) {
+		if queryKey(pair) != key {
+			kept = append(kept, pair)
+		}
+	}
+
+	return strings.Join(kept, "&")
+}
@@ -0,0 +1,139 @@
+package passthrough
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+)
+
+func TestMergeQuery(t *testing.T) {
+	tests := map[string]struct {
+		target   string
+		query    string
+		expected string
+	}{
+		"Empty incoming query": {
+			target:   "https://example.com/a?x=1",
+			query:    "",
+			expected: "https://example.com/a?x=1",
+		},
+		"Target without query": {
+			target:   "https://example.com/a",
+			query:    "utm_source=x",
+			expected: "https://example.com/a?utm_source=x",
+		},
+		"Incoming overrides target key": {
+			target:   "https://example.com/a?utm_source=default&id=7",
+			query:    "utm_source=x",
+			expected: "https://example.com/a?id=7&utm_source=x",
+		},
+		"Repeated incoming keys are forwarded": {
+			target:   "https://example.com/a?tag=a",
+			query:    "tag=b&tag=c",
+			expected: "https://example.com/a?tag=b&tag=c",
+		},
+		"Empty pairs are dropped": {
+			target:   "https://example.com/a?x=1&&",
+			query:    "&y=2&",
+			expected: "https://example.com/a?x=1&y=2",
+		},
+		"Encoding is preserved": {
+			target:   "https://example.com/a?q=a%20b",
+			query:    "next=%2Fpath%3Fa%3D1&name=J%C3%BCrgen+M",
+			expected: "https://example.com/a?q=a%20b&next=%2Fpath%3Fa%3D1&name=J%C3%BCrgen+M",
+		},
+		"Encoded keys are compared decoded": {
+			target:   "https://example.com/a?utm%5Fsource=default",
+			query:    "utm_source=x",
+			expected: "https://example.com/a?utm_source=x",
+		},
+		"Fragment is kept": {
+			target:   "https://example.com/a?x=1#section",
+			query:    "y=2",
+			expected: "https://example.com/a?x=1&y=2#section",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			target, err := url.Parse(tc.target)
+			require.NoError(t, err)
+
+			MergeQuery(target, tc.query)
+			assert.Equal(t, tc.expected, target.String())
+		})
+	}
+}
+
+func TestAppendPath(t *testing.T) {
+	tests := map[string]struct {
+		target   string
+		subPath  string
+		expected string
+		wantErr  bool
+	}{
+		"Empty sub-path": {
+			target:   "https://example.com/docs",
+			subPath:  "",
+			expected: "https://example.com/docs",
+		},
+		"Target without path": {
+			target:   "https://example.com",
+			subPath:  "extra/path",
+			expected: "https://example.com/extra/path",
+		},
+		"Target with trailing slash": {
+			target:   "https://example.com/docs/",
+			subPath:  "extra/path",
+			expected: "https://example.com/docs/extra/path",
+		},
+		"Trailing slash of sub-path is kept": {
+			target:   "https://example.com/docs",
+			subPath:  "extra/",
+			expected: "https://example.com/docs/extra/",
+		},
+		"Dot segments are dropped": {
+			target:   "https://example.com/docs",
+			subPath:  "../../admin/./%2e%2e/page",
+			expected: "https://example.com/docs/admin/page",
+		},
+		"Encoding is preserved": {
+			target:   "https://example.com/docs",
+			subPath:  "a%2Fb/c%20d",
+			expected: "https://example.com/docs/a%2Fb/c%20d",
+		},
+		"Query and fragment are kept": {
+			target:   "https://example.com/docs?v=2#top",
+			subPath:  "api",
+			expected: "https://example.com/docs/api?v=2#top",
+		},
+		"Invalid escaping": {
+			target:  "https://example.com/docs",
+			subPath: "%zz",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			target, err := url.Parse(tc.target)
+			require.NoError(t, err)
+
+			err = AppendPath(target, tc.subPath)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, target.String())
+		})
+	}
+}
+
+func TestDropParam(t *testing.T) {
+	assert.Equal(t, "a=1&c=3", DropParam("a=1&password=x&c=3&password=y", "password"))
+	assert.Equal(t, "a=1", DropParam("pass%77ord=x&a=1", "password"))
+	assert.Equal(t, "", DropParam("password=x", "password"))
+}
@@ -0,0 +1,145 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/skip2/go-qrcode"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+var (
+	ErrUnknownLevel = errors.New("unknown error correction level")
+	ErrSizeTooSmall = errors.New("size is too small for the code")
+)
+
+// Level is the error correction level of a code, higher levels survive more damage but need more modules
+type Level string
+
+const (
+	// LevelLow recovers 7% of the code
+	LevelLow Level = "L"
+	// LevelMedium recovers 15% of the code
+	LevelMedium Level = "M"
+	// LevelQuartile recovers 25% of the code
+	LevelQuartile Level = "Q"
+	// LevelHigh recovers 30% of the code
+	LevelHigh Level = "H"
+)
+
+var recoveryLevels = map[Level]qrcode.RecoveryLevel{
+	LevelLow:      qrcode.Low,
+	LevelMedium:   qrcode.Medium,
+	LevelQuartile: qrcode.High,
+	LevelHigh:     qrcode.Highest,
+}
+
+// ParseLevel returns the level of a case-insensitive letter L, M, Q or H
+func ParseLevel(name string) (Level, error) {
+	level := Level(strings.ToUpper(name))
+	if _, ok := recoveryLevels[level]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownLevel, name)
+	}
+
+	return level, nil
+}
+
+// Options of a rendered code
+type Options struct {
+	// Size is the width and the height of the image in pixels
+	Size  int
+	Level Level
+	// Margin is the width of the quiet zone around the code in modules, 4 is recommended by the standard
+	Margin int
+}
+
+// PNG renders the content as a black and white PNG image
+func PNG(content string, opts Options) ([]byte, error) {
+	modules, err := encode(content, opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	//modules are scaled by a whole number of pixels, the rest of the size is split around the code
+	total := len(modules) + 2*opts.Margin
+	scale := opts.Size / total
+	if scale < 1 {
+		return nil, fmt.Errorf("%w: %d pixels for %d modules", ErrSizeTooSmall, opts.Size, total)
+	}
+	offset := (opts.Size-scale*total)/2 + scale*opts.Margin
+
+	img := image.NewPaletted(image.Rect(0, 0, opts.Size, opts.Size), color.Palette{color.White, color.Black})
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err = png.Encode(&buf, img)
+	if err != nil {
+		return nil, fmt.Errorf("encode png: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SVG renders the content as an SVG image, dark modules of a row are joined into a single path
+func SVG(content string, opts Options) ([]byte, error) {
+	modules, err := encode(content, opts.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(modules) + 2*opts.Margin
+
+	var path strings.Builder
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start+opts.Margin, y+opts.Margin, x-start, x-start)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, total, total)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="%s"/></svg>`+"\n", path.String())
+
+	return buf.Bytes(), nil
+}
+
+// encode returns the dark modules of the code without the quiet zone
+func encode(content string, level Level) ([][]bool, error) {
+	recovery, ok := recoveryLevels[level]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownLevel, level)
+	}
+
+	code, err := qrcode.New(content, recovery)
+	if err != nil {
+		return nil, fmt.Errorf("encode qr code: %w", err)
+	}
+	code.DisableBorder = true
+
+	return code.Bitmap(), nil
+}
@@ -0,0 +1,105 @@
+package qr
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"image/png"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+const content = "https://sho.rt/v1/launc"
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("q")
+	require.NoError(t, err)
+	assert.Equal(t, LevelQuartile, level)
+
+	_, err = ParseLevel("X")
+	assert.ErrorIs(t, err, ErrUnknownLevel)
+}
+
+func TestPNG(t *testing.T) {
+	const (
+		size   = 300
+		margin = 2
+	)
+
+	modules, err := encode(content, LevelMedium)
+	require.NoError(t, err)
+
+	data, err := PNG(content, Options{Size: size, Level: LevelMedium, Margin: margin})
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, size, img.Bounds().Dx())
+	require.Equal(t, size, img.Bounds().Dy())
+
+	total := len(modules) + 2*margin
+	scale := size / total
+	offset := (size-scale*total)/2 + scale*margin
+
+	dark := func(x, y int) bool {
+		r, _, _, _ := img.At(x, y).RGBA()
+		return r == 0
+	}
+
+	//the center pixel of every module has the color of the module
+	for y, row := range modules {
+		for x, want := range row {
+			assert.Equal(t, want, dark(offset+x*scale+scale/2, offset+y*scale+scale/2), "module %d,%d", x, y)
+		}
+	}
+
+	//the quiet zone is white
+	for i := 0; i < offset; i++ {
+		assert.False(t, dark(i, i))
+		assert.False(t, dark(size-1-i, size-1-i))
+	}
+}
+
+func TestPNG_SizeTooSmall(t *testing.T) {
+	_, err := PNG(content, Options{Size: 20, Level: LevelHigh, Margin: 4})
+	assert.ErrorIs(t, err, ErrSizeTooSmall)
+}
+
+func TestSVG(t *testing.T) {
+	modules, err := encode(content, LevelHigh)
+	require.NoError(t, err)
+
+	data, err := SVG(content, Options{Size: 512, Level: LevelHigh, Margin: 4})
+	require.NoError(t, err)
+
+	total := strconv.Itoa(len(modules) + 8)
+	assert.Contains(t, string(data), `width="512" height="512" viewBox="0 0 `+total+` `+total+`"`)
+
+	darkModules := 0
+	for _, row := range modules {
+		for _, dark := range row {
+			if dark {
+				darkModules++
+			}
+		}
+	}
+
+	//every dark module is covered by exactly one horizontal run
+	covered := 0
+	for _, run := range regexp.MustCompile(`M(\d+) (\d+)h(\d+)`).FindAllStringSubmatch(string(data), -1) {
+		x, _ := strconv.Atoi(run[1])
+		y, _ := strconv.Atoi(run[2])
+		width, _ := strconv.Atoi(run[3])
+		for i := 0; i < width; i++ {
+			assert.True(t, modules[y-4][x-4+i])
+		}
+		covered += width
+	}
+	assert.Equal(t, darkModules, covered)
+}
+
+func TestEncode_UnknownLevel(t *testing.T) {
+	_, err := SVG(content, Options{Size: 100, Level: "Z"})
+	assert.ErrorIs(t, err, ErrUnknownLevel)
+}
@@ -0,0 +1,27 @@
+package tagname
+
+import (
+	"slices"
+	"strings"
+)
+
+// Normalize returns the stored form of a tag name, names are case-insensitive and stored in lower case
+func Normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// NormalizeAll normalizes the tag names of a link, sorts them and drops duplicates.
+// Blank names become empty and are left for validation to reject
+func NormalizeAll(names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, Normalize(name))
+	}
+	slices.Sort(tags)
+
+	return slices.Compact(tags)
+}
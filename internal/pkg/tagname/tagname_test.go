@@ -0,0 +1,25 @@
+package tagname
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeAll(t *testing.T) {
+	tests := map[string]struct {
+		names []string
+		want  []string
+	}{
+		"nil":        {names: nil, want: nil},
+		"trimmed":    {names: []string{" Launch", "launch "}, want: []string{"launch"}},
+		"sorted":     {names: []string{"spring", "Launch"}, want: []string{"launch", "spring"}},
+		"duplicates": {names: []string{"a", "b", "A"}, want: []string{"a", "b"}},
+		"blank":      {names: []string{"a", " "}, want: []string{"", "a"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, NormalizeAll(tc.names))
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package urlnorm
+
+import (
+	"fmt"
+	"golang.org/x/net/idna"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// defaultPorts are dropped from hosts of urls with these schemes
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// trackingParams are query keys added by ad networks and mailing tools, keys ending with "_" are prefixes
+var trackingParams = []string{
+	"utm_", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid", "yclid", "igshid", "twclid", "mc_cid", "mc_eid",
+	"_hsenc", "_hsmi",
+}
+
+// Options of the normalization
+type Options struct {
+	// StripTracking removes tracking parameters from the query
+	StripTracking bool
+}
+
+// Normalize returns the canonical form of an absolute url, so that equivalent urls compare equal.
+//
+// Rules:
+//   - the scheme and the host are lower-cased, internationalized hosts are converted to punycode;
+//   - the default port of http and https is dropped;
+//   - "." and ".." segments and repeated slashes are removed from the path, a trailing slash is kept;
+//   - an empty path of a url with a host becomes "/";
+//   - an empty query ("?") is dropped, tracking parameters are dropped if opts.StripTracking is set;
+//   - percent-encoding, the order of query parameters and the fragment are preserved.
+func Normalize(raw string, opts Options) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Scheme == "" {
+		return "", fmt.Errorf("url %q has no scheme", raw)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	//opaque urls such as "mailto:" have nothing else to normalize
+	if u.Opaque != "" {
+		return u.String(), nil
+	}
+
+	if u.Host != "" {
+		u.Host, err = normalizeHost(u.Scheme, u.Host)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = normalizePath(u)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.StripTracking {
+		u.RawQuery = stripTracking(u.RawQuery)
+	}
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+func normalizeHost(scheme string, host string) (string, error) {
+	//hosts without a port keep the brackets of ipv6 addresses
+	hostname, port := strings.Trim(host, "[]"), ""
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		hostname, port = h, p
+	}
+
+	if port == defaultPorts[scheme] {
+		port = ""
+	}
+
+	//ip addresses are kept as they are
+	if net.ParseIP(hostname) == nil {
+		ascii, err := idna.Lookup.ToASCII(hostname)
+		if err != nil {
+			return "", fmt.Errorf("invalid host %q: %w", hostname, err)
+		}
+		hostname = ascii
+	}
+	hostname = strings.ToLower(hostname)
+
+	if port == "" {
+		if strings.Contains(hostname, ":") {
+			return "[" + hostname + "]", nil
+		}
+
+		return hostname, nil
+	}
+
+	return net.JoinHostPort(hostname, port), nil
+}
+
+func normalizePath(u *url.URL) error {
+	escaped := u.EscapedPath()
+	if escaped == "" {
+		if u.Host != "" {
+			u.Path, u.RawPath = "/", ""
+		}
+
+		return nil
+	}
+
+	cleaned := path.Clean(escaped)
+	if strings.HasSuffix(escaped, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	decoded, err := url.PathUnescape(cleaned)
+	if err != nil {
+		return err
+	}
+	u.Path, u.RawPath = decoded, cleaned
+
+	return nil
+}
+
+// stripTracking drops tracking parameters from a raw query, other pairs are copied as is
+func stripTracking(rawQuery string) string {
+	var kept []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+
+		key, _, _ := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(key); err == nil {
+			key = decoded
+		}
+
+		if !isTracking(strings.ToLower(key)) {
+			kept = append(kept, pair)
+		}
+	}
+
+	return strings.Join(kept, "&")
+}
+
+func isTracking(key string) bool {
+	for _, param := range trackingParams {
+		if strings.HasSuffix(param, "_") && strings.HasPrefix(key, param) || key == param {
+			return true
+		}
+	}
+
+	return false
+}
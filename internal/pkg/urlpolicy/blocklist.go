@@ -0,0 +1,109 @@
+package urlpolicy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Blocklist is a file of domain patterns, one per line, "#" starts a comment
+type Blocklist struct {
+	path string
+
+	mu       sync.RWMutex
+	patterns []string
+	modTime  time.Time
+	size     int64
+}
+
+// LoadBlocklist reads the blocklist file
+func LoadBlocklist(path string) (*Blocklist, error) {
+	b := &Blocklist{path: path}
+
+	_, err := b.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Match reports whether the host matches a pattern of the blocklist
+func (b *Blocklist) Match(host string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return matchAny(b.patterns, host)
+}
+
+// Reload reads the file again if its size or modification time changed, it reports whether the list was replaced.
+// The previous list is kept if the file cannot be read
+func (b *Blocklist) Reload() (bool, error) {
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return false, fmt.Errorf("stat blocklist: %w", err)
+	}
+
+	b.mu.RLock()
+	unchanged := info.ModTime().Equal(b.modTime) && info.Size() == b.size
+	b.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	patterns, err := readPatterns(b.path)
+	if err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	b.patterns = patterns
+	b.modTime = info.ModTime()
+	b.size = info.Size()
+	b.mu.Unlock()
+
+	return true, nil
+}
+
+// Watch reloads the file every interval until the context is done, reload errors are passed to onError
+func (b *Blocklist) Watch(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := b.Reload()
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func readPatterns(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open blocklist: %w", err)
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		patterns = append(patterns, line)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read blocklist: %w", err)
+	}
+
+	return normalizePatterns(patterns), nil
+}
@@ -0,0 +1,146 @@
+package urlpolicy
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/idna"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrSchemeNotAllowed = errors.New("scheme is not allowed")
+	ErrDomainNotAllowed = errors.New("domain is not in the allow-list")
+	ErrDomainDenied     = errors.New("domain is denied")
+	ErrDomainBlocked    = errors.New("domain is blocklisted")
+)
+
+// DefaultSchemes are allowed if no schemes are configured
+var DefaultSchemes = []string{"http", "https"}
+
+// Options of a policy, domain patterns are either exact hosts, "*.example.com" for any subdomain or "*" for any host
+type Options struct {
+	Schemes        []string
+	AllowedDomains []string
+	DeniedDomains  []string
+	// Blocklist is checked after the denied domains, nil disables it
+	Blocklist *Blocklist
+}
+
+// Policy decides which urls links may point to
+type Policy struct {
+	schemes   map[string]struct{}
+	allowed   []string
+	denied    []string
+	blocklist *Blocklist
+}
+
+func New(opts Options) *Policy {
+	schemes := opts.Schemes
+	if len(schemes) == 0 {
+		schemes = DefaultSchemes
+	}
+
+	policy := &Policy{
+		schemes:   make(map[string]struct{}, len(schemes)),
+		allowed:   normalizePatterns(opts.AllowedDomains),
+		denied:    normalizePatterns(opts.DeniedDomains),
+		blocklist: opts.Blocklist,
+	}
+	for _, scheme := range schemes {
+		policy.schemes[strings.ToLower(strings.TrimSpace(scheme))] = struct{}{}
+	}
+
+	return policy
+}
+
+// Check reports why a link may not point to the url, the scheme is checked first,
+// then the denied domains, the blocklist and the allow-list
+func (p *Policy) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if _, ok := p.schemes[scheme]; !ok {
+		return fmt.Errorf("%w: %q", ErrSchemeNotAllowed, scheme)
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return err
+	}
+
+	if matchAny(p.denied, host) {
+		return fmt.Errorf("%w: %q", ErrDomainDenied, host)
+	}
+
+	if p.blocklist != nil && p.blocklist.Match(host) {
+		return fmt.Errorf("%w: %q", ErrDomainBlocked, host)
+	}
+
+	if len(p.allowed) > 0 && !matchAny(p.allowed, host) {
+		return fmt.Errorf("%w: %q", ErrDomainNotAllowed, host)
+	}
+
+	return nil
+}
+
+// normalizeHost brings a host to the lower-case ascii form patterns are matched against
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(host, ".")
+	if host == "" {
+		return "", nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		//ip addresses and other hosts idna rejects are matched as they are
+		return strings.ToLower(host), nil
+	}
+
+	return strings.ToLower(ascii), nil
+}
+
+func normalizePatterns(patterns []string) []string {
+	var normalized []string
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		wildcard := strings.HasPrefix(pattern, "*.")
+		host, _ := normalizeHost(strings.TrimPrefix(pattern, "*."))
+		if wildcard {
+			host = "*." + host
+		}
+		normalized = append(normalized, host)
+	}
+
+	return normalized
+}
+
+func matchAny(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if matchDomain(pattern, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchDomain matches a host against a pattern, "*.example.com" matches subdomains at any depth but not example.com
+func matchDomain(pattern string, host string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix)
+	}
+
+	return host == pattern
+}
@@ -0,0 +1,142 @@
+package urlpolicy
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	tests := map[string]struct {
+		opts    Options
+		url     string
+		wantErr error
+	}{
+		"Default schemes": {
+			url: "https://example.com",
+		},
+		"Javascript": {
+			url:     "javascript:alert(1)",
+			wantErr: ErrSchemeNotAllowed,
+		},
+		"File": {
+			url:     "file:///etc/passwd",
+			wantErr: ErrSchemeNotAllowed,
+		},
+		"Data": {
+			url:     "data:text/html;base64,PHNjcmlwdD4=",
+			wantErr: ErrSchemeNotAllowed,
+		},
+		"Upper case scheme": {
+			url:     "JavaScript:alert(1)",
+			wantErr: ErrSchemeNotAllowed,
+		},
+		"Configured scheme": {
+			opts: Options{Schemes: []string{"https", "mailto"}},
+			url:  "mailto:team@example.com",
+		},
+		"Scheme outside configured list": {
+			opts:    Options{Schemes: []string{"https"}},
+			url:     "http://example.com",
+			wantErr: ErrSchemeNotAllowed,
+		},
+		"Denied domain": {
+			opts:    Options{DeniedDomains: []string{"evil.com"}},
+			url:     "https://EVIL.com./login",
+			wantErr: ErrDomainDenied,
+		},
+		"Denied wildcard": {
+			opts:    Options{DeniedDomains: []string{"*.evil.com"}},
+			url:     "https://login.secure.evil.com",
+			wantErr: ErrDomainDenied,
+		},
+		"Wildcard does not match the apex": {
+			opts: Options{DeniedDomains: []string{"*.evil.com"}},
+			url:  "https://evil.com",
+		},
+		"Wildcard does not match a suffix": {
+			opts: Options{DeniedDomains: []string{"*.evil.com"}},
+			url:  "https://notevil.com",
+		},
+		"Denied IDN domain": {
+			opts:    Options{DeniedDomains: []string{"bücher.example"}},
+			url:     "https://xn--bcher-kva.example",
+			wantErr: ErrDomainDenied,
+		},
+		"Allowed domain": {
+			opts: Options{AllowedDomains: []string{"example.com", "*.example.com"}},
+			url:  "https://docs.example.com",
+		},
+		"Not allowed domain": {
+			opts:    Options{AllowedDomains: []string{"*.example.com"}},
+			url:     "https://example.org",
+			wantErr: ErrDomainNotAllowed,
+		},
+		"Deny wins over allow": {
+			opts:    Options{AllowedDomains: []string{"*"}, DeniedDomains: []string{"evil.com"}},
+			url:     "https://evil.com",
+			wantErr: ErrDomainDenied,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := New(tc.opts).Check(tc.url)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# phishing\nphish.example\n*.malware.test # whole zone\n\n"), 0o644))
+
+	blocklist, err := LoadBlocklist(path)
+	require.NoError(t, err)
+
+	policy := New(Options{Blocklist: blocklist})
+	assert.ErrorIs(t, policy.Check("https://phish.example/login"), ErrDomainBlocked)
+	assert.ErrorIs(t, policy.Check("https://cdn.malware.test/x.exe"), ErrDomainBlocked)
+	assert.NoError(t, policy.Check("https://example.com"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go blocklist.Watch(ctx, 10*time.Millisecond, func(err error) { t.Error(err) })
+
+	require.NoError(t, os.WriteFile(path, []byte("example.com\n"), 0o644))
+	//the modification time may not change within the resolution of the file system
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	require.Eventually(t, func() bool {
+		return policy.Check("https://example.com") != nil
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, policy.Check("https://phish.example/login"))
+}
+
+func TestBlocklist_MissingFile(t *testing.T) {
+	_, err := LoadBlocklist(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
+
+func TestBlocklist_KeepsListOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("phish.example\n"), 0o644))
+
+	blocklist, err := LoadBlocklist(path)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Remove(path))
+	_, err = blocklist.Reload()
+	assert.Error(t, err)
+	assert.True(t, blocklist.Match("phish.example"))
+}
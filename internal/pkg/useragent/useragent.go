@@ -0,0 +1,79 @@
+package useragent
+
+import "strings"
+
+const (
+	OSiOS      = "ios"
+	OSAndroid  = "android"
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSChromeOS = "chromeos"
+	OSLinux    = "linux"
+	OSOther    = "other"
+)
+
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+)
+
+// Agent is the platform parsed from a User-Agent header
+type Agent struct {
+	OS     string
+	Device string
+}
+
+// Parse detects the operating system and the device class of a User-Agent header.
+// Unknown agents are reported as desktops with OSOther.
+func Parse(ua string) Agent {
+	agent := Agent{OS: OSOther, Device: DeviceDesktop}
+
+	switch {
+	case strings.Contains(ua, "iPad"):
+		agent.OS, agent.Device = OSiOS, DeviceTablet
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPod"):
+		agent.OS, agent.Device = OSiOS, DeviceMobile
+	case strings.Contains(ua, "Android"):
+		agent.OS, agent.Device = OSAndroid, DeviceTablet
+		//android phones mark themselves as mobile, tablets do not
+		if strings.Contains(ua, "Mobile") {
+			agent.Device = DeviceMobile
+		}
+	case strings.Contains(ua, "Windows Phone"):
+		agent.OS, agent.Device = OSWindows, DeviceMobile
+	case strings.Contains(ua, "Windows"):
+		agent.OS = OSWindows
+	case strings.Contains(ua, "CrOS"):
+		agent.OS = OSChromeOS
+	case strings.Contains(ua, "Macintosh"), strings.Contains(ua, "Mac OS X"):
+		agent.OS = OSMacOS
+	case strings.Contains(ua, "Linux"):
+		agent.OS = OSLinux
+	}
+
+	if agent.Device == DeviceDesktop && strings.Contains(ua, "Mobi") {
+		agent.Device = DeviceMobile
+	}
+
+	return agent
+}
+
+// crawlers are lower-cased tokens of the agents that fetch links to build previews in chats and social networks
+var crawlers = []string{
+	"facebookexternalhit", "facebot", "twitterbot", "linkedinbot", "slackbot", "discordbot", "telegrambot",
+	"whatsapp", "skypeuripreview", "pinterest", "redditbot", "applebot", "vkshare", "embedly", "iframely",
+	"mastodon", "bitlybot", "google-pagerenderer", "viber", "snapchat", "mattermost", "zoominfobot",
+}
+
+// IsCrawler reports whether the User-Agent header belongs to a known link preview crawler
+func IsCrawler(ua string) bool {
+	ua = strings.ToLower(ua)
+	for _, crawler := range crawlers {
+		if strings.Contains(ua, crawler) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package useragent
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		ua       string
+		expected Agent
+	}{
+		"iPhone": {
+			ua:       "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			expected: Agent{OS: OSiOS, Device: DeviceMobile},
+		},
+		"iPad": {
+			ua:       "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+			expected: Agent{OS: OSiOS, Device: DeviceTablet},
+		},
+		"Android phone": {
+			ua:       "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			expected: Agent{OS: OSAndroid, Device: DeviceMobile},
+		},
+		"Android tablet": {
+			ua:       "Mozilla/5.0 (Linux; Android 13; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			expected: Agent{OS: OSAndroid, Device: DeviceTablet},
+		},
+		"Windows": {
+			ua:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			expected: Agent{OS: OSWindows, Device: DeviceDesktop},
+		},
+		"macOS": {
+			ua:       "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+			expected: Agent{OS: OSMacOS, Device: DeviceDesktop},
+		},
+		"ChromeOS": {
+			ua:       "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			expected: Agent{OS: OSChromeOS, Device: DeviceDesktop},
+		},
+		"Linux": {
+			ua:       "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			expected: Agent{OS: OSLinux, Device: DeviceDesktop},
+		},
+		"Unknown": {
+			ua:       "curl/8.4.0",
+			expected: Agent{OS: OSOther, Device: DeviceDesktop},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Parse(tc.ua))
+		})
+	}
+}
+
+func TestIsCrawler(t *testing.T) {
+	tests := map[string]struct {
+		ua       string
+		expected bool
+	}{
+		"Facebook": {ua: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", expected: true},
+		"Twitter":  {ua: "Twitterbot/1.0", expected: true},
+		"Slack":    {ua: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", expected: true},
+		"Discord":  {ua: "Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)", expected: true},
+		"Telegram": {ua: "TelegramBot (like TwitterBot)", expected: true},
+		"WhatsApp": {ua: "WhatsApp/2.23.20.0", expected: true},
+		"LinkedIn": {ua: "LinkedInBot/1.0 (compatible; Mozilla/5.0; Apache-HttpClient +http://www.linkedin.com)", expected: true},
+		"Browser":  {ua: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", expected: false},
+		"curl":     {ua: "curl/8.4.0", expected: false},
+		"Empty":    {ua: "", expected: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsCrawler(tc.ua))
+		})
+	}
+}
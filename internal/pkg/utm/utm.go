@@ -0,0 +1,37 @@
+package utm
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Param is a single utm parameter, e.g. utm_source=newsletter
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Apply adds the parameters to the target url query.
+// Parameters with an empty value and keys that the target url already has are skipped,
+// so values set explicitly on a link always win over its template.
+func Apply(target *url.URL, params ...Param) {
+	existing := target.Query()
+
+	var added []string
+	for _, param := range params {
+		if param.Value == "" || existing.Has(param.Key) {
+			continue
+		}
+		added = append(added, url.QueryEscape(param.Key)+"="+url.QueryEscape(param.Value))
+	}
+
+	if len(added) == 0 {
+		return
+	}
+
+	if target.RawQuery != "" {
+		added = append([]string{target.RawQuery}, added...)
+	}
+
+	target.RawQuery = strings.Join(added, "&")
+}
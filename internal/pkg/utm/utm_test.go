@@ -0,0 +1,52 @@
+package utm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := map[string]struct {
+		target   string
+		params   []Param
+		expected string
+	}{
+		"Target without query": {
+			target:   "https://example.com/a",
+			params:   []Param{{Key: "utm_source", Value: "newsletter"}, {Key: "utm_medium", Value: "email"}},
+			expected: "https://example.com/a?utm_source=newsletter&utm_medium=email",
+		},
+		"Existing query is kept": {
+			target:   "https://example.com/a?id=7",
+			params:   []Param{{Key: "utm_source", Value: "newsletter"}},
+			expected: "https://example.com/a?id=7&utm_source=newsletter",
+		},
+		"Explicit target value wins": {
+			target:   "https://example.com/a?utm_source=partner",
+			params:   []Param{{Key: "utm_source", Value: "newsletter"}, {Key: "utm_campaign", Value: "spring"}},
+			expected: "https://example.com/a?utm_source=partner&utm_campaign=spring",
+		},
+		"Empty values are skipped": {
+			target:   "https://example.com/a",
+			params:   []Param{{Key: "utm_source", Value: ""}},
+			expected: "https://example.com/a",
+		},
+		"Values are escaped": {
+			target:   "https://example.com/a#top",
+			params:   []Param{{Key: "utm_campaign", Value: "spring sale & more"}},
+			expected: "https://example.com/a?utm_campaign=spring+sale+%26+more#top",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			target, err := url.Parse(tc.target)
+			require.NoError(t, err)
+
+			Apply(target, tc.params...)
+			assert.Equal(t, tc.expected, target.String())
+		})
+	}
+}
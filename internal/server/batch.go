@@ -0,0 +1,187 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/storage"
+	"time"
+)
+
+// MaxBatchSize limits the number of links created by one batch request
+const MaxBatchSize = 10000
+
+// BatchRequest creates several links at once, in atomic mode either all links are saved or none
+type BatchRequest struct {
+	Items  []Request `json:"items"`
+	Atomic bool      `json:"atomic,omitempty"`
+}
+
+// BatchResponse holds the outcome of every item in the order of the request
+type BatchResponse struct {
+	resp.Response
+	Results []Response `json:"results,omitempty"`
+}
+
+const handlersOperationSaveBatch = "handlers.url.batch"
+
+const errBatchAborted = "not saved: batch aborted"
+
+func (ro *router) saveBatchHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationSaveBatch),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	var req BatchRequest
+	err := render.DecodeJSON(r.Body, &req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return
+	}
+
+	if len(req.Items) == 0 || len(req.Items) > MaxBatchSize {
+		log.Info("invalid batch size", slog.Int("size", len(req.Items)))
+		render.JSON(w, r, resp.Error(fmt.Sprintf("invalid request: batch must contain from 1 to %d items", MaxBatchSize)))
+
+		return
+	}
+
+	log.Info("batch decoded successfully", slog.Int("size", len(req.Items)), slog.Bool("atomic", req.Atomic))
+
+	results := make([]Response, len(req.Items))
+	links := make([]storage.Link, 0, len(req.Items))
+	//positions of the valid items, links are saved in the same order
+	positions := make([]int, 0, len(req.Items))
+	failed := false
+	for i, item := range req.Items {
+		link, msg := ro.linkFromRequest(workspaceID(r), item)
+		if msg != "" {
+			results[i] = Response{Response: resp.Error(msg)}
+			failed = true
+
+			continue
+		}
+		link.Owner = caller(r)
+
+		if item.ReuseExisting {
+			reused, err := ro.existingAlias(link)
+			if err != nil {
+				results[i] = Response{Response: resp.Error("failed to save url")}
+				failed = true
+
+				continue
+			}
+
+			if reused != "" {
+				results[i] = Response{Response: resp.OK(), Alias: reused, Reused: true}
+
+				continue
+			}
+		}
+
+		links = append(links, link)
+		positions = append(positions, i)
+	}
+
+	if failed && req.Atomic {
+		log.Info("batch has invalid items")
+		render.JSON(w, r, BatchResponse{
+			Response: resp.Error("batch contains invalid items"),
+			Results:  abortedResults(results),
+		})
+
+		return
+	}
+
+	//the whole batch must fit the quota of the caller, links that are not saved are given back
+	now := time.Now()
+	if !ro.reserveLinks(w, r, int64(len(links)), now) {
+		return
+	}
+
+	saved, err := ro.storage.SaveLinks(links, req.Atomic)
+	ro.releaseLinks(r, unsaved(links, saved, err), now)
+	if err != nil && !errors.Is(err, storage.ErrBatchAborted) {
+		log.Error("failed to save batch", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to save urls"))
+
+		return
+	}
+
+	for j, result := range saved {
+		i := positions[j]
+		switch {
+		case errors.Is(result.Err, storage.ErrURLAlreadyExists):
+			results[i] = Response{Response: resp.Error("url already exists"), Alias: links[j].Alias}
+		case errors.Is(result.Err, storage.ErrFolderNotFound):
+			results[i] = Response{Response: resp.Error("folder not found"), Alias: links[j].Alias}
+		case errors.Is(result.Err, storage.ErrLinkQuotaExceeded):
+			results[i] = Response{Response: resp.Error("workspace link quota exceeded"), Alias: links[j].Alias}
+		case result.Err != nil:
+			log.Error("failed to save url", slog.String("alias", links[j].Alias), slo.Err(result.Err))
+			results[i] = Response{Response: resp.Error("failed to save url"), Alias: links[j].Alias}
+		default:
+			results[i] = Response{Response: resp.OK(), Alias: links[j].Alias}
+		}
+	}
+
+	if err != nil {
+		log.Info("batch aborted", slo.Err(err))
+		render.JSON(w, r, BatchResponse{
+			Response: resp.Error("batch aborted"),
+			Results:  abortedResults(results),
+		})
+
+		return
+	}
+
+	log.Info("batch saved", slog.Int("size", len(saved)))
+
+	render.JSON(w, r, BatchResponse{
+		Response: resp.OK(),
+		Results:  results,
+	})
+}
+
+// abortedResults marks every item of a rolled back batch that has no error of its own
+func abortedResults(results []Response) []Response {
+	for i := range results {
+		if results[i].Status != resp.StatusError {
+			results[i] = Response{Response: resp.Error(errBatchAborted), Alias: results[i].Alias}
+		}
+	}
+
+	return results
+}
+
+// unsaved returns the number of links of a batch that were not saved, an aborted batch saves none
+func unsaved(links []storage.Link, saved []storage.SaveResult, err error) int64 {
+	if err != nil {
+		return int64(len(links))
+	}
+
+	var count int64
+	for _, result := range saved {
+		if result.Err != nil {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestSaveBatchHandler(t *testing.T) {
+	tests := map[string]struct {
+		input        string
+		expectedResp BatchResponse
+		prepare      func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Success": {
+			input: `{"items": [{"url": "https://example.com/a", "alias": "aaaaa"}, {"url": "https://example.com/b", "alias": "bbbbb"}]}`,
+			expectedResp: BatchResponse{
+				Response: resp.OK(),
+				Results: []Response{
+					{Response: resp.OK(), Alias: "aaaaa"},
+					{Response: resp.OK(), Alias: "bbbbb"},
+				},
+			},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveLinks([]storage.Link{
+					{Alias: "aaaaa", URL: "https://example.com/a", OriginalURL: "https://example.com/a"},
+					{Alias: "bbbbb", URL: "https://example.com/b", OriginalURL: "https://example.com/b"},
+				}, false).Return([]storage.SaveResult{{ID: 1}, {ID: 2}}, nil)
+			},
+		},
+		"Partial": {
+			input: `{"items": [{"url": "not a url", "alias": "aaaaa"}, {"url": "https://example.com/b", "alias": "bbbbb"}, {"url": "https://example.com/c", "alias": "ccccc"}]}`,
+			expectedResp: BatchResponse{
+				Response: resp.OK(),
+				Results: []Response{
+					{Response: resp.Error("\"URL\" is not a valid URL")},
+					{Response: resp.Error("url already exists"), Alias: "bbbbb"},
+					{Response: resp.OK(), Alias: "ccccc"},
+				},
+			},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveLinks([]storage.Link{
+					{Alias: "bbbbb", URL: "https://example.com/b", OriginalURL: "https://example.com/b"},
+					{Alias: "ccccc", URL: "https://example.com/c", OriginalURL: "https://example.com/c"},
+				}, false).Return([]storage.SaveResult{
+					{Err: fmt.Errorf("%s: %w", "storage.sqlite.SaveLinks", storage.ErrURLAlreadyExists)},
+					{ID: 3},
+				}, nil)
+			},
+		},
+		"Atomic: invalid item": {
+			input: `{"atomic": true, "items": [{"url": "https://example.com/a", "alias": "aaaaa"}, {"alias": "bbbbb"}]}`,
+			expectedResp: BatchResponse{
+				Response: resp.Error("batch contains invalid items"),
+				Results: []Response{
+					{Response: resp.Error(errBatchAborted)},
+					{Response: resp.Error("\"URL\" field is mandatory")},
+				},
+			},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Atomic: aborted": {
+			input: `{"atomic": true, "items": [{"url": "https://example.com/a", "alias": "aaaaa"}, {"url": "https://example.com/b", "alias": "bbbbb"}, {"url": "https://example.com/c", "alias": "ccccc"}]}`,
+			expectedResp: BatchResponse{
+				Response: resp.Error("batch aborted"),
+				Results: []Response{
+					{Response: resp.Error(errBatchAborted), Alias: "aaaaa"},
+					{Response: resp.Error("url already exists"), Alias: "bbbbb"},
+					{Response: resp.Error(errBatchAborted)},
+				},
+			},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveLinks(gomock.Len(3), true).Return([]storage.SaveResult{
+					{ID: 1},
+					{Err: storage.ErrURLAlreadyExists},
+				}, fmt.Errorf("%s: %w", "storage.sqlite.SaveLinks", storage.ErrBatchAborted))
+			},
+		},
+		"Empty batch": {
+			input:        `{"items": []}`,
+			expectedResp: BatchResponse{Response: resp.Error("invalid request: batch must contain from 1 to 10000 items")},
+			prepare:      func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Storage failure": {
+			input:        `{"items": [{"url": "https://example.com/a", "alias": "aaaaa"}]}`,
+			expectedResp: BatchResponse{Response: resp.Error("failed to save urls")},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveLinks(gomock.Any(), false).Return(nil, fmt.Errorf("database is locked"))
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectLinkCounts(mockStorage)
+			tc.prepare(mockStorage)
+
+			r := &router{
+				storage: mockStorage,
+				log:     slog.Default(),
+			}
+
+			chiRouter := chi.NewRouter()
+			chiRouter.Post("/v1/url/batch", r.saveBatchHandler)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/url/batch", bytes.NewBufferString(tc.input))
+			w := httptest.NewRecorder()
+			chiRouter.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response BatchResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.expectedResp, response)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/urlnorm"
+	"shorty/internal/storage"
+	"strings"
+)
+
+// chains follows targets of links that point to other short links of the service
+type chains struct {
+	//base is the normalized prefix short links are served under, nil if the checks are disabled
+	base     *url.URL
+	maxDepth int
+}
+
+func newChains(baseURL string, maxDepth int) (chains, error) {
+	if baseURL == "" {
+		return chains{}, nil
+	}
+
+	normalized, err := urlnorm.Normalize(baseURL, urlnorm.Options{})
+	if err != nil {
+		return chains{}, fmt.Errorf("invalid base url %q: %w", baseURL, err)
+	}
+
+	base, err := url.Parse(normalized)
+	if err != nil || base.Host == "" {
+		return chains{}, fmt.Errorf("invalid base url %q", baseURL)
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/")
+
+	return chains{base: base, maxDepth: maxDepth}, nil
+}
+
+// internal reports whether the target is served by this service, alias is the short link it points to,
+// empty if the target is another page of the service
+func (c chains) internal(target string) (alias string, ok bool) {
+	host, alias, ok := c.route(target)
+	if !ok || !strings.EqualFold(host, c.base.Host) {
+		return "", false
+	}
+
+	return alias, true
+}
+
+// route splits a target with a path under the base url path into its host and the alias it points to,
+// custom domains serve aliases under the same path as the base url
+func (c chains) route(target string) (host string, alias string, ok bool) {
+	normalized, err := urlnorm.Normalize(target, urlnorm.Options{})
+	if err != nil {
+		return "", "", false
+	}
+
+	u, err := url.Parse(normalized)
+	if err != nil {
+		return "", "", false
+	}
+
+	rest, found := strings.CutPrefix(u.Path, c.base.Path+"/")
+	if !found {
+		return u.Host, "", u.Path == c.base.Path
+	}
+
+	alias, _, _ = strings.Cut(rest, "/")
+
+	return u.Host, alias, true
+}
+
+// chainTarget returns the domain and the alias of the short link the target points to, the base url serves
+// the links of the workspace. ok is false if the target is served neither under the base url nor on a registered custom domain
+func (ro *router) chainTarget(workspaceID int64, target string) (domain storage.Domain, alias string, ok bool, err error) {
+	host, alias, ok := ro.chains.route(target)
+	if !ok {
+		return storage.Domain{}, "", false, nil
+	}
+
+	if strings.EqualFold(host, ro.chains.base.Host) {
+		return storage.Domain{WorkspaceID: workspaceID}, alias, true, nil
+	}
+
+	custom, err := ro.storage.ResolveDomain(normalizeHost(host))
+	if errors.Is(err, storage.ErrDomainNotFound) {
+		return storage.Domain{}, "", false, nil
+	}
+
+	if err != nil {
+		return storage.Domain{}, "", false, err
+	}
+
+	return custom, alias, true, nil
+}
+
+// chainKey identifies a short link across domains, custom domains belong to a single workspace
+func chainKey(domain string, alias string) string {
+	if domain == "" {
+		return alias
+	}
+
+	return domain + "/" + alias
+}
+
+// checkChain returns a human-readable error text for a client if a target of the link with the alias
+// on the domain of the workspace leads back to it or passes through more short links than allowed, or an empty string otherwise.
+// Targets pointing to aliases that do not exist yet end the chain.
+func (ro *router) checkChain(workspaceID int64, domain string, alias string, targets ...string) string {
+	if ro.chains.base == nil {
+		return ""
+	}
+
+	key := chainKey(domain, alias)
+	msg, err := ro.followChain(workspaceID, key, targets, map[string]bool{key: true}, 1)
+	if err != nil {
+		ro.log.Error("failed to follow redirect chain", slog.String("alias", alias), slo.Err(err))
+
+		return "failed to check redirect chain"
+	}
+
+	if msg != "" {
+		ro.log.Info("redirect chain rejected", slog.String("alias", alias), slog.String("reason", msg))
+	}
+
+	return msg
+}
+
+// checkRename returns a human-readable error text for a client if the link with the old alias may not be renamed
+// to the new alias because its targets would lead back to it or pass through more short links than allowed
+func (ro *router) checkRename(workspaceID int64, domain string, oldAlias string, newAlias string) string {
+	if ro.chains.base == nil {
+		return ""
+	}
+
+	link, err := ro.storage.GetLink(workspaceID, domain, oldAlias)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		return "url not found for given alias"
+	}
+
+	if err != nil {
+		ro.log.Error("failed to get link", slog.String("alias", oldAlias), slo.Err(err))
+
+		return "failed to check redirect chain"
+	}
+
+	return ro.checkChain(workspaceID, domain, newAlias, linkTargets(link)...)
+}
+
+// followChain walks the short links the targets point to, path holds the chain keys of the current chain
+func (ro *router) followChain(workspaceID int64, key string, targets []string, path map[string]bool, depth int) (string, error) {
+	for _, target := range targets {
+		domain, alias, ok, err := ro.chainTarget(workspaceID, target)
+		if err != nil {
+			return "", err
+		}
+
+		if !ok {
+			continue
+		}
+
+		next := chainKey(domain.Host, alias)
+		if next == key && depth == 1 {
+			return "invalid request: link points to itself", nil
+		}
+
+		if path[next] {
+			return fmt.Sprintf("invalid request: redirect loop through %q", next), nil
+		}
+
+		if depth > ro.chains.maxDepth {
+			if ro.chains.maxDepth == 0 {
+				return "invalid request: links to this service are not allowed", nil
+			}
+
+			return fmt.Sprintf("invalid request: redirect chain is longer than %d links", ro.chains.maxDepth), nil
+		}
+
+		if alias == "" {
+			continue
+		}
+
+		link, err := ro.storage.GetLink(domain.WorkspaceID, domain.Host, alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		path[next] = true
+		msg, err := ro.followChain(workspaceID, key, linkTargets(link), path, depth+1)
+		delete(path, next)
+		if msg != "" || err != nil {
+			return msg, err
+		}
+	}
+
+	return "", nil
+}
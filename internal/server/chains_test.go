@@ -0,0 +1,250 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestChains_Internal(t *testing.T) {
+	c, err := newChains("https://Sho.rt:443/v1/", 3)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		target   string
+		alias    string
+		internal bool
+	}{
+		"alias":           {target: "https://sho.rt/v1/abcde", alias: "abcde", internal: true},
+		"alias with path": {target: "https://SHO.RT/v1/abcde/docs?x=1", alias: "abcde", internal: true},
+		"other scheme":    {target: "http://sho.rt/v1/abcde", alias: "abcde", internal: true},
+		"base url":        {target: "https://sho.rt/v1", internal: true},
+		"other page":      {target: "https://sho.rt/about", internal: false},
+		"other host":      {target: "https://example.com/v1/abcde", internal: false},
+		"subdomain":       {target: "https://www.sho.rt/v1/abcde", internal: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			alias, internal := c.internal(tc.target)
+			assert.Equal(t, tc.internal, internal)
+			assert.Equal(t, tc.alias, alias)
+		})
+	}
+}
+
+func TestSaveHandler_Chains(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		maxDepth int
+		wantErr  string
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Self reference": {
+			input:    `{"url": "https://sho.rt/v1/loopy", "alias": "loopy"}`,
+			maxDepth: 3,
+			wantErr:  "invalid request: link points to itself",
+		},
+		"Cycle": {
+			input:    `{"url": "https://sho.rt/v1/first", "alias": "third"}`,
+			maxDepth: 3,
+			wantErr:  `invalid request: redirect loop through "third"`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "first").Return(storage.Link{Alias: "first", URL: "https://example.com/",
+					Rules: []storage.Rule{{OS: "ios", Target: "https://sho.rt/v1/secnd"}}}, nil)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "secnd").Return(storage.Link{Alias: "secnd", URL: "https://sho.rt/v1/third"}, nil)
+			},
+		},
+		"Loop through a custom domain": {
+			input:    `{"url": "https://go.brand.com/v1/promo", "alias": "third"}`,
+			maxDepth: 3,
+			wantErr:  `invalid request: redirect loop through "third"`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("go.brand.com").Return(storage.Domain{ID: 1, Host: "go.brand.com"}, nil)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "go.brand.com", "promo").Return(storage.Link{Domain: "go.brand.com", Alias: "promo", URL: "https://sho.rt/v1/third"}, nil)
+			},
+		},
+		"Same alias on a custom domain": {
+			input:    `{"url": "https://sho.rt/v1/promo", "alias": "promo", "domain": "go.brand.com"}`,
+			maxDepth: 3,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetDomain(storage.DefaultWorkspace, "go.brand.com").Return(storage.Domain{ID: 1, Host: "go.brand.com"}, nil)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "promo").Return(storage.Link{Alias: "promo", URL: "https://example.com"}, nil)
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).Return(int64(1), nil)
+			},
+		},
+		"Chain too long": {
+			input:    `{"url": "https://sho.rt/v1/first", "alias": "third"}`,
+			maxDepth: 1,
+			wantErr:  "invalid request: redirect chain is longer than 1 links",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "first").Return(storage.Link{Alias: "first", URL: "https://sho.rt/v1/secnd"}, nil)
+			},
+		},
+		"Internal targets disabled": {
+			input:    `{"url": "https://example.com", "alias": "third", "fallback_url": "https://sho.rt/v1/first"}`,
+			maxDepth: 0,
+			wantErr:  "invalid request: links to this service are not allowed",
+		},
+		"Storage error": {
+			input:    `{"url": "https://sho.rt/v1/first", "alias": "third"}`,
+			maxDepth: 3,
+			wantErr:  "failed to check redirect chain",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "first").Return(storage.Link{}, errors.New("database is locked"))
+			},
+		},
+		"Success: chain within depth": {
+			input:    `{"url": "https://sho.rt/v1/first", "alias": "third"}`,
+			maxDepth: 3,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "first").Return(storage.Link{Alias: "first", URL: "https://sho.rt/v1/secnd"}, nil)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "secnd").Return(storage.Link{}, storage.ErrURLNotFound)
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).Return(int64(1), nil)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectLinkCounts(mockStorage)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			cfg := config.Config{BaseURL: "https://sho.rt/v1", RedirectChains: config.RedirectChains{MaxDepth: tc.maxDepth}}
+			r := SetupRouter(mockStorage, cfg, slog.Default())
+			req := httptest.NewRequest(http.MethodPost, "/v1/url/", bytes.NewReader([]byte(tc.input)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.wantErr, response.Error)
+		})
+	}
+}
+
+func TestUpdateDomainHandler_Chains(t *testing.T) {
+	brand := storage.Domain{ID: 1, Host: "go.brand.com"}
+
+	tests := map[string]struct {
+		input   string
+		wantErr string
+		prepare func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Self reference": {
+			input:   `{"default_url": "https://go.brand.com/v1"}`,
+			wantErr: "invalid request: link points to itself",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("go.brand.com").Return(brand, nil)
+			},
+		},
+		"Loop through a link": {
+			input:   `{"default_url": "https://sho.rt/v1/promo"}`,
+			wantErr: `invalid request: redirect loop through "go.brand.com/"`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "promo").Return(storage.Link{Alias: "promo", URL: "https://go.brand.com/v1"}, nil)
+				mockUrlProvider.EXPECT().ResolveDomain("go.brand.com").Return(brand, nil)
+			},
+		},
+		"Success": {
+			input: `{"default_url": "https://sho.rt/v1/promo"}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "promo").Return(storage.Link{Alias: "promo", URL: "https://example.com"}, nil)
+				mockUrlProvider.EXPECT().UpdateDomain(storage.Domain{Host: "go.brand.com", DefaultURL: "https://sho.rt/v1/promo"}).Return(nil)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			tc.prepare(mockStorage)
+
+			cfg := config.Config{BaseURL: "https://sho.rt/v1", RedirectChains: config.RedirectChains{MaxDepth: 3}}
+			r := SetupRouter(mockStorage, cfg, slog.Default())
+			req := httptest.NewRequest(http.MethodPut, "/v1/domains/go.brand.com", bytes.NewReader([]byte(tc.input)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.wantErr, response.Error)
+		})
+	}
+}
+
+func TestUpdateHandler_Chains(t *testing.T) {
+	tests := map[string]struct {
+		wantErr string
+		prepare func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Loop": {
+			wantErr: `invalid request: redirect loop through "third"`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "renme").Return(storage.Link{Alias: "renme", URL: "https://sho.rt/v1/first"}, nil)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "first").Return(storage.Link{Alias: "first", URL: "https://sho.rt/v1/third"}, nil)
+			},
+		},
+		"Not found": {
+			wantErr: "url not found for given alias",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "renme").Return(storage.Link{}, storage.ErrURLNotFound)
+			},
+		},
+		"Success": {
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "renme").Return(storage.Link{Alias: "renme", URL: "https://sho.rt/v1/first"}, nil)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "first").Return(storage.Link{Alias: "first", URL: "https://example.com"}, nil)
+				mockUrlProvider.EXPECT().UpdateAlias(storage.DefaultWorkspace, "", "renme", "third").Return(nil)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			tc.prepare(mockStorage)
+
+			cfg := config.Config{BaseURL: "https://sho.rt/v1", RedirectChains: config.RedirectChains{MaxDepth: 3}}
+			r := SetupRouter(mockStorage, cfg, slog.Default())
+			req := httptest.NewRequest(http.MethodPatch, "/v1/url/renme", bytes.NewReader([]byte(`{"new_alias": "third"}`)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.wantErr, response.Error)
+		})
+	}
+}
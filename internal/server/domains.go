@@ -0,0 +1,352 @@
+package server
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/storage"
+	"strconv"
+	"strings"
+)
+
+type Domain struct {
+	Host string `json:"host" validate:"required,hostname"`
+	//DefaultURL is the target of the root and of unknown aliases of the domain
+	DefaultURL string `json:"default_url,omitempty" validate:"omitempty,http_url"`
+}
+
+type DomainResponse struct {
+	resp.Response
+	Domain *Domain `json:"domain,omitempty"`
+}
+
+type DomainsResponse struct {
+	resp.Response
+	Domains []Domain `json:"domains"`
+}
+
+const (
+	handlersOperationSaveDomain   = "handlers.domain.save"
+	handlersOperationGetDomain    = "handlers.domain.get"
+	handlersOperationListDomains  = "handlers.domain.list"
+	handlersOperationUpdateDomain = "handlers.domain.update"
+	handlersOperationDeleteDomain = "handlers.domain.delete"
+	handlersOperationRoot         = "handlers.domain.root"
+)
+
+func (ro *router) saveDomainHandler(w http.ResponseWriter, r *http.Request) {
+	var req Domain
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationSaveDomain),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	if !decodeDomain(w, r, log, &req) || !ro.checkDefaultURL(w, r, req) {
+		return
+	}
+
+	_, err := ro.storage.SaveDomain(req.toStorage(workspaceID(r)))
+	if errors.Is(err, storage.ErrDomainAlreadyExists) {
+		log.Info("domain already exists", slog.String("host", req.Host))
+		render.JSON(w, r, resp.Error("domain already exists"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to save domain", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to save domain"))
+
+		return
+	}
+
+	log.Info("domain successfully saved", slog.String("host", req.Host))
+	render.JSON(w, r, DomainResponse{
+		Response: resp.OK(),
+		Domain:   &req,
+	})
+}
+
+func (ro *router) getDomainHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationGetDomain),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	host := hostParam(r)
+	domain, err := ro.storage.GetDomain(workspaceID(r), host)
+	if errors.Is(err, storage.ErrDomainNotFound) {
+		log.Info("domain not found", slog.String("host", host))
+		render.JSON(w, r, resp.Error("domain not found"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to get domain", slog.String("host", host), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	result := domainFromStorage(domain)
+	render.JSON(w, r, DomainResponse{
+		Response: resp.OK(),
+		Domain:   &result,
+	})
+}
+
+func (ro *router) listDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationListDomains),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	domains, err := ro.storage.ListDomains(workspaceID(r))
+	if err != nil {
+		log.Error("failed to list domains", slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	result := make([]Domain, 0, len(domains))
+	for _, domain := range domains {
+		result = append(result, domainFromStorage(domain))
+	}
+
+	render.JSON(w, r, DomainsResponse{
+		Response: resp.OK(),
+		Domains:  result,
+	})
+}
+
+func (ro *router) updateDomainHandler(w http.ResponseWriter, r *http.Request) {
+	var req Domain
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationUpdateDomain),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	//the host in the path identifies the domain, links are bound to it and it can not be changed
+	host := hostParam(r)
+	req.Host = host
+	if !decodeDomain(w, r, log, &req) {
+		return
+	}
+
+	if req.Host != host {
+		log.Info("domain host can not be changed", slog.String("host", req.Host))
+		render.JSON(w, r, resp.Error("invalid request: domain host can not be changed"))
+
+		return
+	}
+
+	if !ro.checkDefaultURL(w, r, req) {
+		return
+	}
+
+	err := ro.storage.UpdateDomain(req.toStorage(workspaceID(r)))
+	if errors.Is(err, storage.ErrDomainNotFound) {
+		log.Info("domain not found", slog.String("host", host))
+		render.JSON(w, r, resp.Error("domain not found"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to update domain", slog.String("host", host), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	log.Info("domain successfully updated", slog.String("host", host))
+	render.JSON(w, r, DomainResponse{
+		Response: resp.OK(),
+		Domain:   &req,
+	})
+}
+
+func (ro *router) deleteDomainHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationDeleteDomain),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	host := hostParam(r)
+	err := ro.storage.DeleteDomain(workspaceID(r), host)
+	if errors.Is(err, storage.ErrDomainNotFound) {
+		log.Info("domain not found", slog.String("host", host))
+		render.JSON(w, r, resp.Error("domain not found"))
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrDomainInUse) {
+		log.Info("domain has links", slog.String("host", host))
+		render.JSON(w, r, resp.Error("domain has links, delete them first"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to delete domain", slog.String("host", host), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	log.Info("domain successfully deleted", slog.String("host", host))
+	render.JSON(w, r, resp.OK())
+}
+
+// rootHandler sends visitors of the root of a custom domain to its default url
+func (ro *router) rootHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationRoot),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	domain, err := ro.requestDomain(r)
+	if err != nil {
+		log.Error("failed to get domain", slog.String("host", r.Host), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	if domain.DefaultURL == "" {
+		if ro.renderErrorPage(w, r, http.StatusNotFound) {
+			return
+		}
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, resp.Error("not found"))
+
+		return
+	}
+
+	http.Redirect(w, r, domain.DefaultURL, http.StatusFound)
+}
+
+// requestDomain returns the custom domain the request was sent to, requests to hosts that are not registered
+// are served by the default domain with the links of the default workspace, visitors are not authenticated
+func (ro *router) requestDomain(r *http.Request) (storage.Domain, error) {
+	domain, err := ro.storage.ResolveDomain(normalizeHost(r.Host))
+	if errors.Is(err, storage.ErrDomainNotFound) {
+		return storage.Domain{WorkspaceID: storage.DefaultWorkspace}, nil
+	}
+
+	return domain, err
+}
+
+// hostParam returns the host in the path of a domain request,
+// middleware.URLFormat takes the last label of the host for a format extension
+func hostParam(r *http.Request) string {
+	host := chi.URLParam(r, "host")
+	if format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string); format != "" {
+		host += "." + format
+	}
+
+	return normalizeHost(host)
+}
+
+// domainParam returns the domain of the link a management request refers to, empty for the default domain
+func domainParam(r *http.Request) string {
+	return normalizeHost(r.URL.Query().Get("domain"))
+}
+
+// normalizeHost lowercases a host and drops its port and the trailing dot
+func normalizeHost(host string) string {
+	if hostname, port, err := net.SplitHostPort(host); err == nil && validPort(port) {
+		host = hostname
+	}
+
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+func validPort(port string) bool {
+	_, err := strconv.ParseUint(port, 10, 16)
+
+	return err == nil
+}
+
+// decodeDomain decodes and validates a domain from the request body,
+// it writes an error response and returns false if the domain is not valid
+func decodeDomain(w http.ResponseWriter, r *http.Request, log *slog.Logger, req *Domain) bool {
+	err := render.DecodeJSON(r.Body, req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+
+		return false
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return false
+	}
+
+	req.Host = normalizeHost(req.Host)
+
+	err = validator.New().Struct(req)
+	if err != nil {
+		validateErr := err.(validator.ValidationErrors)
+		log.Error("invalid request", slo.Err(err))
+		render.JSON(w, r, resp.ValidationError(validateErr))
+
+		return false
+	}
+
+	return true
+}
+
+// checkDefaultURL runs the destination checks of links on the default url of the domain,
+// it writes an error response and returns false if the url may not be published
+func (ro *router) checkDefaultURL(w http.ResponseWriter, r *http.Request, req Domain) bool {
+	if req.DefaultURL == "" {
+		return true
+	}
+
+	if msg := ro.checkTargets(req.DefaultURL); msg != "" {
+		render.JSON(w, r, resp.Error(msg))
+
+		return false
+	}
+
+	//the default url is served on the root of the domain
+	if msg := ro.checkChain(workspaceID(r), req.Host, "", req.DefaultURL); msg != "" {
+		render.JSON(w, r, resp.Error(msg))
+
+		return false
+	}
+
+	return true
+}
+
+func (d Domain) toStorage(workspaceID int64) storage.Domain {
+	return storage.Domain{
+		WorkspaceID: workspaceID,
+		Host:        d.Host,
+		DefaultURL:  d.DefaultURL,
+	}
+}
+
+func domainFromStorage(d storage.Domain) Domain {
+	return Domain{
+		Host:       d.Host,
+		DefaultURL: d.DefaultURL,
+	}
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+)
+
+// expectDefaultDomain serves requests to any host by the default domain
+func expectDefaultDomain(mockUrlProvider *mocks.MockUrlProvider) {
+	mockUrlProvider.EXPECT().ResolveDomain(gomock.Any()).Return(storage.Domain{}, storage.ErrDomainNotFound).AnyTimes()
+}
+
+func TestDomainHandlers(t *testing.T) {
+	brand := storage.Domain{ID: 1, Host: "go.brand.com", DefaultURL: "https://brand.com"}
+
+	tests := map[string]struct {
+		method   string
+		path     string
+		input    string
+		wantErr  error
+		expected string
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Save: success": {
+			method:   http.MethodPost,
+			path:     "/v1/domains",
+			input:    `{"host": "Go.Brand.com", "default_url": "https://brand.com"}`,
+			expected: `{"status":"ok","domain":{"host":"go.brand.com","default_url":"https://brand.com"}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveDomain(storage.Domain{Host: "go.brand.com", DefaultURL: "https://brand.com"}).Return(int64(1), nil)
+			},
+		},
+		"Save: invalid host": {
+			method:  http.MethodPost,
+			path:    "/v1/domains",
+			input:   `{"host": "https://go.brand.com"}`,
+			wantErr: errors.New("\"Host\" field is not valid"),
+		},
+		"Save: invalid default url": {
+			method:  http.MethodPost,
+			path:    "/v1/domains",
+			input:   `{"host": "go.brand.com", "default_url": "ftp://brand.com"}`,
+			wantErr: errors.New("\"DefaultURL\" field is not valid"),
+		},
+		"Save: already exists": {
+			method:  http.MethodPost,
+			path:    "/v1/domains",
+			input:   `{"host": "go.brand.com"}`,
+			wantErr: errors.New("domain already exists"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveDomain(gomock.Any()).Return(int64(0), storage.ErrDomainAlreadyExists)
+			},
+		},
+		"Get: success": {
+			method:   http.MethodGet,
+			path:     "/v1/domains/go.brand.com",
+			expected: `{"status":"ok","domain":{"host":"go.brand.com","default_url":"https://brand.com"}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetDomain(storage.DefaultWorkspace, "go.brand.com").Return(brand, nil)
+			},
+		},
+		"Get: not found": {
+			method:  http.MethodGet,
+			path:    "/v1/domains/go.brand.com",
+			wantErr: errors.New("domain not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetDomain(storage.DefaultWorkspace, "go.brand.com").Return(storage.Domain{}, storage.ErrDomainNotFound)
+			},
+		},
+		"List: success": {
+			method:   http.MethodGet,
+			path:     "/v1/domains",
+			expected: `{"status":"ok","domains":[{"host":"go.brand.com","default_url":"https://brand.com"}]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ListDomains(storage.DefaultWorkspace).Return([]storage.Domain{brand}, nil)
+			},
+		},
+		"Update: success": {
+			method:   http.MethodPut,
+			path:     "/v1/domains/go.brand.com",
+			input:    `{"default_url": "https://brand.com/home"}`,
+			expected: `{"status":"ok","domain":{"host":"go.brand.com","default_url":"https://brand.com/home"}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().UpdateDomain(storage.Domain{Host: "go.brand.com", DefaultURL: "https://brand.com/home"}).Return(nil)
+			},
+		},
+		"Update: change host": {
+			method:  http.MethodPut,
+			path:    "/v1/domains/go.brand.com",
+			input:   `{"host": "go.other.com"}`,
+			wantErr: errors.New("invalid request: domain host can not be changed"),
+		},
+		"Delete: success": {
+			method:   http.MethodDelete,
+			path:     "/v1/domains/go.brand.com",
+			expected: `{"status":"ok"}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().DeleteDomain(storage.DefaultWorkspace, "go.brand.com").Return(nil)
+			},
+		},
+		"Delete: in use": {
+			method:  http.MethodDelete,
+			path:    "/v1/domains/go.brand.com",
+			wantErr: errors.New("domain has links, delete them first"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().DeleteDomain(storage.DefaultWorkspace, "go.brand.com").Return(storage.ErrDomainInUse)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.input)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if tc.wantErr != nil {
+				var response DomainResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.wantErr.Error(), response.Error)
+			} else {
+				assert.JSONEq(t, tc.expected, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRedirectHandler_Domains(t *testing.T) {
+	brand := storage.Domain{ID: 1, Host: "go.brand.com", DefaultURL: "https://brand.com"}
+
+	tests := map[string]struct {
+		host     string
+		path     string
+		wantCode int
+		location string
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Alias of the custom domain": {
+			host:     "Go.Brand.com:8080",
+			path:     "/v1/promo",
+			wantCode: http.StatusFound,
+			location: "https://example.com/brand",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("go.brand.com").Return(brand, nil)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "go.brand.com", "promo").Return(storage.Link{Domain: "go.brand.com", Alias: "promo", URL: "https://example.com/brand"}, nil)
+			},
+		},
+		"Unknown alias of the custom domain": {
+			host:     "go.brand.com",
+			path:     "/v1/other",
+			wantCode: http.StatusFound,
+			location: "https://brand.com",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("go.brand.com").Return(brand, nil)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "go.brand.com", "other").Return(storage.Link{}, storage.ErrURLNotFound)
+			},
+		},
+		"Unknown alias without a default url": {
+			host:     "go.plain.com",
+			path:     "/v1/other",
+			wantCode: http.StatusOK,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("go.plain.com").Return(storage.Domain{ID: 2, Host: "go.plain.com"}, nil)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "go.plain.com", "other").Return(storage.Link{}, storage.ErrURLNotFound)
+			},
+		},
+		"Unregistered host": {
+			host:     "sho.rt",
+			path:     "/v1/promo",
+			wantCode: http.StatusFound,
+			location: "https://example.com/default",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("sho.rt").Return(storage.Domain{}, storage.ErrDomainNotFound)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "promo").Return(storage.Link{Alias: "promo", URL: "https://example.com/default"}, nil)
+			},
+		},
+		"Root of the custom domain": {
+			host:     "go.brand.com",
+			path:     "/",
+			wantCode: http.StatusFound,
+			location: "https://brand.com",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("go.brand.com").Return(brand, nil)
+			},
+		},
+		"Root of the default domain": {
+			host:     "sho.rt",
+			path:     "/",
+			wantCode: http.StatusNotFound,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("sho.rt").Return(storage.Domain{}, storage.ErrDomainNotFound)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			tc.prepare(mockStorage)
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Host = tc.host
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tc.wantCode, w.Code)
+			assert.Equal(t, tc.location, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestSaveHandler_Domain(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		wantErr string
+		prepare func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Custom domain": {
+			input: `{"url": "https://example.com", "alias": "promo", "domain": "GO.brand.com"}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetDomain(storage.DefaultWorkspace, "go.brand.com").Return(storage.Domain{ID: 1, Host: "go.brand.com"}, nil)
+				mockUrlProvider.EXPECT().SaveLink(gomock.Cond(func(link storage.Link) bool {
+					return link.Domain == "go.brand.com" && link.Alias == "promo"
+				})).Return(int64(1), nil)
+			},
+		},
+		"Unknown domain": {
+			input:   `{"url": "https://example.com", "alias": "promo", "domain": "go.brand.com"}`,
+			wantErr: "domain not found",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetDomain(storage.DefaultWorkspace, "go.brand.com").Return(storage.Domain{}, storage.ErrDomainNotFound)
+			},
+		},
+		"Alias taken on the domain": {
+			input:   `{"url": "https://example.com", "alias": "promo", "domain": "go.brand.com"}`,
+			wantErr: "url already exists",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetDomain(storage.DefaultWorkspace, "go.brand.com").Return(storage.Domain{ID: 1, Host: "go.brand.com"}, nil)
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).Return(int64(0), storage.ErrURLAlreadyExists)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectLinkCounts(mockStorage)
+			tc.prepare(mockStorage)
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(http.MethodPost, "/v1/url/", bytes.NewReader([]byte(tc.input)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.wantErr, response.Error)
+		})
+	}
+}
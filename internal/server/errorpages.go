@@ -0,0 +1,89 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/server/pages"
+	"strconv"
+	"strings"
+)
+
+// errorPages are the pages shown to browsers by the status of a failed redirect
+var errorPages = map[int]pages.Error{
+	http.StatusNotFound: {
+		Status:  http.StatusNotFound,
+		Title:   "Link not found",
+		Message: "The link you followed does not exist or is not active.",
+	},
+	http.StatusGone: {
+		Status:  http.StatusGone,
+		Title:   "Link is no longer available",
+		Message: "The link you followed has reached its limit of visits.",
+	},
+}
+
+// renderErrorPage writes the error page of the status if the client prefers html to json,
+// it returns false if the client should get a json error instead
+func (ro *router) renderErrorPage(w http.ResponseWriter, r *http.Request, status int) bool {
+	w.Header().Add("Vary", "Accept")
+
+	page, ok := errorPages[status]
+	if !ok || !acceptsHTML(r) {
+		return false
+	}
+
+	err := pages.Render(w, status, "error.html", page)
+	if err != nil {
+		ro.log.Error("failed to render error page", slo.Err(err))
+	}
+
+	return true
+}
+
+// acceptsHTML reports whether the Accept header prefers html to json, clients without the header get json
+func acceptsHTML(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+
+	return mediaQuality(accept, "text/html") > mediaQuality(accept, "application/json")
+}
+
+// mediaQuality returns the q value of the most specific range of the Accept header that matches the media type
+func mediaQuality(accept string, mediaType string) float64 {
+	group, _, _ := strings.Cut(mediaType, "/")
+
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		var match int
+		switch mediaRange {
+		case mediaType:
+			match = 2
+		case group + "/*":
+			match = 1
+		case "*/*":
+			match = 0
+		default:
+			continue
+		}
+
+		if match <= specificity {
+			continue
+		}
+		specificity = match
+
+		quality = 1
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+	}
+
+	return quality
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+	"time"
+)
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+
+func TestAcceptsHTML(t *testing.T) {
+	tests := map[string]struct {
+		accept string
+		html   bool
+	}{
+		"No header":           {accept: "", html: false},
+		"Browser":             {accept: browserAccept, html: true},
+		"Any":                 {accept: "*/*", html: false},
+		"Json":                {accept: "application/json", html: false},
+		"Json preferred":      {accept: "text/html;q=0.5, application/json", html: false},
+		"Html preferred":      {accept: "application/json;q=0.5, text/html", html: true},
+		"Text wildcard":       {accept: "text/*, application/json;q=0.9", html: true},
+		"Html excluded":       {accept: "text/html;q=0, */*", html: false},
+		"Malformed ignored":   {accept: "text/html;;=, */*;q=0.1", html: false},
+		"Case insensitive":    {accept: "Text/HTML", html: true},
+		"Specific range wins": {accept: "*/*;q=1, application/json;q=0.1", html: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/launc", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			assert.Equal(t, tc.html, acceptsHTML(req))
+		})
+	}
+}
+
+func TestRedirectHandler_ErrorPages(t *testing.T) {
+	tests := map[string]struct {
+		link     storage.Link
+		err      error
+		accept   string
+		wantCode int
+		wantPage string
+		wantErr  string
+	}{
+		"Unknown alias: browser": {
+			err:      storage.ErrURLNotFound,
+			accept:   browserAccept,
+			wantCode: http.StatusNotFound,
+			wantPage: "Link not found",
+		},
+		"Unknown alias: api client": {
+			err:      storage.ErrURLNotFound,
+			accept:   "application/json",
+			wantCode: http.StatusOK,
+			wantErr:  "url not found for given alias",
+		},
+		"Inactive: browser": {
+			link:     storage.Link{Alias: "launc", URL: "https://example.com", NotAfter: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+			accept:   browserAccept,
+			wantCode: http.StatusNotFound,
+			wantPage: "Link not found",
+		},
+		"Exhausted: browser": {
+			link:     storage.Link{Alias: "launc", URL: "https://example.com", MaxClicks: 1},
+			accept:   browserAccept,
+			wantCode: http.StatusGone,
+			wantPage: "Link is no longer available",
+		},
+		"Exhausted: api client": {
+			link:     storage.Link{Alias: "launc", URL: "https://example.com", MaxClicks: 1},
+			wantCode: http.StatusGone,
+			wantErr:  "link is no longer available",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectDefaultDomain(mockStorage)
+			mockStorage.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(tc.link, tc.err)
+
+			r := &router{
+				storage: mockStorage,
+				log:     slog.Default(),
+			}
+
+			chiRouter := chi.NewRouter()
+			chiRouter.Get("/v1/{alias}", r.redirectHandler)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/launc", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			w := httptest.NewRecorder()
+			chiRouter.ServeHTTP(w, req)
+			require.Equal(t, tc.wantCode, w.Code)
+			assert.Contains(t, w.Header().Values("Vary"), "Accept")
+
+			if tc.wantPage != "" {
+				assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+				assert.Contains(t, w.Body.String(), "<h1>"+tc.wantPage+"</h1>")
+
+				return
+			}
+
+			var response Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.wantErr, response.Error)
+		})
+	}
+}
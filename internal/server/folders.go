@@ -0,0 +1,345 @@
+package server
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/storage"
+	"strconv"
+	"strings"
+)
+
+// Folder files links, folders are nested under their parent
+type Folder struct {
+	ID   int64  `json:"id,omitempty"`
+	Name string `json:"name" validate:"required,max=100,excludes=/"`
+	//ParentID is the folder the folder is nested in, 0 or omitted for a top level folder
+	ParentID int64 `json:"parent_id,omitempty" validate:"gte=0"`
+}
+
+type FolderResponse struct {
+	resp.Response
+	Folder *Folder `json:"folder,omitempty"`
+}
+
+type FoldersResponse struct {
+	resp.Response
+	Folders []Folder `json:"folders"`
+}
+
+// LinkFolderRequest files a link in a folder, folder id 0 takes the link out of its folder
+type LinkFolderRequest struct {
+	FolderID int64 `json:"folder_id" validate:"gte=0"`
+}
+
+const (
+	handlersOperationSaveFolder    = "handlers.folder.save"
+	handlersOperationGetFolder     = "handlers.folder.get"
+	handlersOperationListFolders   = "handlers.folder.list"
+	handlersOperationUpdateFolder  = "handlers.folder.update"
+	handlersOperationDeleteFolder  = "handlers.folder.delete"
+	handlersOperationSetLinkFolder = "handlers.url.folder"
+)
+
+func (ro *router) saveFolderHandler(w http.ResponseWriter, r *http.Request) {
+	var req Folder
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationSaveFolder),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	if !decodeFolder(w, r, log, &req) {
+		return
+	}
+
+	id, err := ro.storage.SaveFolder(req.toStorage(workspaceID(r)))
+	if ro.folderError(w, r, log, err, req) {
+		return
+	}
+
+	req.ID = id
+	log.Info("folder successfully saved", slog.Int64("id", id))
+	render.JSON(w, r, FolderResponse{
+		Response: resp.OK(),
+		Folder:   &req,
+	})
+}
+
+func (ro *router) getFolderHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationGetFolder),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	id, ok := folderParam(w, r, log)
+	if !ok {
+		return
+	}
+
+	folder, err := ro.storage.GetFolder(workspaceID(r), id)
+	if errors.Is(err, storage.ErrFolderNotFound) {
+		log.Info("folder not found", slog.Int64("id", id))
+		render.JSON(w, r, resp.Error("folder not found"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to get folder", slog.Int64("id", id), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	result := folderFromStorage(folder)
+	render.JSON(w, r, FolderResponse{
+		Response: resp.OK(),
+		Folder:   &result,
+	})
+}
+
+// listFoldersHandler returns all folders of the workspace, clients build the hierarchy from the parent ids
+func (ro *router) listFoldersHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationListFolders),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	folders, err := ro.storage.ListFolders(workspaceID(r))
+	if err != nil {
+		log.Error("failed to list folders", slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	result := make([]Folder, 0, len(folders))
+	for _, folder := range folders {
+		result = append(result, folderFromStorage(folder))
+	}
+
+	render.JSON(w, r, FoldersResponse{
+		Response: resp.OK(),
+		Folders:  result,
+	})
+}
+
+// updateFolderHandler renames a folder and moves it under the parent of the request
+func (ro *router) updateFolderHandler(w http.ResponseWriter, r *http.Request) {
+	var req Folder
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationUpdateFolder),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	id, ok := folderParam(w, r, log)
+	if !ok {
+		return
+	}
+
+	if !decodeFolder(w, r, log, &req) {
+		return
+	}
+	req.ID = id
+
+	err := ro.storage.UpdateFolder(req.toStorage(workspaceID(r)))
+	if ro.folderError(w, r, log, err, req) {
+		return
+	}
+
+	log.Info("folder successfully updated", slog.Int64("id", id))
+	render.JSON(w, r, FolderResponse{
+		Response: resp.OK(),
+		Folder:   &req,
+	})
+}
+
+// deleteFolderHandler removes an empty folder, links and subfolders have to be moved out first
+func (ro *router) deleteFolderHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationDeleteFolder),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	id, ok := folderParam(w, r, log)
+	if !ok {
+		return
+	}
+
+	err := ro.storage.DeleteFolder(workspaceID(r), id)
+	if errors.Is(err, storage.ErrFolderNotFound) {
+		log.Info("folder not found", slog.Int64("id", id))
+		render.JSON(w, r, resp.Error("folder not found"))
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrFolderNotEmpty) {
+		log.Info("folder is not empty", slog.Int64("id", id))
+		render.JSON(w, r, resp.Error("folder has links or subfolders"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to delete folder", slog.Int64("id", id), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	log.Info("folder successfully deleted", slog.Int64("id", id))
+	render.JSON(w, r, resp.OK())
+}
+
+func (ro *router) setLinkFolderHandler(w http.ResponseWriter, r *http.Request) {
+	var req LinkFolderRequest
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationSetLinkFolder),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	alias := chi.URLParam(r, "alias")
+
+	err := render.DecodeJSON(r.Body, &req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return
+	}
+
+	err = validator.New().Struct(req)
+	if err != nil {
+		validateErr := err.(validator.ValidationErrors)
+		log.Error("invalid request", slo.Err(err))
+		render.JSON(w, r, resp.ValidationError(validateErr))
+
+		return
+	}
+
+	err = ro.storage.SetFolder(workspaceID(r), domainParam(r), alias, req.FolderID)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		log.Info("url not found", slog.String("alias", alias))
+		render.JSON(w, r, resp.Error("url not found for given alias"))
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrFolderNotFound) {
+		log.Info("folder not found", slog.Int64("id", req.FolderID))
+		render.JSON(w, r, resp.Error("folder not found"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to set folder", slog.String("alias", alias), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	log.Info("link successfully filed", slog.String("alias", alias), slog.Int64("folder_id", req.FolderID))
+	render.JSON(w, r, resp.OK())
+}
+
+// folderError writes the response to a failed save or update of a folder, it returns false if there is no error
+func (ro *router) folderError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error, req Folder) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, storage.ErrFolderNotFound):
+		log.Info("folder not found", slog.Int64("id", req.ID), slog.Int64("parent_id", req.ParentID))
+		render.JSON(w, r, resp.Error("folder not found"))
+	case errors.Is(err, storage.ErrFolderAlreadyExists):
+		log.Info("folder already exists", slog.String("name", req.Name), slog.Int64("parent_id", req.ParentID))
+		render.JSON(w, r, resp.Error("folder already exists"))
+	case errors.Is(err, storage.ErrFolderCycle):
+		log.Info("folder can not be moved into itself", slog.Int64("id", req.ID), slog.Int64("parent_id", req.ParentID))
+		render.JSON(w, r, resp.Error("invalid request: folder can not be moved into itself"))
+	default:
+		log.Error("failed to save folder", slog.String("name", req.Name), slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to save folder"))
+	}
+
+	return true
+}
+
+// folderParam reads the folder id of the path, it writes an error response and returns false if it is not an id
+func folderParam(w http.ResponseWriter, r *http.Request, log *slog.Logger) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		log.Info("invalid folder id", slog.String("id", chi.URLParam(r, "id")))
+		render.JSON(w, r, resp.Error("folder not found"))
+
+		return 0, false
+	}
+
+	return id, true
+}
+
+// decodeFolder decodes and validates a folder from the request body,
+// it writes an error response and returns false if the folder is not valid
+func decodeFolder(w http.ResponseWriter, r *http.Request, log *slog.Logger, req *Folder) bool {
+	err := render.DecodeJSON(r.Body, req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+
+		return false
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	err = validator.New().Struct(req)
+	if err != nil {
+		validateErr := err.(validator.ValidationErrors)
+		log.Error("invalid request", slo.Err(err))
+		render.JSON(w, r, resp.ValidationError(validateErr))
+
+		return false
+	}
+
+	return true
+}
+
+func (f Folder) toStorage(workspaceID int64) storage.Folder {
+	return storage.Folder{
+		ID:          f.ID,
+		WorkspaceID: workspaceID,
+		ParentID:    f.ParentID,
+		Name:        f.Name,
+	}
+}
+
+func folderFromStorage(f storage.Folder) Folder {
+	return Folder{
+		ID:       f.ID,
+		Name:     f.Name,
+		ParentID: f.ParentID,
+	}
+}
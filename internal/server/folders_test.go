@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestFolderHandlers(t *testing.T) {
+	tests := map[string]struct {
+		method   string
+		path     string
+		input    string
+		wantErr  error
+		expected string
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Save: success": {
+			method:   http.MethodPost,
+			path:     "/v1/folders",
+			input:    `{"name": "acme", "parent_id": 1}`,
+			expected: `{"status":"ok","folder":{"id":2,"name":"acme","parent_id":1}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveFolder(storage.Folder{ParentID: 1, Name: "acme"}).Return(int64(2), nil)
+			},
+		},
+		"Save: slash in name": {
+			method:  http.MethodPost,
+			path:    "/v1/folders",
+			input:   `{"name": "projects/acme"}`,
+			wantErr: errors.New("\"Name\" field is not valid"),
+		},
+		"Save: parent not found": {
+			method:  http.MethodPost,
+			path:    "/v1/folders",
+			input:   `{"name": "acme", "parent_id": 9}`,
+			wantErr: errors.New("folder not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveFolder(gomock.Any()).Return(int64(0), storage.ErrFolderNotFound)
+			},
+		},
+		"Save: already exists": {
+			method:  http.MethodPost,
+			path:    "/v1/folders",
+			input:   `{"name": "acme"}`,
+			wantErr: errors.New("folder already exists"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveFolder(gomock.Any()).Return(int64(0), storage.ErrFolderAlreadyExists)
+			},
+		},
+		"Get: invalid id": {
+			method:  http.MethodGet,
+			path:    "/v1/folders/acme",
+			wantErr: errors.New("folder not found"),
+		},
+		"List": {
+			method:   http.MethodGet,
+			path:     "/v1/folders",
+			expected: `{"status":"ok","folders":[{"id":1,"name":"projects"},{"id":2,"name":"acme","parent_id":1}]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ListFolders(storage.DefaultWorkspace).Return([]storage.Folder{
+					{ID: 1, Name: "projects"},
+					{ID: 2, ParentID: 1, Name: "acme"},
+				}, nil)
+			},
+		},
+		"Update: cycle": {
+			method:  http.MethodPut,
+			path:    "/v1/folders/1",
+			input:   `{"name": "projects", "parent_id": 2}`,
+			wantErr: errors.New("invalid request: folder can not be moved into itself"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().UpdateFolder(storage.Folder{ID: 1, ParentID: 2, Name: "projects"}).Return(storage.ErrFolderCycle)
+			},
+		},
+		"Delete: not empty": {
+			method:  http.MethodDelete,
+			path:    "/v1/folders/1",
+			wantErr: errors.New("folder has links or subfolders"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().DeleteFolder(storage.DefaultWorkspace, int64(1)).Return(storage.ErrFolderNotEmpty)
+			},
+		},
+		"Set link folder: success": {
+			method:   http.MethodPut,
+			path:     "/v1/url/promo/folder",
+			input:    `{"folder_id": 2}`,
+			expected: `{"status":"ok"}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SetFolder(storage.DefaultWorkspace, "", "promo", int64(2)).Return(nil)
+			},
+		},
+		"Set link folder: folder not found": {
+			method:  http.MethodPut,
+			path:    "/v1/url/promo/folder",
+			input:   `{"folder_id": 9}`,
+			wantErr: errors.New("folder not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SetFolder(storage.DefaultWorkspace, "", "promo", int64(9)).Return(storage.ErrFolderNotFound)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.input)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if tc.wantErr != nil {
+				var response FolderResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.wantErr.Error(), response.Error)
+			} else {
+				assert.JSONEq(t, tc.expected, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSaveHandler_Organization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockUrlProvider(ctrl)
+	expectLinkCounts(mockStorage)
+	mockStorage.EXPECT().SaveLink(gomock.Any()).DoAndReturn(func(link storage.Link) (int64, error) {
+		assert.Equal(t, []string{"launch", "q3"}, link.Tags)
+		assert.Equal(t, int64(4), link.FolderID)
+
+		return 0, storage.ErrFolderNotFound
+	})
+
+	r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+	body := `{"url": "https://example.com", "alias": "promo", "tags": ["Q3", "launch"], "folder_id": 4}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/url", bytes.NewReader([]byte(body)))
+	req.SetBasicAuth("", "")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "folder not found", response.Error)
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"shorty/internal/pkg/logger/slo"
+	"strings"
+)
+
+// CountryLocator resolves the ISO 3166-1 alpha-2 country code of an ip,
+// it returns an empty string for unknown addresses
+type CountryLocator interface {
+	Country(ip net.IP) (string, error)
+}
+
+// WithGeoIP enables country-based redirect targets and country click analytics
+func WithGeoIP(geo CountryLocator) Option {
+	return func(ro *router) {
+		ro.geo = geo
+	}
+}
+
+// clientCountry returns the country of the client, or an empty string if it is unknown or geoip is disabled
+func (ro *router) clientCountry(r *http.Request) string {
+	if ro.geo == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+
+	country, err := ro.geo.Country(ip)
+	if err != nil {
+		ro.log.Error("failed to look up client country", slog.String("ip", host), slo.Err(err))
+
+		return ""
+	}
+
+	return country
+}
+
+// upperCountries brings the country codes of a country map to upper case,
+// it returns a human-readable error text for a client if a code is repeated
+func upperCountries(countries map[string]string) (map[string]string, string) {
+	if len(countries) == 0 {
+		return nil, ""
+	}
+
+	result := make(map[string]string, len(countries))
+	for country, target := range countries {
+		upper := strings.ToUpper(country)
+		if _, ok := result[upper]; ok {
+			return nil, "invalid request: country \"" + upper + "\" is repeated"
+		}
+
+		result[upper] = target
+	}
+
+	return result, ""
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+)
+
+type fakeLocator map[string]string
+
+func (f fakeLocator) Country(ip net.IP) (string, error) {
+	if ip.Equal(net.ParseIP("192.0.2.66")) {
+		return "", errors.New("corrupted database")
+	}
+
+	return f[ip.String()], nil
+}
+
+func TestUpperCountries(t *testing.T) {
+	tests := map[string]struct {
+		countries map[string]string
+		expected  map[string]string
+		wantErr   string
+	}{
+		"Upper case": {
+			countries: map[string]string{"de": "https://example.de", "FR": "https://example.fr"},
+			expected:  map[string]string{"DE": "https://example.de", "FR": "https://example.fr"},
+		},
+		"Empty": {},
+		"Repeated code": {
+			countries: map[string]string{"de": "https://example.de/a", "DE": "https://example.de/b"},
+			wantErr:   `invalid request: country "DE" is repeated`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			countries, msg := upperCountries(tc.countries)
+			assert.Equal(t, tc.wantErr, msg)
+			assert.Equal(t, tc.expected, countries)
+		})
+	}
+}
+
+func TestRedirectHandler_Countries(t *testing.T) {
+	link := storage.Link{
+		ID:    7,
+		Alias: "shop",
+		URL:   "https://example.com/shop",
+		Countries: map[string]string{
+			"DE": "https://example.de/shop",
+		},
+		Locales: map[string]string{
+			"fr": "https://example.com/fr/shop",
+		},
+	}
+
+	tests := map[string]struct {
+		remoteAddr     string
+		geo            CountryLocator
+		acceptLanguage string
+		url            string
+		country        string
+	}{
+		"Country target": {
+			remoteAddr: "203.0.113.5:4000",
+			geo:        fakeLocator{"203.0.113.5": "DE"},
+			url:        "https://example.de/shop",
+			country:    "DE",
+		},
+		"Country before locale": {
+			remoteAddr:     "203.0.113.5:4000",
+			geo:            fakeLocator{"203.0.113.5": "DE"},
+			acceptLanguage: "fr",
+			url:            "https://example.de/shop",
+			country:        "DE",
+		},
+		"Other country": {
+			remoteAddr: "198.51.100.1:4000",
+			geo:        fakeLocator{"198.51.100.1": "US"},
+			url:        "https://example.com/shop",
+			country:    "US",
+		},
+		"Unknown address": {
+			remoteAddr: "10.0.0.1:4000",
+			geo:        fakeLocator{},
+			url:        "https://example.com/shop",
+		},
+		"Lookup error": {
+			remoteAddr: "192.0.2.66:4000",
+			geo:        fakeLocator{},
+			url:        "https://example.com/shop",
+		},
+		"GeoIP disabled": {
+			remoteAddr: "203.0.113.5:4000",
+			url:        "https://example.com/shop",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectDefaultDomain(mockStorage)
+			mockStorage.EXPECT().GetLink(storage.DefaultWorkspace, "", "shop").Return(link, nil)
+			if tc.country != "" {
+				mockStorage.EXPECT().CountCountryClick(int64(7), tc.country).Return(nil)
+			}
+
+			r := &router{
+				storage: mockStorage,
+				log:     slog.Default(),
+				geo:     tc.geo,
+			}
+
+			chiRouter := chi.NewRouter()
+			chiRouter.Get("/v1/{alias}", r.redirectHandler)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/shop", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+
+			w := httptest.NewRecorder()
+			chiRouter.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, tc.url, w.Header().Get("Location"))
+		})
+	}
+}
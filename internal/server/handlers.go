@@ -1,32 +1,139 @@
 package server
 
 import (
+	"bytes"
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	resp "shorty/internal/pkg/api/response"
 	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/passthrough"
 	"shorty/internal/pkg/random"
+	"shorty/internal/pkg/tagname"
+	"shorty/internal/pkg/urlnorm"
+	"shorty/internal/pkg/utm"
 	"shorty/internal/storage"
+	"strings"
+	"time"
 )
 
 //go:generate mockgen -source=handlers.go -destination=mocks/handlers.go -package=mocks
 
 type UrlProvider interface {
-	SaveURL(urlToSave string, alias string) (int64, error)
-	GetURL(alias string) (string, error)
-	DeleteURL(alias string) error
-	UpdateAlias(oldAlias string, newAlias string) error
+	SaveLink(link storage.Link) (int64, error)
+	SaveLinks(links []storage.Link, atomic bool) ([]storage.SaveResult, error)
+	GetLink(workspaceID int64, domain string, alias string) (storage.Link, error)
+	FindLinkByURL(workspaceID int64, domain string, owner string, target string) (storage.Link, error)
+	ListLinks(filter storage.LinkFilter) ([]storage.Link, error)
+	DeleteURL(workspaceID int64, domain string, alias string) error
+	ConsumeClick(workspaceID int64, domain string, alias string) error
+	SetRules(workspaceID int64, domain string, alias string, rules []storage.Rule) error
+	CountVariantClick(variantID int64) error
+	CountCountryClick(linkID int64, country string) error
+	UpdateAlias(workspaceID int64, domain string, oldAlias string, newAlias string) error
+	EachLink(workspaceID int64, fn func(link storage.Link) error) error
+	ImportLinks(links []storage.Link, policy storage.ConflictPolicy) (storage.ImportResult, error)
+
+	SaveTemplate(template storage.Template) (int64, error)
+	GetTemplate(workspaceID int64, name string) (storage.Template, error)
+	ListTemplates(workspaceID int64) ([]storage.Template, error)
+	UpdateTemplate(template storage.Template) error
+	DeleteTemplate(workspaceID int64, name string) error
+
+	SaveDomain(domain storage.Domain) (int64, error)
+	GetDomain(workspaceID int64, host string) (storage.Domain, error)
+	ResolveDomain(host string) (storage.Domain, error)
+	ListDomains(workspaceID int64) ([]storage.Domain, error)
+	UpdateDomain(domain storage.Domain) error
+	DeleteDomain(workspaceID int64, host string) error
+
+	ReserveIdempotencyKey(workspaceID int64, key string, fingerprint string, expireBefore time.Time) (storage.IdempotencyRecord, error)
+	CompleteIdempotencyKey(workspaceID int64, key string, response []byte) error
+	ReleaseIdempotencyKey(workspaceID int64, key string) error
+
+	SaveWorkspace(workspace storage.Workspace) (int64, error)
+	GetWorkspace(id int64) (storage.Workspace, error)
+	ListWorkspaces() ([]storage.Workspace, error)
+	UpdateWorkspace(workspace storage.Workspace) error
+	CountRequest(workspaceID int64, now time.Time) (int64, error)
+
+	SaveUser(user storage.User) (int64, error)
+	GetUser(name string) (storage.User, error)
+	ListUsers(workspaceID int64) ([]storage.User, error)
+	DeleteUser(workspaceID int64, name string) error
+
+	SaveTag(tag storage.Tag) (int64, error)
+	ListTags(workspaceID int64) ([]storage.Tag, error)
+	RenameTag(workspaceID int64, name string, newName string) error
+	DeleteTag(workspaceID int64, name string) error
+	SetTags(workspaceID int64, domain string, alias string, tags []string) error
+
+	SaveFolder(folder storage.Folder) (int64, error)
+	GetFolder(workspaceID int64, id int64) (storage.Folder, error)
+	ListFolders(workspaceID int64) ([]storage.Folder, error)
+	UpdateFolder(folder storage.Folder) error
+	DeleteFolder(workspaceID int64, id int64) error
+	SetFolder(workspaceID int64, domain string, alias string, folderID int64) error
+
+	ReserveLinks(owner string, count int64, quota storage.LinkQuota, now time.Time) (storage.QuotaUsage, error)
+	ReleaseLinks(owner string, count int64, now time.Time) error
+	GetQuotaUsage(owner string, now time.Time) (storage.QuotaUsage, error)
 }
 
 type Request struct {
 	URL   string `json:"url" validate:"required,url"`
 	Alias string `json:"alias,omitempty"`
+	//Domain is the host of the custom domain the link is created on, the default domain is used if it is empty
+	Domain     string `json:"domain,omitempty"`
+	MergeQuery bool   `json:"merge_query,omitempty"`
+	AppendPath bool   `json:"append_path,omitempty"`
+	Template   string `json:"template,omitempty"`
+	Password   string `json:"password,omitempty"`
+	MaxClicks  int64  `json:"max_clicks,omitempty" validate:"gte=0"`
+	//Title is shown on the preview page of the link
+	Title string `json:"title,omitempty" validate:"max=200"`
+	//Preview shows visitors the destination before redirecting them
+	Preview bool `json:"preview,omitempty"`
+	//OGTitle, OGDescription and OGImage replace the preview of the destination in chats and social networks
+	OGTitle       string `json:"og_title,omitempty" validate:"max=300"`
+	OGDescription string `json:"og_description,omitempty" validate:"max=1000"`
+	OGImage       string `json:"og_image,omitempty" validate:"omitempty,http_url"`
+	//ReuseExisting returns the alias of a link the caller already has for the same url instead of creating one
+	ReuseExisting bool `json:"reuse_existing,omitempty"`
+	//Tags label the link, missing tags are created
+	Tags []string `json:"tags,omitempty" validate:"max=20,dive,required,max=50"`
+	//FolderID files the link in a folder of the workspace
+	FolderID int64 `json:"folder_id,omitempty" validate:"gte=0"`
+
+	NotBefore   *time.Time `json:"not_before,omitempty"`
+	NotAfter    *time.Time `json:"not_after,omitempty"`
+	FallbackURL string     `json:"fallback_url,omitempty" validate:"omitempty,url"`
+
+	Rules []Rule `json:"rules,omitempty" validate:"dive"`
+	//Variants split visitors between weighted targets, the url is kept for display only
+	Variants []Variant `json:"variants,omitempty" validate:"omitempty,min=2,dive"`
+	//Locales map language tags to localized targets, the url is the default
+	Locales map[string]string `json:"locales,omitempty" validate:"dive,keys,required,endkeys,required,url"`
+	//Countries map ISO 3166-1 alpha-2 codes to regional targets
+	Countries map[string]string `json:"countries,omitempty" validate:"dive,keys,len=2,alpha,endkeys,required,url"`
+}
+
+// LogValue keeps the link password out of the logs
+func (r Request) LogValue() slog.Value {
+	type request Request //no LogValue method, prevents recursion
+
+	if r.Password != "" {
+		r.Password = "[redacted]"
+	}
+
+	return slog.AnyValue(request(r))
 }
 
 type UpdateRequest struct {
@@ -36,6 +143,8 @@ type UpdateRequest struct {
 type Response struct {
 	resp.Response
 	Alias string `json:"alias,omitempty"`
+	//Reused is set if an existing link was returned for a request with reuse_existing
+	Reused bool `json:"reused,omitempty"`
 }
 
 const (
@@ -55,8 +164,31 @@ func (ro *router) saveAliasHandler(w http.ResponseWriter, r *http.Request) {
 		slog.String("request_id", middleware.GetReqID(r.Context())), //req tracing
 	)
 
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		ro.log.Error("failed to read request body", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return
+	}
+
+	workspace := workspaceID(r)
+
+	//a repeated request with the same key gets the original response instead of a new link
+	key := r.Header.Get(idempotencyHeader)
+	if key != "" && !ro.reserveIdempotencyKey(w, r, key, body) {
+		return
+	}
+
+	completed := false
+	defer func() {
+		if key != "" && !completed {
+			ro.releaseIdempotencyKey(workspace, key)
+		}
+	}()
+
 	//parse request
-	err := render.DecodeJSON(r.Body, &req)
+	err = render.DecodeJSON(bytes.NewReader(body), &req)
 	if errors.Is(err, io.EOF) {
 		ro.log.Error("request body is empty")
 		render.JSON(w, r, resp.Error("empty request"))
@@ -73,15 +205,164 @@ func (ro *router) saveAliasHandler(w http.ResponseWriter, r *http.Request) {
 
 	ro.log.Info("request body decoded successfully", slog.Any("request", req))
 
-	err = validator.New().Struct(req)
+	link, msg := ro.linkFromRequest(workspace, req)
+	if msg != "" {
+		render.JSON(w, r, resp.Error(msg))
+
+		return
+	}
+	link.Owner = caller(r)
+
+	var reused string
+	if req.ReuseExisting {
+		reused, err = ro.existingAlias(link)
+		if err != nil {
+			render.JSON(w, r, resp.Error("failed to save url"))
+
+			return
+		}
+	}
+
+	response := Response{
+		Response: resp.OK(),
+		Alias:    link.Alias,
+	}
+
+	if reused != "" {
+		ro.log.Info("existing link reused", slog.String("alias", reused))
+		response.Alias = reused
+		response.Reused = true
+	} else {
+		//the link counts against the quota of the caller before it is inserted
+		now := time.Now()
+		if !ro.reserveLinks(w, r, 1, now) {
+			return
+		}
+
+		id, err := ro.storage.SaveLink(link)
+		if err != nil {
+			ro.releaseLinks(r, 1, now)
+		}
+
+		if errors.Is(err, storage.ErrURLAlreadyExists) {
+			ro.log.Info("url already exists", slog.String("url", req.URL))
+			render.JSON(w, r, resp.Error("url already exists"))
+
+			return
+		}
+
+		if errors.Is(err, storage.ErrFolderNotFound) {
+			ro.log.Info("folder not found", slog.Int64("folder_id", req.FolderID))
+			render.JSON(w, r, resp.Error("folder not found"))
+
+			return
+		}
+
+		if errors.Is(err, storage.ErrLinkQuotaExceeded) {
+			ro.log.Info("workspace link quota exceeded", slog.Int64("workspace_id", workspace))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error("workspace link quota exceeded"))
+
+			return
+		}
+
+		if err != nil {
+			ro.log.Error("failed to save url", slo.Err(err))
+			render.JSON(w, r, resp.Error("failed to save url"))
+
+			return
+		}
+
+		ro.log.Info("url successfully saved", slog.Int64("id", id))
+	}
+
+	if key != "" {
+		ro.completeIdempotencyKey(workspace, key, response)
+		completed = true
+	}
+
+	render.JSON(w, r, response)
+}
+
+// existingAlias returns the alias of the latest link of the same owner with the same target, or an empty string
+func (ro *router) existingAlias(link storage.Link) (string, error) {
+	existing, err := ro.storage.FindLinkByURL(link.WorkspaceID, link.Domain, link.Owner, link.URL)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		return "", nil
+	}
+
+	if err != nil {
+		ro.log.Error("failed to find existing link", slo.Err(err))
+
+		return "", err
+	}
+
+	return existing.Alias, nil
+}
+
+// caller returns the api user of the request, links are owned by it
+func caller(r *http.Request) string {
+	user, _, _ := r.BasicAuth()
+
+	return user
+}
+
+// validateRequest runs the checks of a creation request that do not need the storage, imported links pass them too.
+// It returns the canonical locales and countries of the link, or a human-readable error text for a client
+func (ro *router) validateRequest(req Request) (map[string]string, map[string]string, string) {
+	err := validator.New().Struct(req)
 	if err != nil {
 		validateErr := err.(validator.ValidationErrors)
 		ro.log.Error("invalid request", slo.Err(err))
 
 		//human-readable error text for a client
-		render.JSON(w, r, resp.ValidationError(validateErr))
+		return nil, nil, resp.ValidationError(validateErr).Error
+	}
 
-		return
+	if req.NotBefore != nil && req.NotAfter != nil && !req.NotAfter.After(*req.NotBefore) {
+		ro.log.Info("invalid activation window", slog.Time("not_before", *req.NotBefore), slog.Time("not_after", *req.NotAfter))
+
+		return nil, nil, "invalid request: not_after must be later than not_before"
+	}
+
+	if msg := validateRules(req.Rules); msg != "" {
+		ro.log.Info("invalid rules", slog.String("reason", msg))
+
+		return nil, nil, msg
+	}
+
+	locales, msg := canonicalLocales(req.Locales)
+	if msg != "" {
+		ro.log.Info("invalid locales", slog.String("reason", msg))
+
+		return nil, nil, msg
+	}
+
+	countries, msg := upperCountries(req.Countries)
+	if msg != "" {
+		ro.log.Info("invalid countries", slog.String("reason", msg))
+
+		return nil, nil, msg
+	}
+
+	return locales, countries, ""
+}
+
+// linkFromRequest validates a creation request and builds the link to be saved in the workspace,
+// it returns a human-readable error text for a client if the request cannot be saved
+func (ro *router) linkFromRequest(workspaceID int64, req Request) (storage.Link, string) {
+	req.Tags = tagname.NormalizeAll(req.Tags)
+	locales, countries, msg := ro.validateRequest(req)
+	if msg != "" {
+		return storage.Link{}, msg
+	}
+
+	//equivalent urls are saved in the same form, the original is kept for display
+	normalized, err := urlnorm.Normalize(req.URL, ro.normalization)
+	if err != nil {
+		ro.log.Info("failed to normalize url", slog.String("url", req.URL), slo.Err(err))
+
+		return storage.Link{}, "\"URL\" is not a valid URL"
 	}
 
 	alias := req.Alias
@@ -90,27 +371,83 @@ func (ro *router) saveAliasHandler(w http.ResponseWriter, r *http.Request) {
 		//TODO: check alias uniqueness
 	}
 
-	id, err := ro.storage.SaveURL(req.URL, alias)
-	if errors.Is(err, storage.ErrURLAlreadyExists) {
-		ro.log.Info("url already exists", slog.String("url", req.URL))
-		render.JSON(w, r, resp.Error("url already exists"))
+	link := storage.Link{
+		WorkspaceID: workspaceID,
+		Domain:      normalizeHost(req.Domain),
+		Alias:       alias,
+		URL:         normalized,
+		OriginalURL: req.URL,
+		Title:       req.Title,
+		Preview:     req.Preview,
+		OpenGraph:   storage.OpenGraph{Title: req.OGTitle, Description: req.OGDescription, Image: req.OGImage},
+		MergeQuery:  req.MergeQuery,
+		AppendPath:  req.AppendPath,
+		MaxClicks:   req.MaxClicks,
+
+		FallbackURL: req.FallbackURL,
+		Rules:       rulesToStorage(req.Rules),
+		Variants:    variantsToStorage(req.Variants),
+		Locales:     locales,
+		Countries:   countries,
+		Tags:        req.Tags,
+		FolderID:    req.FolderID,
+	}
 
-		return
+	if msg := ro.checkDestinations(link); msg != "" {
+		return storage.Link{}, msg
 	}
 
-	if err != nil {
-		ro.log.Error("failed to save url", slo.Err(err))
-		render.JSON(w, r, resp.Error("failed to save url"))
+	if link.Domain != "" {
+		_, err := ro.storage.GetDomain(workspaceID, link.Domain)
+		if errors.Is(err, storage.ErrDomainNotFound) {
+			ro.log.Info("domain not found", slog.String("domain", link.Domain))
+			return storage.Link{}, "domain not found"
+		}
+
+		if err != nil {
+			ro.log.Error("failed to get domain", slog.String("domain", link.Domain), slo.Err(err))
+			return storage.Link{}, "failed to save url"
+		}
+	}
 
-		return
+	if msg := ro.checkChain(workspaceID, link.Domain, link.Alias, linkTargets(link)...); msg != "" {
+		return storage.Link{}, msg
 	}
 
-	ro.log.Info("url successfully saved", slog.Int64("id", id))
+	if req.NotBefore != nil {
+		link.NotBefore = *req.NotBefore
+	}
 
-	render.JSON(w, r, Response{
-		Response: resp.OK(),
-		Alias:    alias,
-	})
+	if req.NotAfter != nil {
+		link.NotAfter = *req.NotAfter
+	}
+
+	if req.Template != "" {
+		template, err := ro.storage.GetTemplate(workspaceID, req.Template)
+		if errors.Is(err, storage.ErrTemplateNotFound) {
+			ro.log.Info("template not found", slog.String("template", req.Template))
+			return storage.Link{}, "template not found"
+		}
+
+		if err != nil {
+			ro.log.Error("failed to get template", slog.String("template", req.Template), slo.Err(err))
+			return storage.Link{}, "failed to save url"
+		}
+
+		link.Template = &template
+	}
+
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			ro.log.Error("failed to hash password", slo.Err(err))
+			return storage.Link{}, "failed to save url"
+		}
+
+		link.PasswordHash = string(hash)
+	}
+
+	return link, ""
 }
 
 func (ro *router) redirectHandler(w http.ResponseWriter, r *http.Request) {
@@ -119,7 +456,8 @@ func (ro *router) redirectHandler(w http.ResponseWriter, r *http.Request) {
 		slog.String("request_id", middleware.GetReqID(r.Context())),
 	)
 
-	alias := chi.URLParam(r, "alias")
+	//"/{alias}+" shows the preview page of any link
+	alias, previewRequested := strings.CutSuffix(chi.URLParam(r, "alias"), previewSuffix)
 	if alias == "" {
 		ro.log.Info("alias is empty")
 		render.JSON(w, r, resp.Error("invalid request"))
@@ -127,9 +465,27 @@ func (ro *router) redirectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := ro.storage.GetURL(alias)
+	//the host of the request selects the domain and the workspace the alias is looked up in
+	domain, err := ro.requestDomain(r)
+	if err != nil {
+		ro.log.Error("failed to get domain", slog.String("host", r.Host), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	link, err := ro.storage.GetLink(domain.WorkspaceID, domain.Host, alias)
 	if errors.Is(err, storage.ErrURLNotFound) {
-		ro.log.Info("url not found", "alias", alias)
+		ro.log.Info("url not found", "alias", alias, "domain", domain.Host)
+		if domain.DefaultURL != "" {
+			http.Redirect(w, r, domain.DefaultURL, http.StatusFound)
+
+			return
+		}
+
+		if ro.renderErrorPage(w, r, http.StatusNotFound) {
+			return
+		}
 		render.JSON(w, r, resp.Error("url not found for given alias"))
 
 		return
@@ -142,8 +498,197 @@ func (ro *router) redirectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ro.log.Info("got url", slog.String("url", result))
-	http.Redirect(w, r, result, http.StatusFound)
+	if !linkActive(link, time.Now()) {
+		ro.log.Info("link is outside of its active window", slog.String("alias", alias))
+
+		if link.FallbackURL != "" {
+			http.Redirect(w, r, link.FallbackURL, http.StatusFound)
+
+			return
+		}
+
+		if ro.renderErrorPage(w, r, http.StatusNotFound) {
+			return
+		}
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, resp.Error("url not found for given alias"))
+
+		return
+	}
+
+	if link.MaxClicks > 0 && link.ClicksLeft <= 0 {
+		ro.log.Info("link click limit reached", slog.String("alias", alias))
+		if ro.renderErrorPage(w, r, http.StatusGone) {
+			return
+		}
+		render.Status(r, http.StatusGone)
+		render.JSON(w, r, resp.Error("link is no longer available"))
+
+		return
+	}
+
+	if !ro.checkPassword(w, r, link) {
+		return
+	}
+
+	//the destination of a protected link is not shown, the password form already stops the visitor
+	preview := link.PasswordHash == "" && (previewRequested || ro.previewEnabled(link) && !continued(r))
+
+	country := ro.clientCountry(r)
+
+	target, variant, ok := ro.resolveTarget(w, r, link, country)
+	if !ok {
+		return
+	}
+
+	if ro.renderOpenGraph(w, r, link, target) {
+		return
+	}
+
+	if preview {
+		ro.renderPreview(w, r, link, target)
+
+		return
+	}
+
+	//the click is taken last, so that invalid requests do not use up the link
+	if link.MaxClicks > 0 {
+		err = ro.storage.ConsumeClick(link.WorkspaceID, link.Domain, alias)
+		if errors.Is(err, storage.ErrLinkExhausted) {
+			ro.log.Info("link click limit reached", slog.String("alias", alias))
+			if ro.renderErrorPage(w, r, http.StatusGone) {
+				return
+			}
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, resp.Error("link is no longer available"))
+
+			return
+		}
+
+		if err != nil {
+			ro.log.Error("failed to consume click", slog.String("alias", alias), slo.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+
+			return
+		}
+	}
+
+	//analytics must not break redirects
+	if variant != nil {
+		err = ro.storage.CountVariantClick(variant.ID)
+		if err != nil {
+			ro.log.Error("failed to count variant click", slog.String("alias", alias), slo.Err(err))
+		}
+	}
+
+	if country != "" {
+		err = ro.storage.CountCountryClick(link.ID, country)
+		if err != nil {
+			ro.log.Error("failed to count country click", slog.String("alias", alias), slo.Err(err))
+		}
+	}
+
+	ro.log.Info("got url", slog.String("url", target.String()))
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// resolveTarget chooses the destination of the visitor and applies the path, the template and the query to it,
+// it writes an error and returns false if the visitor cannot be redirected
+func (ro *router) resolveTarget(w http.ResponseWriter, r *http.Request, link storage.Link, country string) (*url.URL, *storage.Variant, bool) {
+	if len(link.Locales) > 0 {
+		w.Header().Add("Vary", "Accept-Language")
+	}
+
+	//targets are chosen by platform rules first, then by country, then by language, then by the A/B split
+	var (
+		destination = link.URL
+		variant     *storage.Variant
+	)
+	if ruleTarget, ok := matchRule(link.Rules, r); ok {
+		destination = ruleTarget
+	} else if countryTarget, ok := link.Countries[country]; ok && country != "" {
+		destination = countryTarget
+	} else if localeTarget, ok := matchLocale(link.Locales, r); ok {
+		destination = localeTarget
+	} else if picked, ok := pickVariant(w, r, link); ok {
+		destination = picked.Target
+		variant = &picked
+	}
+
+	target, err := url.Parse(destination)
+	if err != nil {
+		ro.log.Error("failed to parse saved url", slog.String("alias", link.Alias), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return nil, nil, false
+	}
+
+	subPath := redirectSubPath(r)
+	if subPath != "" && !link.AppendPath {
+		ro.log.Info("path passthrough is disabled", slog.String("alias", link.Alias), slog.String("sub_path", subPath))
+		if ro.renderErrorPage(w, r, http.StatusNotFound) {
+			return nil, nil, false
+		}
+		render.JSON(w, r, resp.Error("url not found for given alias"))
+
+		return nil, nil, false
+	}
+
+	if link.AppendPath {
+		err = passthrough.AppendPath(target, subPath)
+		if err != nil {
+			ro.log.Info("invalid sub-path", slog.String("sub_path", subPath), slo.Err(err))
+			render.JSON(w, r, resp.Error("invalid request"))
+
+			return nil, nil, false
+		}
+	}
+
+	if link.Template != nil {
+		utm.Apply(target, templateParams(*link.Template)...)
+	}
+
+	if link.MergeQuery {
+		query := passthrough.DropParam(r.URL.RawQuery, continueParam)
+		//clients may still send the password in the query, it is not read but must not reach the destination
+		if link.PasswordHash != "" {
+			query = passthrough.DropParam(query, passwordParam)
+		}
+		passthrough.MergeQuery(target, query)
+	}
+
+	return target, variant, true
+}
+
+// linkActive reports whether the time is inside the active window of the link
+func linkActive(link storage.Link, now time.Time) bool {
+	if !link.NotBefore.IsZero() && now.Before(link.NotBefore) {
+		return false
+	}
+
+	if !link.NotAfter.IsZero() && !now.Before(link.NotAfter) {
+		return false
+	}
+
+	return true
+}
+
+// redirectSubPath returns the escaped part of the request path that follows the alias,
+// or an empty string if the request was routed without a wildcard
+func redirectSubPath(r *http.Request) string {
+	pattern := chi.RouteContext(r.Context()).RoutePattern()
+	if !strings.HasSuffix(pattern, "/*") {
+		return ""
+	}
+
+	//everything before the wildcard, e.g. "/v1/{alias}/" has 3 separators
+	depth := strings.Count(strings.TrimSuffix(pattern, "*"), "/")
+	segments := strings.SplitN(r.URL.EscapedPath(), "/", depth+1)
+	if len(segments) <= depth {
+		return ""
+	}
+
+	return segments[depth]
 }
 
 func (ro *router) deleteAliasHandler(w http.ResponseWriter, r *http.Request) {
@@ -160,7 +705,7 @@ func (ro *router) deleteAliasHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := ro.storage.DeleteURL(alias)
+	err := ro.storage.DeleteURL(workspaceID(r), domainParam(r), alias)
 	if err != nil {
 		ro.log.Error("failed to delete url", slog.String("alias", alias))
 		render.JSON(w, r, resp.Error("internal error"))
@@ -239,7 +784,27 @@ func (ro *router) updateAliasHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = ro.storage.UpdateAlias(oldAlias, newAlias)
+	if msg := ro.checkRename(workspaceID(r), domainParam(r), oldAlias, newAlias); msg != "" {
+		render.JSON(w, r, resp.Error(msg))
+
+		return
+	}
+
+	err = ro.storage.UpdateAlias(workspaceID(r), domainParam(r), oldAlias, newAlias)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		ro.log.Info("url not found", slog.String("old_alias", oldAlias))
+		render.JSON(w, r, resp.Error("url not found for given alias"))
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrURLAlreadyExists) {
+		ro.log.Info("new alias already exists", slog.String("new_alias", newAlias))
+		render.JSON(w, r, resp.Error("url already exists"))
+
+		return
+	}
+
 	if err != nil {
 		ro.log.Error("failed to update alias", slog.String("old_alias", oldAlias), slog.String("new_alias", newAlias))
 		render.JSON(w, r, resp.Error("internal error"))
@@ -17,6 +17,7 @@ import (
 	"shorty/internal/server/mocks"
 	"shorty/internal/storage"
 	"testing"
+	"time"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -34,7 +35,7 @@ func TestSaveHandler(t *testing.T) {
 				Alias:    "55555", //length
 			},
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(int64(6), nil)
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).Return(int64(6), nil)
 			},
 		},
 		"Success: custom alias": {
@@ -45,34 +46,199 @@ func TestSaveHandler(t *testing.T) {
 				Alias:    "youtb",
 			},
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(int64(8), nil)
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).Return(int64(8), nil)
 			},
 		},
 		"Empty URL": {
 			input:   `{"alias": "55555"}`,
 			wantErr: errors.New("\"URL\" field is mandatory"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().SaveURL(gomock.Any(), gomock.Any()).AnyTimes()
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).AnyTimes()
 			},
 		},
+		"Invalid og image": {
+			input:   `{"url": "https://example.com", "og_image": "ftp://example.com/og.png"}`,
+			wantErr: errors.New("\"OGImage\" field is not valid"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
 		"Failed to save url": {
 			input:   `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}`,
 			wantErr: errors.New("failed to save url"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(int64(0), errors.New("cannot prepare sql statement"))
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).Return(int64(0), errors.New("cannot prepare sql statement"))
 			},
 		},
 		"Url already exists": {
 			input:   `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}`,
 			wantErr: errors.New("url already exists"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(int64(0), fmt.Errorf("%s: %w", "storage.sqlite.SaveURL", storage.ErrURLAlreadyExists))
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).Return(int64(0), fmt.Errorf("%s: %w", "storage.sqlite.SaveLink", storage.ErrURLAlreadyExists))
 			},
 		},
 		"Empty request": {
 			wantErr: errors.New("empty request"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().SaveURL(gomock.Any(), gomock.Any()).AnyTimes()
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).AnyTimes()
+			},
+		},
+		"Success: with template": {
+			alias: "sprng",
+			input: `{"url": "https://example.com", "alias": "sprng", "template": "spring"}`,
+			expectedResp: Response{
+				Response: resp.OK(),
+				Alias:    "sprng",
+			},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				template := storage.Template{ID: 3, Name: "spring", Source: "newsletter"}
+				mockUrlProvider.EXPECT().GetTemplate(storage.DefaultWorkspace, "spring").Return(template, nil)
+				mockUrlProvider.EXPECT().SaveLink(storage.Link{
+					Alias:       "sprng",
+					URL:         "https://example.com/",
+					OriginalURL: "https://example.com",
+					Template:    &template,
+				}).Return(int64(9), nil)
+			},
+		},
+		"Negative max clicks": {
+			input:   `{"url": "https://example.com", "max_clicks": -1}`,
+			wantErr: errors.New("\"MaxClicks\" field is not valid"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Success: with max clicks": {
+			alias: "onbrd",
+			input: `{"url": "https://example.com", "alias": "onbrd", "max_clicks": 1}`,
+			expectedResp: Response{
+				Response: resp.OK(),
+				Alias:    "onbrd",
+			},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveLink(storage.Link{
+					Alias:       "onbrd",
+					URL:         "https://example.com/",
+					OriginalURL: "https://example.com",
+					MaxClicks:   1,
+				}).Return(int64(10), nil)
+			},
+		},
+		"Success: with activation window": {
+			alias: "launc",
+			input: `{"url": "https://example.com", "alias": "launc", "not_before": "2030-01-01T10:00:00Z", "fallback_url": "https://example.com/soon"}`,
+			expectedResp: Response{
+				Response: resp.OK(),
+				Alias:    "launc",
+			},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveLink(storage.Link{
+					Alias:       "launc",
+					URL:         "https://example.com/",
+					OriginalURL: "https://example.com",
+					NotBefore:   time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC),
+					FallbackURL: "https://example.com/soon",
+				}).Return(int64(11), nil)
+			},
+		},
+		"Invalid activation window": {
+			input:   `{"url": "https://example.com", "not_before": "2030-01-02T00:00:00Z", "not_after": "2030-01-01T00:00:00Z"}`,
+			wantErr: errors.New("invalid request: not_after must be later than not_before"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Rule without conditions": {
+			input:   `{"url": "https://example.com", "rules": [{"target": "https://example.com/other"}]}`,
+			wantErr: errors.New("invalid request: rule must match an os or a device"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Rule with unknown os": {
+			input:   `{"url": "https://example.com", "rules": [{"os": "symbian", "target": "https://example.com/other"}]}`,
+			wantErr: errors.New("\"OS\" field is not valid"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Single variant": {
+			input:   `{"url": "https://example.com", "variants": [{"target": "https://example.com/a", "weight": 1}]}`,
+			wantErr: errors.New("\"Variants\" field is not valid"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Variant without weight": {
+			input:   `{"url": "https://example.com", "variants": [{"target": "https://example.com/a"}, {"target": "https://example.com/b", "weight": 1}]}`,
+			wantErr: errors.New("\"Weight\" field is not valid"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Success: with locales": {
+			alias: "guide",
+			input: `{"url": "https://example.com/en", "alias": "guide", "locales": {"de-de": "https://example.com/de"}}`,
+			expectedResp: Response{
+				Response: resp.OK(),
+				Alias:    "guide",
+			},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveLink(storage.Link{
+					Alias:       "guide",
+					URL:         "https://example.com/en",
+					OriginalURL: "https://example.com/en",
+					Locales:     map[string]string{"de-DE": "https://example.com/de"},
+				}).Return(int64(12), nil)
+			},
+		},
+		"Locale with invalid url": {
+			input:   `{"url": "https://example.com/en", "locales": {"de": "not a url"}}`,
+			wantErr: errors.New("\"Locales[de]\" is not a valid URL"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Unknown locale": {
+			input:   `{"url": "https://example.com/en", "locales": {"klingon-ish": "https://example.com/tlh"}}`,
+			wantErr: errors.New("invalid request: unknown locale \"klingon-ish\""),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Template not found": {
+			input:   `{"url": "https://example.com", "template": "spring"}`,
+			wantErr: errors.New("template not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetTemplate(storage.DefaultWorkspace, "spring").Return(storage.Template{}, storage.ErrTemplateNotFound)
+			},
+		},
+		"Success: reuse existing": {
+			alias: "exist",
+			input: `{"url": "https://example.com", "alias": "newer", "reuse_existing": true}`,
+			expectedResp: Response{
+				Response: resp.OK(),
+				Alias:    "exist",
+				Reused:   true,
+			},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().FindLinkByURL(storage.DefaultWorkspace, "", "", "https://example.com/").Return(storage.Link{Alias: "exist"}, nil)
+			},
+		},
+		"Success: nothing to reuse": {
+			alias: "newer",
+			input: `{"url": "https://example.com", "alias": "newer", "reuse_existing": true}`,
+			expectedResp: Response{
+				Response: resp.OK(),
+				Alias:    "newer",
+			},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().FindLinkByURL(storage.DefaultWorkspace, "", "", "https://example.com/").Return(storage.Link{}, storage.ErrURLNotFound)
+				mockUrlProvider.EXPECT().SaveLink(storage.Link{Alias: "newer", URL: "https://example.com/", OriginalURL: "https://example.com"}).Return(int64(11), nil)
+			},
+		},
+		"Success: normalized url": {
+			alias: "normd",
+			input: `{"url": "HTTP://Example.com:80/a/../b?", "alias": "normd"}`,
+			expectedResp: Response{
+				Response: resp.OK(),
+				Alias:    "normd",
+			},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveLink(storage.Link{
+					Alias:       "normd",
+					URL:         "http://example.com/b",
+					OriginalURL: "HTTP://Example.com:80/a/../b?",
+				}).Return(int64(13), nil)
+			},
+		},
+		"Failed to find existing link": {
+			input:   `{"url": "https://example.com", "reuse_existing": true}`,
+			wantErr: errors.New("failed to save url"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().FindLinkByURL(storage.DefaultWorkspace, "", "", "https://example.com/").Return(storage.Link{}, errors.New("database is locked"))
 			},
 		},
 	}
@@ -84,6 +250,7 @@ func TestSaveHandler(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectLinkCounts(mockStorage)
 			tc.prepare(mockStorage)
 			handler := SetupRouter(mockStorage, config.Config{}, slog.Default())
 			var req *http.Request
@@ -105,6 +272,7 @@ func TestSaveHandler(t *testing.T) {
 			} else {
 				assert.Equal(t, tc.expectedResp.Status, response.Status)
 				assert.Equal(t, len(tc.expectedResp.Alias), len(response.Alias))
+				assert.Equal(t, tc.expectedResp.Reused, response.Reused)
 
 				if tc.alias != "" {
 					assert.Equal(t, tc.expectedResp.Alias, response.Alias)
@@ -115,19 +283,174 @@ func TestSaveHandler(t *testing.T) {
 }
 
 func TestRedirectHandler(t *testing.T) {
+	appLink := storage.Link{
+		URL: "https://example.com/app",
+		Rules: []storage.Rule{
+			{OS: "ios", Target: "https://apps.apple.com/app/id1"},
+			{OS: "android", Device: "mobile", Target: "https://play.google.com/store/apps/details?id=app"},
+		},
+	}
+
 	tests := map[string]struct {
-		alias    string
-		url      string
-		wantErr  error
-		wantCode int
-		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+		alias     string
+		path      string
+		userAgent string
+		url       string
+		wantErr   error
+		wantCode  int
+		prepare   func(mockUrlProvider *mocks.MockUrlProvider)
 	}{
 		"Success": {
 			alias:    "youtb",
 			url:      "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
 			wantCode: http.StatusFound,
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().GetURL(gomock.Any()).Return("https://www.youtube.com/watch?v=dQw4w9WgXcQ", nil)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "youtb").Return(storage.Link{URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}, nil)
+			},
+		},
+		"Query is ignored without passthrough": {
+			alias:    "youtb",
+			path:     "?utm_source=x",
+			url:      "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+			wantCode: http.StatusFound,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "youtb").Return(storage.Link{URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}, nil)
+			},
+		},
+		"Query passthrough": {
+			alias:    "youtb",
+			path:     "?utm_source=x&v=override",
+			url:      "https://www.youtube.com/watch?utm_source=x&v=override",
+			wantCode: http.StatusFound,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "youtb").Return(storage.Link{
+					URL:        "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+					MergeQuery: true,
+				}, nil)
+			},
+		},
+		"Path passthrough": {
+			alias:    "apidc",
+			path:     "/extra/file%20name.json?page=2",
+			url:      "https://example.com/docs/extra/file%20name.json?v=1&page=2",
+			wantCode: http.StatusFound,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "apidc").Return(storage.Link{
+					URL:        "https://example.com/docs?v=1",
+					MergeQuery: true,
+					AppendPath: true,
+				}, nil)
+			},
+		},
+		"Template is applied": {
+			alias:    "sprng",
+			path:     "?utm_medium=chat",
+			url:      "https://example.com/a?utm_source=partner&utm_campaign=spring&utm_medium=chat",
+			wantCode: http.StatusFound,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "sprng").Return(storage.Link{
+					URL:        "https://example.com/a?utm_source=partner",
+					MergeQuery: true,
+					Template:   &storage.Template{Source: "newsletter", Medium: "email", Campaign: "spring"},
+				}, nil)
+			},
+		},
+		"Before activation window with fallback": {
+			alias:    "launc",
+			url:      "https://example.com/soon",
+			wantCode: http.StatusFound,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{
+					URL:         "https://example.com/launch",
+					NotBefore:   time.Now().Add(time.Hour),
+					FallbackURL: "https://example.com/soon",
+				}, nil)
+			},
+		},
+		"After activation window": {
+			alias:    "launc",
+			wantCode: http.StatusNotFound,
+			wantErr:  errors.New("url not found for given alias"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{
+					URL:       "https://example.com/launch",
+					NotBefore: time.Now().Add(-2 * time.Hour),
+					NotAfter:  time.Now().Add(-time.Hour),
+				}, nil)
+			},
+		},
+		"Inside activation window": {
+			alias:    "launc",
+			url:      "https://example.com/launch",
+			wantCode: http.StatusFound,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{
+					URL:       "https://example.com/launch",
+					NotBefore: time.Now().Add(-time.Hour),
+					NotAfter:  time.Now().Add(time.Hour),
+				}, nil)
+			},
+		},
+		"Limited link": {
+			alias:    "onbrd",
+			url:      "https://example.com",
+			wantCode: http.StatusFound,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "onbrd").Return(storage.Link{URL: "https://example.com", MaxClicks: 3, ClicksLeft: 1}, nil)
+				mockUrlProvider.EXPECT().ConsumeClick(storage.DefaultWorkspace, "", "onbrd").Return(nil)
+			},
+		},
+		"Limited link is exhausted": {
+			alias:    "onbrd",
+			wantCode: http.StatusGone,
+			wantErr:  errors.New("link is no longer available"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "onbrd").Return(storage.Link{URL: "https://example.com", MaxClicks: 3}, nil)
+			},
+		},
+		"Limited link is exhausted concurrently": {
+			alias:    "onbrd",
+			wantCode: http.StatusGone,
+			wantErr:  errors.New("link is no longer available"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "onbrd").Return(storage.Link{URL: "https://example.com", MaxClicks: 3, ClicksLeft: 1}, nil)
+				mockUrlProvider.EXPECT().ConsumeClick(storage.DefaultWorkspace, "", "onbrd").Return(fmt.Errorf("%s: %w", "storage.sqlite.ConsumeClick", storage.ErrLinkExhausted))
+			},
+		},
+		"Platform rule: ios": {
+			alias:     "myapp",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148",
+			url:       "https://apps.apple.com/app/id1",
+			wantCode:  http.StatusFound,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "myapp").Return(appLink, nil)
+			},
+		},
+		"Platform rule: android phone": {
+			alias:     "myapp",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) Chrome/120.0.0.0 Mobile Safari/537.36",
+			url:       "https://play.google.com/store/apps/details?id=app",
+			wantCode:  http.StatusFound,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "myapp").Return(appLink, nil)
+			},
+		},
+		"Platform rule: default target": {
+			alias:     "myapp",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X710) Chrome/120.0.0.0 Safari/537.36",
+			url:       "https://example.com/app",
+			wantCode:  http.StatusFound,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "myapp").Return(appLink, nil)
+			},
+		},
+		"Path passthrough is disabled": {
+			alias:    "apidc",
+			path:     "/extra/path",
+			wantCode: http.StatusOK,
+			wantErr:  errors.New("url not found for given alias"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "apidc").Return(storage.Link{URL: "https://example.com/docs"}, nil)
 			},
 		},
 		"Url does not exist": {
@@ -135,7 +458,7 @@ func TestRedirectHandler(t *testing.T) {
 			wantCode: http.StatusOK,
 			wantErr:  errors.New("url not found for given alias"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().GetURL(gomock.Any()).Return("", storage.ErrURLNotFound)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", gomock.Any()).Return(storage.Link{}, storage.ErrURLNotFound)
 			},
 		},
 		"Internal error": {
@@ -143,7 +466,7 @@ func TestRedirectHandler(t *testing.T) {
 			wantCode: http.StatusOK,
 			wantErr:  errors.New("internal error"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().GetURL(gomock.Any()).Return("", errors.New("unexpected error"))
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", gomock.Any()).Return(storage.Link{}, errors.New("unexpected error"))
 			},
 		},
 	}
@@ -154,6 +477,7 @@ func TestRedirectHandler(t *testing.T) {
 			defer ctrl.Finish()
 
 			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectDefaultDomain(mockStorage)
 			tc.prepare(mockStorage)
 
 			r := &router{
@@ -163,8 +487,10 @@ func TestRedirectHandler(t *testing.T) {
 
 			chiRouter := chi.NewRouter()
 			chiRouter.Get("/v1/{alias}", r.redirectHandler)
+			chiRouter.Get("/v1/{alias}/*", r.redirectHandler)
 
-			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/%s", tc.alias), nil)
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/%s%s", tc.alias, tc.path), nil)
+			req.Header.Set("User-Agent", tc.userAgent)
 			w := httptest.NewRecorder()
 			chiRouter.ServeHTTP(w, req)
 			require.Equal(t, tc.wantCode, w.Code)
@@ -199,14 +525,14 @@ func TestUpdateHandler(t *testing.T) {
 				Alias:    "qwert",
 			},
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().UpdateAlias(gomock.Any(), gomock.Any()).Return(nil)
+				mockUrlProvider.EXPECT().UpdateAlias(storage.DefaultWorkspace, "", gomock.Any(), gomock.Any()).Return(nil)
 			},
 		},
 		"Empty request": {
 			oldAlias: "youtb",
 			wantErr:  errors.New("empty request"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().UpdateAlias(gomock.Any(), gomock.Any()).AnyTimes()
+				mockUrlProvider.EXPECT().UpdateAlias(storage.DefaultWorkspace, "", gomock.Any(), gomock.Any()).AnyTimes()
 			},
 		},
 		"Missed mandatory field: new_alias": {
@@ -214,7 +540,7 @@ func TestUpdateHandler(t *testing.T) {
 			input:    `{}`,
 			wantErr:  errors.New("\"NewAlias\" field is mandatory"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().UpdateAlias(gomock.Any(), gomock.Any()).AnyTimes()
+				mockUrlProvider.EXPECT().UpdateAlias(storage.DefaultWorkspace, "", gomock.Any(), gomock.Any()).AnyTimes()
 			},
 		},
 		"new_alias is too short": {
@@ -222,7 +548,7 @@ func TestUpdateHandler(t *testing.T) {
 			input:    `{"new_alias": "qw"}`,
 			wantErr:  errors.New("invalid request: new alias is too short"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().UpdateAlias(gomock.Any(), gomock.Any()).AnyTimes()
+				mockUrlProvider.EXPECT().UpdateAlias(storage.DefaultWorkspace, "", gomock.Any(), gomock.Any()).AnyTimes()
 			},
 		},
 		"Same alias": {
@@ -230,7 +556,25 @@ func TestUpdateHandler(t *testing.T) {
 			input:    `{"new_alias": "youtb"}`,
 			wantErr:  errors.New("new alias is the same as the old one"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().UpdateAlias(gomock.Any(), gomock.Any()).AnyTimes()
+				mockUrlProvider.EXPECT().UpdateAlias(storage.DefaultWorkspace, "", gomock.Any(), gomock.Any()).AnyTimes()
+			},
+		},
+		"Alias already exists": {
+			oldAlias: "youtb",
+			input:    `{"new_alias": "qwert"}`,
+			wantErr:  errors.New("url already exists"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().UpdateAlias(storage.DefaultWorkspace, "", "youtb", "qwert").
+					Return(fmt.Errorf("storage.sqlite.UpdateAlias: %w", storage.ErrURLAlreadyExists))
+			},
+		},
+		"Alias not found": {
+			oldAlias: "youtb",
+			input:    `{"new_alias": "qwert"}`,
+			wantErr:  errors.New("url not found for given alias"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().UpdateAlias(storage.DefaultWorkspace, "", "youtb", "qwert").
+					Return(fmt.Errorf("storage.sqlite.UpdateAlias: %w", storage.ErrURLNotFound))
 			},
 		},
 		"Internal error": {
@@ -238,7 +582,7 @@ func TestUpdateHandler(t *testing.T) {
 			input:    `{"new_alias": "qwert"}`,
 			wantErr:  errors.New("internal error"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().UpdateAlias(gomock.Any(), gomock.Any()).Return(errors.New("unexpected error"))
+				mockUrlProvider.EXPECT().UpdateAlias(storage.DefaultWorkspace, "", gomock.Any(), gomock.Any()).Return(errors.New("unexpected error"))
 			},
 		},
 	}
@@ -281,14 +625,14 @@ func TestDeleteHandler(t *testing.T) {
 		"Successfully deleted url": {
 			alias: "youtb",
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().DeleteURL(gomock.Any()).Return(nil)
+				mockUrlProvider.EXPECT().DeleteURL(storage.DefaultWorkspace, "", gomock.Any()).Return(nil)
 			},
 		},
 		"Internal error": {
 			alias:   "youtb",
 			wantErr: errors.New("internal error"),
 			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
-				mockUrlProvider.EXPECT().DeleteURL(gomock.Any()).Return(errors.New("unexpected error"))
+				mockUrlProvider.EXPECT().DeleteURL(storage.DefaultWorkspace, "", gomock.Any()).Return(errors.New("unexpected error"))
 			},
 		},
 	}
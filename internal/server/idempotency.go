@@ -0,0 +1,102 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/storage"
+	"time"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	//replayedHeader marks a response saved under an idempotency key
+	replayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// reserveIdempotencyKey takes the Idempotency-Key of a creation request in the workspace of the caller before the link is saved.
+// It reports false if a response has been written: the saved response of a repeated request or an error
+func (ro *router) reserveIdempotencyKey(w http.ResponseWriter, r *http.Request, key string, body []byte) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		ro.log.Info("idempotency key is too long", slog.Int("length", len(key)))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, resp.Error("invalid request: idempotency key is too long"))
+
+		return false
+	}
+
+	sum := sha256.Sum256(body)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	record, err := ro.storage.ReserveIdempotencyKey(workspaceID(r), key, fingerprint, time.Now().Add(-ro.idempotencyWindow))
+	if err == nil {
+		return true
+	}
+
+	if !errors.Is(err, storage.ErrIdempotencyKeyExists) {
+		ro.log.Error("failed to reserve idempotency key", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to save url"))
+
+		return false
+	}
+
+	if record.Fingerprint != fingerprint {
+		ro.log.Info("idempotency key reused with a different request")
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, resp.Error("idempotency key was used with a different request"))
+
+		return false
+	}
+
+	if record.Response == nil {
+		ro.log.Info("request with the idempotency key is in progress")
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, resp.Error("request with this idempotency key is in progress"))
+
+		return false
+	}
+
+	var saved Response
+	err = json.Unmarshal(record.Response, &saved)
+	if err != nil {
+		ro.log.Error("failed to decode saved response", slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return false
+	}
+
+	ro.log.Info("replaying saved response", slog.String("alias", saved.Alias))
+	w.Header().Set(replayedHeader, "true")
+	render.JSON(w, r, saved)
+
+	return false
+}
+
+// completeIdempotencyKey saves the response of a successful request, so that repeated requests get it
+func (ro *router) completeIdempotencyKey(workspaceID int64, key string, response Response) {
+	encoded, err := json.Marshal(response)
+	if err == nil {
+		err = ro.storage.CompleteIdempotencyKey(workspaceID, key, encoded)
+	}
+
+	//the link is saved already, a retry would create another one
+	if err != nil {
+		ro.log.Error("failed to save idempotent response", slo.Err(err))
+	}
+}
+
+// releaseIdempotencyKey frees the key of a failed request, so that it can be retried
+func (ro *router) releaseIdempotencyKey(workspaceID int64, key string) {
+	err := ro.storage.ReleaseIdempotencyKey(workspaceID, key)
+	if err != nil {
+		ro.log.Error("failed to release idempotency key", slo.Err(err))
+	}
+}
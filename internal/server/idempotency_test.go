@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveHandler_Idempotency(t *testing.T) {
+	const (
+		body = `{"url": "https://example.com"}`
+		//sha256 of the body
+		fingerprint = "6425a62b6da4d4674ad8b6b7c3594a2bec181e8405b41146deddc4de8bae82b3"
+	)
+	exists := fmt.Errorf("storage.sqlite.ReserveIdempotencyKey: %w", storage.ErrIdempotencyKeyExists)
+
+	tests := map[string]struct {
+		key          string
+		wantCode     int
+		wantReplayed bool
+		expectedResp Response
+		prepare      func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"First request": {
+			key:          "retry-1",
+			wantCode:     http.StatusOK,
+			expectedResp: Response{Response: resp.OK(), Alias: "55555"},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-1", fingerprint, gomock.Any()).Return(storage.IdempotencyRecord{}, nil)
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).Return(int64(1), nil)
+				mockUrlProvider.EXPECT().CompleteIdempotencyKey(storage.DefaultWorkspace, "retry-1", gomock.Any()).DoAndReturn(func(workspaceID int64, key string, response []byte) error {
+					var saved Response
+					require.NoError(t, json.Unmarshal(response, &saved))
+					assert.Equal(t, resp.StatusOk, saved.Status)
+
+					return nil
+				})
+			},
+		},
+		"Repeated request": {
+			key:          "retry-1",
+			wantCode:     http.StatusOK,
+			wantReplayed: true,
+			expectedResp: Response{Response: resp.OK(), Alias: "abcde"},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-1", fingerprint, gomock.Any()).Return(storage.IdempotencyRecord{
+					Key:         "retry-1",
+					Fingerprint: fingerprint,
+					Response:    []byte(`{"status":"ok","alias":"abcde"}`),
+				}, exists)
+			},
+		},
+		"Request in progress": {
+			key:          "retry-1",
+			wantCode:     http.StatusConflict,
+			expectedResp: Response{Response: resp.Error("request with this idempotency key is in progress")},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-1", fingerprint, gomock.Any()).Return(storage.IdempotencyRecord{
+					Key:         "retry-1",
+					Fingerprint: fingerprint,
+				}, exists)
+			},
+		},
+		"Different request": {
+			key:          "retry-1",
+			wantCode:     http.StatusUnprocessableEntity,
+			expectedResp: Response{Response: resp.Error("idempotency key was used with a different request")},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-1", fingerprint, gomock.Any()).Return(storage.IdempotencyRecord{
+					Key:         "retry-1",
+					Fingerprint: "other",
+					Response:    []byte(`{"status":"ok","alias":"abcde"}`),
+				}, exists)
+			},
+		},
+		"Failed request releases the key": {
+			key:          "retry-1",
+			wantCode:     http.StatusOK,
+			expectedResp: Response{Response: resp.Error("failed to save url")},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-1", fingerprint, gomock.Any()).Return(storage.IdempotencyRecord{}, nil)
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).Return(int64(0), errors.New("database is locked"))
+				mockUrlProvider.EXPECT().ReleaseIdempotencyKey(storage.DefaultWorkspace, "retry-1").Return(nil)
+			},
+		},
+		"Key too long": {
+			key:          strings.Repeat("k", maxIdempotencyKeyLength+1),
+			wantCode:     http.StatusBadRequest,
+			expectedResp: Response{Response: resp.Error("invalid request: idempotency key is too long")},
+			prepare:      func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectLinkCounts(mockStorage)
+			tc.prepare(mockStorage)
+
+			r := &router{
+				storage:           mockStorage,
+				log:               slog.Default(),
+				idempotencyWindow: time.Hour,
+			}
+
+			chiRouter := chi.NewRouter()
+			chiRouter.Post("/v1/url", r.saveAliasHandler)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/url", bytes.NewBufferString(body))
+			req.Header.Set(idempotencyHeader, tc.key)
+			w := httptest.NewRecorder()
+			chiRouter.ServeHTTP(w, req)
+
+			require.Equal(t, tc.wantCode, w.Code)
+
+			var response Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			if tc.expectedResp.Alias == "55555" {
+				assert.Len(t, response.Alias, AliasLength)
+				response.Alias = "55555"
+			}
+			assert.Equal(t, tc.expectedResp, response)
+			assert.Equal(t, tc.wantReplayed, w.Header().Get(replayedHeader) == "true")
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/tagname"
+	"shorty/internal/storage"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// LinkInfo is the metadata of a short link
+type LinkInfo struct {
+	Domain        string            `json:"domain,omitempty"`
+	Alias         string            `json:"alias"`
+	URL           string            `json:"url"`
+	OriginalURL   string            `json:"original_url,omitempty"`
+	Owner         string            `json:"owner,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Preview       bool              `json:"preview,omitempty"`
+	OGTitle       string            `json:"og_title,omitempty"`
+	OGDescription string            `json:"og_description,omitempty"`
+	OGImage       string            `json:"og_image,omitempty"`
+	MergeQuery    bool              `json:"merge_query"`
+	AppendPath    bool              `json:"append_path"`
+	Template      string            `json:"template,omitempty"`
+	Protected     bool              `json:"protected"`
+	MaxClicks     int64             `json:"max_clicks,omitempty"`
+	ClicksLeft    *int64            `json:"clicks_left,omitempty"`
+	NotBefore     *time.Time        `json:"not_before,omitempty"`
+	NotAfter      *time.Time        `json:"not_after,omitempty"`
+	FallbackURL   string            `json:"fallback_url,omitempty"`
+	Active        bool              `json:"active"`
+	Rules         []Rule            `json:"rules,omitempty"`
+	Variants      []Variant         `json:"variants,omitempty"`
+	Locales       map[string]string `json:"locales,omitempty"`
+	Countries     map[string]string `json:"countries,omitempty"`
+	//CountryClicks counts redirects per visitor country
+	CountryClicks map[string]int64 `json:"country_clicks,omitempty"`
+	//Health is the result of the last liveness check of the url, omitted if the link was not checked
+	Health   *Health  `json:"health,omitempty"`
+	Broken   bool     `json:"broken,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	FolderID int64    `json:"folder_id,omitempty"`
+}
+
+// Health is the result of a liveness check of a link url
+type Health struct {
+	Status    int       `json:"status,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type LinkResponse struct {
+	resp.Response
+	Link *LinkInfo `json:"link,omitempty"`
+}
+
+type ListResponse struct {
+	resp.Response
+	Links []LinkInfo `json:"links"`
+}
+
+const (
+	handlersOperationGetLink   = "handlers.url.get"
+	handlersOperationListLinks = "handlers.url.list"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+func (ro *router) getLinkHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationGetLink),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	alias := chi.URLParam(r, "alias")
+	link, err := ro.storage.GetLink(workspaceID(r), domainParam(r), alias)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		log.Info("url not found", slog.String("alias", alias))
+		render.JSON(w, r, resp.Error("url not found for given alias"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to get url by given alias", slog.String("alias", alias), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	info := linkInfo(link, time.Now())
+	render.JSON(w, r, LinkResponse{
+		Response: resp.OK(),
+		Link:     &info,
+	})
+}
+
+// listLinksHandler returns a page of links of the workspace, ?broken=true selects links whose last liveness check failed,
+// every ?tag= selects links with the tag and ?folder= selects links filed directly in the folder
+func (ro *router) listLinksHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationListLinks),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	filter, msg := listFilter(r)
+	if msg != "" {
+		log.Info("invalid list request", slog.String("reason", msg))
+		render.JSON(w, r, resp.Error(msg))
+
+		return
+	}
+	filter.WorkspaceID = workspaceID(r)
+
+	links, err := ro.storage.ListLinks(filter)
+	if err != nil {
+		log.Error("failed to list links", slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	now := time.Now()
+	infos := make([]LinkInfo, 0, len(links))
+	for _, link := range links {
+		infos = append(infos, linkInfo(link, now))
+	}
+
+	render.JSON(w, r, ListResponse{
+		Response: resp.OK(),
+		Links:    infos,
+	})
+}
+
+// listFilter reads the page and the filters of a list request,
+// it returns a human-readable error text for a client if a parameter is invalid
+func listFilter(r *http.Request) (storage.LinkFilter, string) {
+	query := r.URL.Query()
+	filter := storage.LinkFilter{Limit: defaultListLimit}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxListLimit {
+			return storage.LinkFilter{}, fmt.Sprintf("invalid request: limit must be between 1 and %d", maxListLimit)
+		}
+		filter.Limit = n
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return storage.LinkFilter{}, "invalid request: offset must not be negative"
+		}
+		filter.Offset = n
+	}
+
+	if broken := query.Get("broken"); broken != "" {
+		b, err := strconv.ParseBool(broken)
+		if err != nil {
+			return storage.LinkFilter{}, "invalid request: broken must be true or false"
+		}
+		filter.Broken = b
+	}
+
+	filter.Tags = tagname.NormalizeAll(query["tag"])
+	if slices.Contains(filter.Tags, "") {
+		return storage.LinkFilter{}, "invalid request: tag must not be empty"
+	}
+
+	if folder := query.Get("folder"); folder != "" {
+		id, err := strconv.ParseInt(folder, 10, 64)
+		if err != nil || id < 1 {
+			return storage.LinkFilter{}, "invalid request: folder must be a folder id"
+		}
+		filter.FolderID = id
+	}
+
+	return filter, ""
+}
+
+func linkInfo(link storage.Link, now time.Time) LinkInfo {
+	info := LinkInfo{
+		Domain:  link.Domain,
+		Alias:   link.Alias,
+		URL:     link.URL,
+		Owner:   link.Owner,
+		Title:   link.Title,
+		Preview: link.Preview,
+
+		OGTitle:       link.OpenGraph.Title,
+		OGDescription: link.OpenGraph.Description,
+		OGImage:       link.OpenGraph.Image,
+		MergeQuery:    link.MergeQuery,
+		AppendPath:    link.AppendPath,
+		Protected:     link.PasswordHash != "",
+		MaxClicks:     link.MaxClicks,
+		FallbackURL:   link.FallbackURL,
+		Locales:       link.Locales,
+		Countries:     link.Countries,
+
+		CountryClicks: link.CountryClicks,
+		Tags:          link.Tags,
+		FolderID:      link.FolderID,
+		Active:        linkActive(link, now) && (link.MaxClicks == 0 || link.ClicksLeft > 0),
+	}
+
+	if link.OriginalURL != link.URL {
+		info.OriginalURL = link.OriginalURL
+	}
+
+	if link.Template != nil {
+		info.Template = link.Template.Name
+	}
+
+	if !link.Health.CheckedAt.IsZero() {
+		info.Health = &Health{
+			Status:    link.Health.Status,
+			LatencyMS: link.Health.Latency.Milliseconds(),
+			CheckedAt: link.Health.CheckedAt,
+			Error:     link.Health.Error,
+		}
+		info.Broken = link.Health.Broken()
+	}
+
+	if len(link.Rules) > 0 {
+		info.Rules = rulesFromStorage(link.Rules)
+	}
+
+	if len(link.Variants) > 0 {
+		info.Variants = variantsFromStorage(link.Variants)
+	}
+
+	if link.MaxClicks > 0 {
+		clicksLeft := link.ClicksLeft
+		info.ClicksLeft = &clicksLeft
+	}
+
+	if !link.NotBefore.IsZero() {
+		notBefore := link.NotBefore
+		info.NotBefore = &notBefore
+	}
+
+	if !link.NotAfter.IsZero() {
+		notAfter := link.NotAfter
+		info.NotAfter = &notAfter
+	}
+
+	return info
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+	"time"
+)
+
+func TestGetLinkHandler(t *testing.T) {
+	tests := map[string]struct {
+		alias    string
+		wantErr  error
+		expected string
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Success": {
+			alias: "launc",
+			expected: `{"status":"ok","link":{
+				"alias":"launc","url":"https://example.com","merge_query":true,"append_path":false,
+				"template":"spring","protected":true,"max_clicks":10,"clicks_left":4,
+				"not_before":"2020-01-01T10:00:00Z","fallback_url":"https://example.com/soon","active":true}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{
+					Alias:        "launc",
+					URL:          "https://example.com",
+					MergeQuery:   true,
+					Template:     &storage.Template{Name: "spring"},
+					PasswordHash: "hash",
+					MaxClicks:    10,
+					ClicksLeft:   4,
+					NotBefore:    time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC),
+					FallbackURL:  "https://example.com/soon",
+				}, nil)
+			},
+		},
+		"Inactive link": {
+			alias: "launc",
+			expected: `{"status":"ok","link":{
+				"alias":"launc","url":"https://example.com","merge_query":false,"append_path":false,
+				"protected":false,"not_after":"2020-01-01T10:00:00Z","active":false}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{
+					Alias:    "launc",
+					URL:      "https://example.com",
+					NotAfter: time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC),
+				}, nil)
+			},
+		},
+		"Not found": {
+			alias:   "launc",
+			wantErr: errors.New("url not found for given alias"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{}, storage.ErrURLNotFound)
+			},
+		},
+		"Internal error": {
+			alias:   "launc",
+			wantErr: errors.New("internal error"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{}, errors.New("unexpected error"))
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			tc.prepare(mockStorage)
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/url/%s", tc.alias), nil)
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if tc.wantErr != nil {
+				var response LinkResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.wantErr.Error(), response.Error)
+			} else {
+				assert.JSONEq(t, tc.expected, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestListLinksHandler(t *testing.T) {
+	checkedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		query    string
+		wantErr  error
+		expected string
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Success": {
+			expected: `{"status":"ok","links":[
+				{"alias":"alive","url":"https://example.com","merge_query":false,"append_path":false,"protected":false,"active":true,
+				 "health":{"status":200,"latency_ms":35,"checked_at":"2024-05-01T12:00:00Z"}},
+				{"alias":"uncheck","url":"https://example.org","merge_query":false,"append_path":false,"protected":false,"active":true}]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ListLinks(storage.LinkFilter{Limit: defaultListLimit}).Return([]storage.Link{
+					{Alias: "alive", URL: "https://example.com", OriginalURL: "https://example.com",
+						Health: storage.Health{Status: 200, Latency: 35 * time.Millisecond, CheckedAt: checkedAt}},
+					{Alias: "uncheck", URL: "https://example.org", OriginalURL: "https://example.org"},
+				}, nil)
+			},
+		},
+		"Broken only": {
+			query: "?broken=true&limit=10&offset=20",
+			expected: `{"status":"ok","links":[
+				{"alias":"gone","url":"https://example.com/gone","merge_query":false,"append_path":false,"protected":false,"active":true,
+				 "health":{"status":404,"latency_ms":12,"checked_at":"2024-05-01T12:00:00Z"},"broken":true}]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ListLinks(storage.LinkFilter{Offset: 20, Limit: 10, Broken: true}).Return([]storage.Link{
+					{Alias: "gone", URL: "https://example.com/gone", OriginalURL: "https://example.com/gone",
+						Health: storage.Health{Status: 404, Latency: 12 * time.Millisecond, CheckedAt: checkedAt}},
+				}, nil)
+			},
+		},
+		"Empty": {
+			expected: `{"status":"ok","links":[]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ListLinks(gomock.Any()).Return(nil, nil)
+			},
+		},
+		"Invalid limit": {
+			query:   "?limit=5000",
+			wantErr: errors.New("invalid request: limit must be between 1 and 1000"),
+		},
+		"Invalid broken": {
+			query:   "?broken=maybe",
+			wantErr: errors.New("invalid request: broken must be true or false"),
+		},
+		"Internal error": {
+			wantErr: errors.New("internal error"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ListLinks(gomock.Any()).Return(nil, errors.New("unexpected error"))
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(http.MethodGet, "/v1/url/"+tc.query, nil)
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if tc.wantErr != nil {
+				var response ListResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.wantErr.Error(), response.Error)
+			} else {
+				assert.JSONEq(t, tc.expected, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"shorty/internal/pkg/locale"
+	"sort"
+)
+
+// canonicalLocales validates the language tags of a locale map and brings them to their canonical form,
+// it returns a human-readable error text for a client if a tag is invalid or repeated
+func canonicalLocales(locales map[string]string) (map[string]string, string) {
+	if len(locales) == 0 {
+		return nil, ""
+	}
+
+	result := make(map[string]string, len(locales))
+	for tag, target := range locales {
+		canonical, err := locale.Canonical(tag)
+		if err != nil {
+			return nil, fmt.Sprintf("invalid request: unknown locale %q", tag)
+		}
+
+		if _, ok := result[canonical]; ok {
+			return nil, fmt.Sprintf("invalid request: locale %q is repeated", canonical)
+		}
+
+		result[canonical] = target
+	}
+
+	return result, ""
+}
+
+// matchLocale returns the target of the link locale negotiated with the Accept-Language header
+func matchLocale(locales map[string]string, r *http.Request) (string, bool) {
+	if len(locales) == 0 {
+		return "", false
+	}
+
+	//sorted, so that equally good matches are resolved the same way on every request
+	tags := make([]string, 0, len(locales))
+	for tag := range locales {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	tag, ok := locale.Match(r.Header.Get("Accept-Language"), tags)
+	if !ok {
+		return "", false
+	}
+
+	return locales[tag], true
+}
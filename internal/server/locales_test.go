@@ -0,0 +1,97 @@
+package server
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestCanonicalLocales(t *testing.T) {
+	tests := map[string]struct {
+		locales  map[string]string
+		expected map[string]string
+		wantErr  string
+	}{
+		"Canonical tags": {
+			locales:  map[string]string{"en-us": "https://example.com/en", "DE": "https://example.com/de"},
+			expected: map[string]string{"en-US": "https://example.com/en", "de": "https://example.com/de"},
+		},
+		"Empty": {
+			locales: map[string]string{},
+		},
+		"Invalid tag": {
+			locales: map[string]string{"english please": "https://example.com/en"},
+			wantErr: `invalid request: unknown locale "english please"`,
+		},
+		"Repeated tag": {
+			locales: map[string]string{"en-us": "https://example.com/a", "en-US": "https://example.com/b"},
+			wantErr: `invalid request: locale "en-US" is repeated`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			locales, msg := canonicalLocales(tc.locales)
+			assert.Equal(t, tc.wantErr, msg)
+			assert.Equal(t, tc.expected, locales)
+		})
+	}
+}
+
+func TestRedirectHandler_Locales(t *testing.T) {
+	link := storage.Link{
+		Alias: "guide",
+		URL:   "https://example.com/en/guide",
+		Locales: map[string]string{
+			"de":    "https://example.com/de/guide",
+			"pt-BR": "https://example.com/pt-br/guide",
+		},
+	}
+
+	tests := map[string]struct {
+		acceptLanguage string
+		url            string
+	}{
+		"German":           {acceptLanguage: "de-CH,de;q=0.9,en;q=0.8", url: "https://example.com/de/guide"},
+		"Brazilian":        {acceptLanguage: "pt-BR,pt;q=0.9", url: "https://example.com/pt-br/guide"},
+		"Preferred locale": {acceptLanguage: "fr,pt-BR;q=0.7,de;q=0.6", url: "https://example.com/pt-br/guide"},
+		"Default":          {acceptLanguage: "ja", url: "https://example.com/en/guide"},
+		"No header":        {url: "https://example.com/en/guide"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectDefaultDomain(mockStorage)
+			mockStorage.EXPECT().GetLink(storage.DefaultWorkspace, "", "guide").Return(link, nil)
+
+			r := &router{
+				storage: mockStorage,
+				log:     slog.Default(),
+			}
+
+			chiRouter := chi.NewRouter()
+			chiRouter.Get("/v1/{alias}", r.redirectHandler)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/guide", nil)
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+
+			w := httptest.NewRecorder()
+			chiRouter.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, tc.url, w.Header().Get("Location"))
+			assert.Equal(t, "Accept-Language", w.Header().Get("Vary"))
+		})
+	}
+}
@@ -11,6 +11,8 @@ package mocks
 
 import (
 	reflect "reflect"
+	storage "shorty/internal/storage"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,60 +41,730 @@ func (m *MockUrlProvider) EXPECT() *MockUrlProviderMockRecorder {
 	return m.recorder
 }
 
+// CompleteIdempotencyKey mocks base method.
+func (m *MockUrlProvider) CompleteIdempotencyKey(workspaceID int64, key string, response []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteIdempotencyKey", workspaceID, key, response)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteIdempotencyKey indicates an expected call of CompleteIdempotencyKey.
+func (mr *MockUrlProviderMockRecorder) CompleteIdempotencyKey(workspaceID, key, response any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteIdempotencyKey", reflect.TypeOf((*MockUrlProvider)(nil).CompleteIdempotencyKey), workspaceID, key, response)
+}
+
+// ConsumeClick mocks base method.
+func (m *MockUrlProvider) ConsumeClick(workspaceID int64, domain, alias string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeClick", workspaceID, domain, alias)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumeClick indicates an expected call of ConsumeClick.
+func (mr *MockUrlProviderMockRecorder) ConsumeClick(workspaceID, domain, alias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeClick", reflect.TypeOf((*MockUrlProvider)(nil).ConsumeClick), workspaceID, domain, alias)
+}
+
+// CountCountryClick mocks base method.
+func (m *MockUrlProvider) CountCountryClick(linkID int64, country string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountCountryClick", linkID, country)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CountCountryClick indicates an expected call of CountCountryClick.
+func (mr *MockUrlProviderMockRecorder) CountCountryClick(linkID, country any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountCountryClick", reflect.TypeOf((*MockUrlProvider)(nil).CountCountryClick), linkID, country)
+}
+
+// CountRequest mocks base method.
+func (m *MockUrlProvider) CountRequest(workspaceID int64, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountRequest", workspaceID, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountRequest indicates an expected call of CountRequest.
+func (mr *MockUrlProviderMockRecorder) CountRequest(workspaceID, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountRequest", reflect.TypeOf((*MockUrlProvider)(nil).CountRequest), workspaceID, now)
+}
+
+// CountVariantClick mocks base method.
+func (m *MockUrlProvider) CountVariantClick(variantID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountVariantClick", variantID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CountVariantClick indicates an expected call of CountVariantClick.
+func (mr *MockUrlProviderMockRecorder) CountVariantClick(variantID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountVariantClick", reflect.TypeOf((*MockUrlProvider)(nil).CountVariantClick), variantID)
+}
+
+// DeleteDomain mocks base method.
+func (m *MockUrlProvider) DeleteDomain(workspaceID int64, host string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDomain", workspaceID, host)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDomain indicates an expected call of DeleteDomain.
+func (mr *MockUrlProviderMockRecorder) DeleteDomain(workspaceID, host any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDomain", reflect.TypeOf((*MockUrlProvider)(nil).DeleteDomain), workspaceID, host)
+}
+
+// DeleteFolder mocks base method.
+func (m *MockUrlProvider) DeleteFolder(workspaceID, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFolder", workspaceID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFolder indicates an expected call of DeleteFolder.
+func (mr *MockUrlProviderMockRecorder) DeleteFolder(workspaceID, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFolder", reflect.TypeOf((*MockUrlProvider)(nil).DeleteFolder), workspaceID, id)
+}
+
+// DeleteTag mocks base method.
+func (m *MockUrlProvider) DeleteTag(workspaceID int64, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTag", workspaceID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTag indicates an expected call of DeleteTag.
+func (mr *MockUrlProviderMockRecorder) DeleteTag(workspaceID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTag", reflect.TypeOf((*MockUrlProvider)(nil).DeleteTag), workspaceID, name)
+}
+
+// DeleteTemplate mocks base method.
+func (m *MockUrlProvider) DeleteTemplate(workspaceID int64, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTemplate", workspaceID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTemplate indicates an expected call of DeleteTemplate.
+func (mr *MockUrlProviderMockRecorder) DeleteTemplate(workspaceID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTemplate", reflect.TypeOf((*MockUrlProvider)(nil).DeleteTemplate), workspaceID, name)
+}
+
 // DeleteURL mocks base method.
-func (m *MockUrlProvider) DeleteURL(alias string) error {
+func (m *MockUrlProvider) DeleteURL(workspaceID int64, domain, alias string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteURL", alias)
+	ret := m.ctrl.Call(m, "DeleteURL", workspaceID, domain, alias)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteURL indicates an expected call of DeleteURL.
-func (mr *MockUrlProviderMockRecorder) DeleteURL(alias any) *gomock.Call {
+func (mr *MockUrlProviderMockRecorder) DeleteURL(workspaceID, domain, alias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURL", reflect.TypeOf((*MockUrlProvider)(nil).DeleteURL), workspaceID, domain, alias)
+}
+
+// DeleteUser mocks base method.
+func (m *MockUrlProvider) DeleteUser(workspaceID int64, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", workspaceID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockUrlProviderMockRecorder) DeleteUser(workspaceID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUrlProvider)(nil).DeleteUser), workspaceID, name)
+}
+
+// EachLink mocks base method.
+func (m *MockUrlProvider) EachLink(workspaceID int64, fn func(storage.Link) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EachLink", workspaceID, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EachLink indicates an expected call of EachLink.
+func (mr *MockUrlProviderMockRecorder) EachLink(workspaceID, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EachLink", reflect.TypeOf((*MockUrlProvider)(nil).EachLink), workspaceID, fn)
+}
+
+// FindLinkByURL mocks base method.
+func (m *MockUrlProvider) FindLinkByURL(workspaceID int64, domain, owner, target string) (storage.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLinkByURL", workspaceID, domain, owner, target)
+	ret0, _ := ret[0].(storage.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLinkByURL indicates an expected call of FindLinkByURL.
+func (mr *MockUrlProviderMockRecorder) FindLinkByURL(workspaceID, domain, owner, target any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLinkByURL", reflect.TypeOf((*MockUrlProvider)(nil).FindLinkByURL), workspaceID, domain, owner, target)
+}
+
+// GetDomain mocks base method.
+func (m *MockUrlProvider) GetDomain(workspaceID int64, host string) (storage.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDomain", workspaceID, host)
+	ret0, _ := ret[0].(storage.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDomain indicates an expected call of GetDomain.
+func (mr *MockUrlProviderMockRecorder) GetDomain(workspaceID, host any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDomain", reflect.TypeOf((*MockUrlProvider)(nil).GetDomain), workspaceID, host)
+}
+
+// GetFolder mocks base method.
+func (m *MockUrlProvider) GetFolder(workspaceID, id int64) (storage.Folder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFolder", workspaceID, id)
+	ret0, _ := ret[0].(storage.Folder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFolder indicates an expected call of GetFolder.
+func (mr *MockUrlProviderMockRecorder) GetFolder(workspaceID, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFolder", reflect.TypeOf((*MockUrlProvider)(nil).GetFolder), workspaceID, id)
+}
+
+// GetLink mocks base method.
+func (m *MockUrlProvider) GetLink(workspaceID int64, domain, alias string) (storage.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLink", workspaceID, domain, alias)
+	ret0, _ := ret[0].(storage.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLink indicates an expected call of GetLink.
+func (mr *MockUrlProviderMockRecorder) GetLink(workspaceID, domain, alias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLink", reflect.TypeOf((*MockUrlProvider)(nil).GetLink), workspaceID, domain, alias)
+}
+
+// GetQuotaUsage mocks base method.
+func (m *MockUrlProvider) GetQuotaUsage(owner string, now time.Time) (storage.QuotaUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuotaUsage", owner, now)
+	ret0, _ := ret[0].(storage.QuotaUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuotaUsage indicates an expected call of GetQuotaUsage.
+func (mr *MockUrlProviderMockRecorder) GetQuotaUsage(owner, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuotaUsage", reflect.TypeOf((*MockUrlProvider)(nil).GetQuotaUsage), owner, now)
+}
+
+// GetTemplate mocks base method.
+func (m *MockUrlProvider) GetTemplate(workspaceID int64, name string) (storage.Template, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTemplate", workspaceID, name)
+	ret0, _ := ret[0].(storage.Template)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTemplate indicates an expected call of GetTemplate.
+func (mr *MockUrlProviderMockRecorder) GetTemplate(workspaceID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTemplate", reflect.TypeOf((*MockUrlProvider)(nil).GetTemplate), workspaceID, name)
+}
+
+// GetUser mocks base method.
+func (m *MockUrlProvider) GetUser(name string) (storage.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", name)
+	ret0, _ := ret[0].(storage.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockUrlProviderMockRecorder) GetUser(name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUrlProvider)(nil).GetUser), name)
+}
+
+// GetWorkspace mocks base method.
+func (m *MockUrlProvider) GetWorkspace(id int64) (storage.Workspace, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWorkspace", id)
+	ret0, _ := ret[0].(storage.Workspace)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWorkspace indicates an expected call of GetWorkspace.
+func (mr *MockUrlProviderMockRecorder) GetWorkspace(id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURL", reflect.TypeOf((*MockUrlProvider)(nil).DeleteURL), alias)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkspace", reflect.TypeOf((*MockUrlProvider)(nil).GetWorkspace), id)
 }
 
-// GetURL mocks base method.
-func (m *MockUrlProvider) GetURL(alias string) (string, error) {
+// ImportLinks mocks base method.
+func (m *MockUrlProvider) ImportLinks(links []storage.Link, policy storage.ConflictPolicy) (storage.ImportResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetURL", alias)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "ImportLinks", links, policy)
+	ret0, _ := ret[0].(storage.ImportResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetURL indicates an expected call of GetURL.
-func (mr *MockUrlProviderMockRecorder) GetURL(alias any) *gomock.Call {
+// ImportLinks indicates an expected call of ImportLinks.
+func (mr *MockUrlProviderMockRecorder) ImportLinks(links, policy any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockUrlProvider)(nil).GetURL), alias)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportLinks", reflect.TypeOf((*MockUrlProvider)(nil).ImportLinks), links, policy)
 }
 
-// SaveURL mocks base method.
-func (m *MockUrlProvider) SaveURL(urlToSave, alias string) (int64, error) {
+// ListDomains mocks base method.
+func (m *MockUrlProvider) ListDomains(workspaceID int64) ([]storage.Domain, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveURL", urlToSave, alias)
+	ret := m.ctrl.Call(m, "ListDomains", workspaceID)
+	ret0, _ := ret[0].([]storage.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDomains indicates an expected call of ListDomains.
+func (mr *MockUrlProviderMockRecorder) ListDomains(workspaceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDomains", reflect.TypeOf((*MockUrlProvider)(nil).ListDomains), workspaceID)
+}
+
+// ListFolders mocks base method.
+func (m *MockUrlProvider) ListFolders(workspaceID int64) ([]storage.Folder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFolders", workspaceID)
+	ret0, _ := ret[0].([]storage.Folder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFolders indicates an expected call of ListFolders.
+func (mr *MockUrlProviderMockRecorder) ListFolders(workspaceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFolders", reflect.TypeOf((*MockUrlProvider)(nil).ListFolders), workspaceID)
+}
+
+// ListLinks mocks base method.
+func (m *MockUrlProvider) ListLinks(filter storage.LinkFilter) ([]storage.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLinks", filter)
+	ret0, _ := ret[0].([]storage.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLinks indicates an expected call of ListLinks.
+func (mr *MockUrlProviderMockRecorder) ListLinks(filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLinks", reflect.TypeOf((*MockUrlProvider)(nil).ListLinks), filter)
+}
+
+// ListTags mocks base method.
+func (m *MockUrlProvider) ListTags(workspaceID int64) ([]storage.Tag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTags", workspaceID)
+	ret0, _ := ret[0].([]storage.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTags indicates an expected call of ListTags.
+func (mr *MockUrlProviderMockRecorder) ListTags(workspaceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTags", reflect.TypeOf((*MockUrlProvider)(nil).ListTags), workspaceID)
+}
+
+// ListTemplates mocks base method.
+func (m *MockUrlProvider) ListTemplates(workspaceID int64) ([]storage.Template, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTemplates", workspaceID)
+	ret0, _ := ret[0].([]storage.Template)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTemplates indicates an expected call of ListTemplates.
+func (mr *MockUrlProviderMockRecorder) ListTemplates(workspaceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTemplates", reflect.TypeOf((*MockUrlProvider)(nil).ListTemplates), workspaceID)
+}
+
+// ListUsers mocks base method.
+func (m *MockUrlProvider) ListUsers(workspaceID int64) ([]storage.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", workspaceID)
+	ret0, _ := ret[0].([]storage.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockUrlProviderMockRecorder) ListUsers(workspaceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockUrlProvider)(nil).ListUsers), workspaceID)
+}
+
+// ListWorkspaces mocks base method.
+func (m *MockUrlProvider) ListWorkspaces() ([]storage.Workspace, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWorkspaces")
+	ret0, _ := ret[0].([]storage.Workspace)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWorkspaces indicates an expected call of ListWorkspaces.
+func (mr *MockUrlProviderMockRecorder) ListWorkspaces() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWorkspaces", reflect.TypeOf((*MockUrlProvider)(nil).ListWorkspaces))
+}
+
+// ReleaseIdempotencyKey mocks base method.
+func (m *MockUrlProvider) ReleaseIdempotencyKey(workspaceID int64, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseIdempotencyKey", workspaceID, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseIdempotencyKey indicates an expected call of ReleaseIdempotencyKey.
+func (mr *MockUrlProviderMockRecorder) ReleaseIdempotencyKey(workspaceID, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseIdempotencyKey", reflect.TypeOf((*MockUrlProvider)(nil).ReleaseIdempotencyKey), workspaceID, key)
+}
+
+// ReleaseLinks mocks base method.
+func (m *MockUrlProvider) ReleaseLinks(owner string, count int64, now time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseLinks", owner, count, now)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseLinks indicates an expected call of ReleaseLinks.
+func (mr *MockUrlProviderMockRecorder) ReleaseLinks(owner, count, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseLinks", reflect.TypeOf((*MockUrlProvider)(nil).ReleaseLinks), owner, count, now)
+}
+
+// RenameTag mocks base method.
+func (m *MockUrlProvider) RenameTag(workspaceID int64, name, newName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameTag", workspaceID, name, newName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenameTag indicates an expected call of RenameTag.
+func (mr *MockUrlProviderMockRecorder) RenameTag(workspaceID, name, newName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameTag", reflect.TypeOf((*MockUrlProvider)(nil).RenameTag), workspaceID, name, newName)
+}
+
+// ReserveIdempotencyKey mocks base method.
+func (m *MockUrlProvider) ReserveIdempotencyKey(workspaceID int64, key, fingerprint string, expireBefore time.Time) (storage.IdempotencyRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveIdempotencyKey", workspaceID, key, fingerprint, expireBefore)
+	ret0, _ := ret[0].(storage.IdempotencyRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveIdempotencyKey indicates an expected call of ReserveIdempotencyKey.
+func (mr *MockUrlProviderMockRecorder) ReserveIdempotencyKey(workspaceID, key, fingerprint, expireBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveIdempotencyKey", reflect.TypeOf((*MockUrlProvider)(nil).ReserveIdempotencyKey), workspaceID, key, fingerprint, expireBefore)
+}
+
+// ReserveLinks mocks base method.
+func (m *MockUrlProvider) ReserveLinks(owner string, count int64, quota storage.LinkQuota, now time.Time) (storage.QuotaUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveLinks", owner, count, quota, now)
+	ret0, _ := ret[0].(storage.QuotaUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveLinks indicates an expected call of ReserveLinks.
+func (mr *MockUrlProviderMockRecorder) ReserveLinks(owner, count, quota, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveLinks", reflect.TypeOf((*MockUrlProvider)(nil).ReserveLinks), owner, count, quota, now)
+}
+
+// ResolveDomain mocks base method.
+func (m *MockUrlProvider) ResolveDomain(host string) (storage.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveDomain", host)
+	ret0, _ := ret[0].(storage.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveDomain indicates an expected call of ResolveDomain.
+func (mr *MockUrlProviderMockRecorder) ResolveDomain(host any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveDomain", reflect.TypeOf((*MockUrlProvider)(nil).ResolveDomain), host)
+}
+
+// SaveDomain mocks base method.
+func (m *MockUrlProvider) SaveDomain(domain storage.Domain) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDomain", domain)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// SaveURL indicates an expected call of SaveURL.
-func (mr *MockUrlProviderMockRecorder) SaveURL(urlToSave, alias any) *gomock.Call {
+// SaveDomain indicates an expected call of SaveDomain.
+func (mr *MockUrlProviderMockRecorder) SaveDomain(domain any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockUrlProvider)(nil).SaveURL), urlToSave, alias)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDomain", reflect.TypeOf((*MockUrlProvider)(nil).SaveDomain), domain)
+}
+
+// SaveFolder mocks base method.
+func (m *MockUrlProvider) SaveFolder(folder storage.Folder) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveFolder", folder)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveFolder indicates an expected call of SaveFolder.
+func (mr *MockUrlProviderMockRecorder) SaveFolder(folder any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveFolder", reflect.TypeOf((*MockUrlProvider)(nil).SaveFolder), folder)
+}
+
+// SaveLink mocks base method.
+func (m *MockUrlProvider) SaveLink(link storage.Link) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLink", link)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveLink indicates an expected call of SaveLink.
+func (mr *MockUrlProviderMockRecorder) SaveLink(link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLink", reflect.TypeOf((*MockUrlProvider)(nil).SaveLink), link)
+}
+
+// SaveLinks mocks base method.
+func (m *MockUrlProvider) SaveLinks(links []storage.Link, atomic bool) ([]storage.SaveResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveLinks", links, atomic)
+	ret0, _ := ret[0].([]storage.SaveResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveLinks indicates an expected call of SaveLinks.
+func (mr *MockUrlProviderMockRecorder) SaveLinks(links, atomic any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveLinks", reflect.TypeOf((*MockUrlProvider)(nil).SaveLinks), links, atomic)
+}
+
+// SaveTag mocks base method.
+func (m *MockUrlProvider) SaveTag(tag storage.Tag) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTag", tag)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveTag indicates an expected call of SaveTag.
+func (mr *MockUrlProviderMockRecorder) SaveTag(tag any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTag", reflect.TypeOf((*MockUrlProvider)(nil).SaveTag), tag)
+}
+
+// SaveTemplate mocks base method.
+func (m *MockUrlProvider) SaveTemplate(template storage.Template) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTemplate", template)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveTemplate indicates an expected call of SaveTemplate.
+func (mr *MockUrlProviderMockRecorder) SaveTemplate(template any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTemplate", reflect.TypeOf((*MockUrlProvider)(nil).SaveTemplate), template)
+}
+
+// SaveUser mocks base method.
+func (m *MockUrlProvider) SaveUser(user storage.User) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveUser", user)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveUser indicates an expected call of SaveUser.
+func (mr *MockUrlProviderMockRecorder) SaveUser(user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUser", reflect.TypeOf((*MockUrlProvider)(nil).SaveUser), user)
+}
+
+// SaveWorkspace mocks base method.
+func (m *MockUrlProvider) SaveWorkspace(workspace storage.Workspace) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveWorkspace", workspace)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveWorkspace indicates an expected call of SaveWorkspace.
+func (mr *MockUrlProviderMockRecorder) SaveWorkspace(workspace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWorkspace", reflect.TypeOf((*MockUrlProvider)(nil).SaveWorkspace), workspace)
+}
+
+// SetFolder mocks base method.
+func (m *MockUrlProvider) SetFolder(workspaceID int64, domain, alias string, folderID int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFolder", workspaceID, domain, alias, folderID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFolder indicates an expected call of SetFolder.
+func (mr *MockUrlProviderMockRecorder) SetFolder(workspaceID, domain, alias, folderID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFolder", reflect.TypeOf((*MockUrlProvider)(nil).SetFolder), workspaceID, domain, alias, folderID)
+}
+
+// SetRules mocks base method.
+func (m *MockUrlProvider) SetRules(workspaceID int64, domain, alias string, rules []storage.Rule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRules", workspaceID, domain, alias, rules)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRules indicates an expected call of SetRules.
+func (mr *MockUrlProviderMockRecorder) SetRules(workspaceID, domain, alias, rules any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRules", reflect.TypeOf((*MockUrlProvider)(nil).SetRules), workspaceID, domain, alias, rules)
+}
+
+// SetTags mocks base method.
+func (m *MockUrlProvider) SetTags(workspaceID int64, domain, alias string, tags []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTags", workspaceID, domain, alias, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTags indicates an expected call of SetTags.
+func (mr *MockUrlProviderMockRecorder) SetTags(workspaceID, domain, alias, tags any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTags", reflect.TypeOf((*MockUrlProvider)(nil).SetTags), workspaceID, domain, alias, tags)
 }
 
 // UpdateAlias mocks base method.
-func (m *MockUrlProvider) UpdateAlias(oldAlias, newAlias string) error {
+func (m *MockUrlProvider) UpdateAlias(workspaceID int64, domain, oldAlias, newAlias string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateAlias", oldAlias, newAlias)
+	ret := m.ctrl.Call(m, "UpdateAlias", workspaceID, domain, oldAlias, newAlias)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateAlias indicates an expected call of UpdateAlias.
-func (mr *MockUrlProviderMockRecorder) UpdateAlias(oldAlias, newAlias any) *gomock.Call {
+func (mr *MockUrlProviderMockRecorder) UpdateAlias(workspaceID, domain, oldAlias, newAlias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAlias", reflect.TypeOf((*MockUrlProvider)(nil).UpdateAlias), workspaceID, domain, oldAlias, newAlias)
+}
+
+// UpdateDomain mocks base method.
+func (m *MockUrlProvider) UpdateDomain(domain storage.Domain) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDomain", domain)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDomain indicates an expected call of UpdateDomain.
+func (mr *MockUrlProviderMockRecorder) UpdateDomain(domain any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDomain", reflect.TypeOf((*MockUrlProvider)(nil).UpdateDomain), domain)
+}
+
+// UpdateFolder mocks base method.
+func (m *MockUrlProvider) UpdateFolder(folder storage.Folder) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFolder", folder)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateFolder indicates an expected call of UpdateFolder.
+func (mr *MockUrlProviderMockRecorder) UpdateFolder(folder any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFolder", reflect.TypeOf((*MockUrlProvider)(nil).UpdateFolder), folder)
+}
+
+// UpdateTemplate mocks base method.
+func (m *MockUrlProvider) UpdateTemplate(template storage.Template) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTemplate", template)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateTemplate indicates an expected call of UpdateTemplate.
+func (mr *MockUrlProviderMockRecorder) UpdateTemplate(template any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTemplate", reflect.TypeOf((*MockUrlProvider)(nil).UpdateTemplate), template)
+}
+
+// UpdateWorkspace mocks base method.
+func (m *MockUrlProvider) UpdateWorkspace(workspace storage.Workspace) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWorkspace", workspace)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWorkspace indicates an expected call of UpdateWorkspace.
+func (mr *MockUrlProviderMockRecorder) UpdateWorkspace(workspace any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAlias", reflect.TypeOf((*MockUrlProvider)(nil).UpdateAlias), oldAlias, newAlias)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWorkspace", reflect.TypeOf((*MockUrlProvider)(nil).UpdateWorkspace), workspace)
 }
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/useragent"
+	"shorty/internal/server/pages"
+	"shorty/internal/storage"
+)
+
+// renderOpenGraph writes the page with the open graph metadata of the link if the request comes from
+// a link preview crawler, it returns false if the visitor should be redirected
+func (ro *router) renderOpenGraph(w http.ResponseWriter, r *http.Request, link storage.Link, target *url.URL) bool {
+	if link.OpenGraph.IsZero() {
+		return false
+	}
+
+	w.Header().Add("Vary", "User-Agent")
+	if !useragent.IsCrawler(r.UserAgent()) {
+		return false
+	}
+
+	page := pages.OpenGraph{
+		Title:       link.OpenGraph.Title,
+		Description: link.OpenGraph.Description,
+		Image:       link.OpenGraph.Image,
+		URL:         ro.shortURL(r, link.Domain, link.Alias),
+		Destination: target.String(),
+	}
+
+	if page.Title == "" {
+		page.Title = link.Title
+	}
+
+	ro.log.Info("open graph page served", slog.String("alias", link.Alias), slog.String("user_agent", r.UserAgent()))
+	err := pages.Render(w, http.StatusOK, "opengraph.html", page)
+	if err != nil {
+		ro.log.Error("failed to render open graph page", slo.Err(err))
+	}
+
+	return true
+}
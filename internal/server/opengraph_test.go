@@ -0,0 +1,126 @@
+package server
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/pkg/lockout"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+	"time"
+)
+
+func TestRedirectHandler_OpenGraph(t *testing.T) {
+	const (
+		crawler = "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)"
+		browser = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	)
+
+	withOpenGraph := storage.Link{
+		Alias:      "launc",
+		URL:        "https://example.com/launch",
+		MaxClicks:  5,
+		ClicksLeft: 5,
+		OpenGraph: storage.OpenGraph{
+			Title:       `Launch "day"`,
+			Description: "Everything <new>",
+			Image:       "https://cdn.example.com/launch.png",
+		},
+	}
+
+	tests := map[string]struct {
+		link      storage.Link
+		userAgent string
+		wantPage  []string
+		wantURL   string
+		prepare   func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Crawler gets the metadata": {
+			link:      withOpenGraph,
+			userAgent: crawler,
+			wantPage: []string{
+				`<meta property="og:title" content="Launch &#34;day&#34;">`,
+				`<meta property="og:description" content="Everything &lt;new&gt;">`,
+				`<meta property="og:image" content="https://cdn.example.com/launch.png">`,
+				`<meta name="twitter:card" content="summary_large_image">`,
+				`<meta property="og:url" content="https://sho.rt/v1/launc">`,
+				`<meta http-equiv="refresh" content="0; url=https://example.com/launch">`,
+			},
+		},
+		"Title falls back to the link title": {
+			link: storage.Link{
+				Alias:     "launc",
+				URL:       "https://example.com/launch",
+				Title:     "Launch",
+				OpenGraph: storage.OpenGraph{Description: "Everything new"},
+			},
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			wantPage: []string{
+				`<meta property="og:title" content="Launch">`,
+				`<meta name="twitter:card" content="summary">`,
+			},
+		},
+		"Browser is redirected": {
+			link:      withOpenGraph,
+			userAgent: browser,
+			wantURL:   "https://example.com/launch",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ConsumeClick(storage.DefaultWorkspace, "", "launc").Return(nil)
+			},
+		},
+		"Crawler is redirected without metadata": {
+			link:      storage.Link{Alias: "launc", URL: "https://example.com/launch"},
+			userAgent: crawler,
+			wantURL:   "https://example.com/launch",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectDefaultDomain(mockStorage)
+			mockStorage.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(tc.link, nil)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			r := &router{
+				storage: mockStorage,
+				log:     slog.Default(),
+				lockout: lockout.New(3, time.Minute, time.Minute),
+				baseURL: "https://sho.rt/v1",
+			}
+
+			chiRouter := chi.NewRouter()
+			chiRouter.Get("/v1/{alias}", r.redirectHandler)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/launc", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+
+			w := httptest.NewRecorder()
+			chiRouter.ServeHTTP(w, req)
+
+			if tc.wantPage == nil {
+				require.Equal(t, http.StatusFound, w.Code)
+				assert.Equal(t, tc.wantURL, w.Header().Get("Location"))
+
+				return
+			}
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+			assert.Contains(t, w.Header().Values("Vary"), "User-Agent")
+			for _, fragment := range tc.wantPage {
+				assert.Contains(t, w.Body.String(), fragment)
+			}
+		})
+	}
+}
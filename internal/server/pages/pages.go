@@ -0,0 +1,62 @@
+package pages
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+)
+
+//go:embed templates/*.html
+var files embed.FS
+
+var templates = template.Must(template.ParseFS(files, "templates/*.html"))
+
+// Password is the data of the password form of a protected link
+type Password struct {
+	Alias string
+	Error string
+}
+
+// Preview is the data of the page that shows the destination of a link before the redirect
+type Preview struct {
+	Alias       string
+	Title       string
+	Host        string
+	Destination string
+	// ContinueURL is the short url that redirects without the preview
+	ContinueURL string
+}
+
+// OpenGraph is the data of the page served to link preview crawlers
+type OpenGraph struct {
+	Title       string
+	Description string
+	Image       string
+	// URL is the short url of the link
+	URL         string
+	Destination string
+}
+
+// Error is the data of the page shown to browsers when a link cannot be followed
+type Error struct {
+	Status  int
+	Title   string
+	Message string
+}
+
+// Render executes the named template and writes it with the given status code
+func Render(w http.ResponseWriter, status int, name string, data any) error {
+	var buf bytes.Buffer
+
+	err := templates.ExecuteTemplate(&buf, name, data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = buf.WriteTo(w)
+
+	return err
+}
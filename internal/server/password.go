@@ -0,0 +1,97 @@
+package server
+
+import (
+	"github.com/go-chi/render"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"math"
+	"net/http"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/server/pages"
+	"shorty/internal/storage"
+	"strconv"
+	"time"
+)
+
+const (
+	// passwordHeader carries the password of a protected link for API clients
+	passwordHeader = "X-Link-Password"
+	// passwordParam carries the password of a protected link in the password form,
+	// it is not read from the query, request logs contain the full url
+	passwordParam = "password"
+)
+
+// checkPassword verifies the password of a protected link,
+// it writes the password form or an error and returns false if the visitor may not be redirected
+func (ro *router) checkPassword(w http.ResponseWriter, r *http.Request, link storage.Link) bool {
+	if link.PasswordHash == "" {
+		return true
+	}
+
+	password, fromForm := linkPassword(r)
+	//browsers get the form, clients that sent the password in a header get json
+	interactive := fromForm || password == ""
+
+	//aliases are unique only per workspace and domain, so attempts are counted per link
+	key := strconv.FormatInt(link.ID, 10)
+	if password == "" {
+		if retryAfter, locked := ro.lockout.Locked(key); locked {
+			ro.lockedError(w, r, link, interactive, retryAfter)
+
+			return false
+		}
+
+		ro.passwordError(w, r, link, interactive, http.StatusUnauthorized, "")
+
+		return false
+	}
+
+	//the attempt is counted before the hash is compared, concurrent guesses cannot exceed the limit
+	if retryAfter, ok := ro.lockout.Attempt(key); !ok {
+		ro.lockedError(w, r, link, interactive, retryAfter)
+
+		return false
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password))
+	if err != nil {
+		ro.log.Info("invalid password", slog.String("alias", link.Alias))
+		ro.passwordError(w, r, link, interactive, http.StatusUnauthorized, "invalid password")
+
+		return false
+	}
+
+	ro.lockout.Reset(key)
+
+	return true
+}
+
+func (ro *router) lockedError(w http.ResponseWriter, r *http.Request, link storage.Link, interactive bool, retryAfter time.Duration) {
+	ro.log.Info("password attempts are locked", slog.String("alias", link.Alias))
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	ro.passwordError(w, r, link, interactive, http.StatusTooManyRequests, "too many attempts, try again later")
+}
+
+func (ro *router) passwordError(w http.ResponseWriter, r *http.Request, link storage.Link, interactive bool, status int, msg string) {
+	if !interactive {
+		render.Status(r, status)
+		render.JSON(w, r, resp.Error(msg))
+
+		return
+	}
+
+	err := pages.Render(w, status, "password.html", pages.Password{Alias: link.Alias, Error: msg})
+	if err != nil {
+		ro.log.Error("failed to render password form", slo.Err(err))
+	}
+}
+
+// linkPassword returns the password sent by the visitor and whether it came from the password form
+func linkPassword(r *http.Request) (string, bool) {
+	if r.Method == http.MethodPost {
+		return r.PostFormValue(passwordParam), true
+	}
+
+	return r.Header.Get(passwordHeader), false
+}
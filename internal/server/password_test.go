@@ -0,0 +1,197 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"shorty/internal/pkg/lockout"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedirectHandler_Password(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	link := storage.Link{
+		ID:           1,
+		Alias:        "docs1",
+		URL:          "https://example.com/internal",
+		MergeQuery:   true,
+		PasswordHash: string(hash),
+	}
+
+	tests := map[string]struct {
+		request  func() *http.Request
+		attempts int
+		wantCode int
+		wantURL  string
+		wantErr  string
+		wantForm bool
+	}{
+		"Form is served without password": {
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/v1/docs1", nil)
+			},
+			wantCode: http.StatusUnauthorized,
+			wantForm: true,
+		},
+		"Correct header": {
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/v1/docs1", nil)
+				req.Header.Set(passwordHeader, "secret")
+				return req
+			},
+			wantCode: http.StatusFound,
+			wantURL:  "https://example.com/internal",
+		},
+		"Query is not read": {
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/v1/docs1?password=secret&page=2", nil)
+			},
+			wantCode: http.StatusUnauthorized,
+			wantForm: true,
+		},
+		"Query is not forwarded": {
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/v1/docs1?password=secret&page=2", nil)
+				req.Header.Set(passwordHeader, "secret")
+				return req
+			},
+			wantCode: http.StatusFound,
+			wantURL:  "https://example.com/internal?page=2",
+		},
+		"Wrong header": {
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/v1/docs1", nil)
+				req.Header.Set(passwordHeader, "guess")
+				return req
+			},
+			wantCode: http.StatusUnauthorized,
+			wantErr:  "invalid password",
+		},
+		"Correct form": {
+			request: func() *http.Request {
+				form := url.Values{passwordParam: {"secret"}}
+				req := httptest.NewRequest(http.MethodPost, "/v1/docs1", strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			wantCode: http.StatusFound,
+			wantURL:  "https://example.com/internal",
+		},
+		"Wrong form": {
+			request: func() *http.Request {
+				form := url.Values{passwordParam: {"guess"}}
+				req := httptest.NewRequest(http.MethodPost, "/v1/docs1", strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			wantCode: http.StatusUnauthorized,
+			wantForm: true,
+		},
+		"Locked after too many attempts": {
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/v1/docs1", nil)
+				req.Header.Set(passwordHeader, "secret")
+				return req
+			},
+			attempts: 3,
+			wantCode: http.StatusTooManyRequests,
+			wantErr:  "too many attempts, try again later",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectDefaultDomain(mockStorage)
+			mockStorage.EXPECT().GetLink(storage.DefaultWorkspace, "", "docs1").Return(link, nil).AnyTimes()
+
+			r := &router{
+				storage: mockStorage,
+				log:     slog.Default(),
+				lockout: lockout.New(3, time.Minute, time.Minute),
+			}
+
+			chiRouter := chi.NewRouter()
+			chiRouter.Get("/v1/{alias}", r.redirectHandler)
+			chiRouter.Post("/v1/{alias}", r.redirectHandler)
+
+			for i := 0; i < tc.attempts; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/v1/docs1", nil)
+				req.Header.Set(passwordHeader, "guess")
+				chiRouter.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			w := httptest.NewRecorder()
+			chiRouter.ServeHTTP(w, tc.request())
+			require.Equal(t, tc.wantCode, w.Code)
+
+			switch {
+			case tc.wantForm:
+				assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+				assert.Contains(t, w.Body.String(), `<form method="post">`)
+			case tc.wantErr != "":
+				var response Response
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.wantErr, response.Error)
+			default:
+				assert.Equal(t, tc.wantURL, w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestCheckPassword_LockoutPerLink(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	r := &router{
+		log:     slog.Default(),
+		lockout: lockout.New(3, time.Minute, time.Minute),
+	}
+
+	locked := storage.Link{ID: 1, Alias: "docs1", URL: "https://example.com", PasswordHash: string(hash)}
+	other := storage.Link{ID: 2, Domain: "go.example.org", Alias: "docs1", URL: "https://example.org", PasswordHash: string(hash)}
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/docs1", nil)
+		req.Header.Set(passwordHeader, "guess")
+		require.False(t, r.checkPassword(httptest.NewRecorder(), req, locked))
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/docs1", nil)
+	req.Header.Set(passwordHeader, "secret")
+	require.False(t, r.checkPassword(w, req, locked))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/v1/docs1", nil)
+	req.Header.Set(passwordHeader, "secret")
+	assert.True(t, r.checkPassword(w, req, other))
+}
+
+func TestRequest_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, nil))
+
+	log.Info("request", slog.Any("request", Request{URL: "https://example.com", Password: "secret"}))
+	assert.NotContains(t, buf.String(), "secret")
+	assert.Contains(t, buf.String(), "https://example.com")
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"log/slog"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/urlpolicy"
+	"shorty/internal/storage"
+)
+
+// WithDestinationPolicy restricts link targets, only http and https targets are allowed without it
+func WithDestinationPolicy(policy *urlpolicy.Policy) Option {
+	return func(ro *router) {
+		ro.policy = policy
+	}
+}
+
+// checkDestinations returns a human-readable error text for a client if a target of the link is not allowed,
+// or an empty string if all of them are
+func (ro *router) checkDestinations(link storage.Link) string {
+	return ro.checkTargets(linkTargets(link)...)
+}
+
+// linkTargets returns every url the link may redirect to
+func linkTargets(link storage.Link) []string {
+	targets := []string{link.URL}
+	if link.FallbackURL != "" {
+		targets = append(targets, link.FallbackURL)
+	}
+
+	for _, rule := range link.Rules {
+		targets = append(targets, rule.Target)
+	}
+
+	for _, variant := range link.Variants {
+		targets = append(targets, variant.Target)
+	}
+
+	for _, target := range link.Locales {
+		targets = append(targets, target)
+	}
+
+	for _, target := range link.Countries {
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// checkTargets returns a human-readable error text for a client for the first target that is not allowed
+func (ro *router) checkTargets(targets ...string) string {
+	if ro.policy == nil {
+		return ""
+	}
+
+	for _, target := range targets {
+		err := ro.policy.Check(target)
+		if err != nil {
+			ro.log.Info("destination is not allowed", slog.String("target", target), slo.Err(err))
+
+			return "invalid request: destination is not allowed: " + err.Error()
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	"shorty/internal/pkg/urlpolicy"
+	"shorty/internal/server/mocks"
+	"testing"
+)
+
+func TestDestinationPolicy(t *testing.T) {
+	policy := urlpolicy.New(urlpolicy.Options{DeniedDomains: []string{"*.evil.com"}})
+
+	tests := map[string]struct {
+		method  string
+		path    string
+		input   string
+		wantErr string
+	}{
+		"Save: javascript scheme": {
+			method:  http.MethodPost,
+			path:    "/v1/url/",
+			input:   `{"url": "javascript:alert(1)", "alias": "js"}`,
+			wantErr: `invalid request: destination is not allowed: scheme is not allowed: "javascript"`,
+		},
+		"Save: denied domain": {
+			method:  http.MethodPost,
+			path:    "/v1/url/",
+			input:   `{"url": "https://www.evil.com/login", "alias": "evil"}`,
+			wantErr: `invalid request: destination is not allowed: domain is denied: "www.evil.com"`,
+		},
+		"Save: denied fallback": {
+			method:  http.MethodPost,
+			path:    "/v1/url/",
+			input:   `{"url": "https://example.com", "alias": "evil", "fallback_url": "https://a.evil.com"}`,
+			wantErr: `invalid request: destination is not allowed: domain is denied: "a.evil.com"`,
+		},
+		"Set rules: denied target": {
+			method:  http.MethodPut,
+			path:    "/v1/url/myapp/rules",
+			input:   `{"rules": [{"os": "ios", "target": "https://apps.evil.com"}]}`,
+			wantErr: `invalid request: destination is not allowed: domain is denied: "apps.evil.com"`,
+		},
+		"Save domain: denied default url": {
+			method:  http.MethodPost,
+			path:    "/v1/domains",
+			input:   `{"host": "go.brand.com", "default_url": "https://www.evil.com"}`,
+			wantErr: `invalid request: destination is not allowed: domain is denied: "www.evil.com"`,
+		},
+		"Update domain: denied default url": {
+			method:  http.MethodPut,
+			path:    "/v1/domains/go.brand.com",
+			input:   `{"default_url": "https://www.evil.com"}`,
+			wantErr: `invalid request: destination is not allowed: domain is denied: "www.evil.com"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default(), WithDestinationPolicy(policy))
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.input)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response struct {
+				Error string `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.wantErr, response.Error)
+		})
+	}
+}
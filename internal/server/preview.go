@@ -0,0 +1,72 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/server/pages"
+	"shorty/internal/storage"
+	"strings"
+)
+
+const (
+	// previewSuffix after the alias shows the preview page instead of redirecting
+	previewSuffix = "+"
+	// continueParam skips the preview page of a link that is always previewed
+	continueParam = "continue"
+)
+
+// previewEnabled reports whether visitors of the link see the preview page before the redirect,
+// the preview can be enabled for all links, for the links of its workspace or for the link itself
+func (ro *router) previewEnabled(link storage.Link) bool {
+	if ro.previewAll || link.Preview {
+		return true
+	}
+
+	if link.WorkspaceID == storage.DefaultWorkspace {
+		return false
+	}
+
+	workspace, err := ro.storage.GetWorkspace(link.WorkspaceID)
+	if err != nil {
+		ro.log.Error("failed to get workspace", slog.Int64("workspace_id", link.WorkspaceID), slo.Err(err))
+
+		return false
+	}
+
+	return workspace.PreviewAll
+}
+
+// continued reports whether the visitor has confirmed the redirect on the preview page
+func continued(r *http.Request) bool {
+	return r.URL.Query().Get(continueParam) != ""
+}
+
+// renderPreview writes the page with the destination of the visitor and a button that follows the link
+func (ro *router) renderPreview(w http.ResponseWriter, r *http.Request, link storage.Link, target *url.URL) {
+	page := pages.Preview{
+		Alias:       link.Alias,
+		Title:       link.Title,
+		Host:        target.Hostname(),
+		Destination: target.String(),
+		ContinueURL: continueURL(r, link.Alias),
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	err := pages.Render(w, http.StatusOK, "preview.html", page)
+	if err != nil {
+		ro.log.Error("failed to render preview page", slo.Err(err))
+	}
+}
+
+// continueURL returns the request url without the preview suffix and with the continue parameter
+func continueURL(r *http.Request, alias string) string {
+	escaped := url.PathEscape(alias)
+	path := strings.Replace(r.URL.EscapedPath(), "/"+escaped+previewSuffix, "/"+escaped, 1)
+
+	query := r.URL.Query()
+	query.Set(continueParam, "1")
+
+	return path + "?" + query.Encode()
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/pkg/lockout"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+	"time"
+)
+
+func TestRedirectHandler_Preview(t *testing.T) {
+	tests := map[string]struct {
+		link        storage.Link
+		path        string
+		previewAll  bool
+		wantURL     string
+		wantPreview []string
+		prepare     func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Suffix previews any link": {
+			link: storage.Link{Alias: "launc", URL: "https://example.com/launch"},
+			path: "/v1/launc+",
+			wantPreview: []string{
+				`<h1>The link launc</h1>`,
+				`<span class="host">example.com</span>`,
+				`<p class="destination">https://example.com/launch</p>`,
+				`href="/v1/launc?continue=1"`,
+			},
+		},
+		"Flagged link shows title": {
+			link: storage.Link{Alias: "launc", URL: "https://example.com/launch", Title: "Launch <beta>", Preview: true},
+			path: "/v1/launc",
+			wantPreview: []string{
+				`<title>Launch &lt;beta&gt; - Link preview</title>`,
+				`<h1>Launch &lt;beta&gt;</h1>`,
+			},
+		},
+		"Preview keeps the path and the query": {
+			link: storage.Link{Alias: "launc", URL: "https://example.com/docs", AppendPath: true, MergeQuery: true},
+			path: "/v1/launc+/guide?page=2",
+			wantPreview: []string{
+				`<p class="destination">https://example.com/docs/guide?page=2</p>`,
+				`href="/v1/launc/guide?continue=1&amp;page=2"`,
+			},
+		},
+		"Global preview": {
+			link:        storage.Link{Alias: "launc", URL: "https://example.com/launch"},
+			path:        "/v1/launc",
+			previewAll:  true,
+			wantPreview: []string{`href="/v1/launc?continue=1"`},
+		},
+		"Continue redirects and drops the parameter": {
+			link:    storage.Link{Alias: "launc", URL: "https://example.com/launch", MergeQuery: true, Preview: true},
+			path:    "/v1/launc?continue=1&page=2",
+			wantURL: "https://example.com/launch?page=2",
+		},
+		"Preview does not use up clicks": {
+			link:        storage.Link{Alias: "launc", URL: "https://example.com/launch", MaxClicks: 1, ClicksLeft: 1, Preview: true},
+			path:        "/v1/launc",
+			wantPreview: []string{`href="/v1/launc?continue=1"`},
+		},
+		"Continue uses up a click": {
+			link:    storage.Link{Alias: "launc", URL: "https://example.com/launch", MaxClicks: 1, ClicksLeft: 1, Preview: true},
+			path:    "/v1/launc?continue=1",
+			wantURL: "https://example.com/launch",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ConsumeClick(storage.DefaultWorkspace, "", "launc").Return(nil)
+			},
+		},
+		"Link without preview redirects": {
+			link:    storage.Link{Alias: "launc", URL: "https://example.com/launch"},
+			path:    "/v1/launc",
+			wantURL: "https://example.com/launch",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectDefaultDomain(mockStorage)
+			mockStorage.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(tc.link, nil)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			r := &router{
+				storage:    mockStorage,
+				log:        slog.Default(),
+				lockout:    lockout.New(3, time.Minute, time.Minute),
+				previewAll: tc.previewAll,
+			}
+
+			chiRouter := chi.NewRouter()
+			chiRouter.Get("/v1/{alias}", r.redirectHandler)
+			chiRouter.Get("/v1/{alias}/*", r.redirectHandler)
+
+			w := httptest.NewRecorder()
+			chiRouter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if tc.wantPreview == nil {
+				require.Equal(t, http.StatusFound, w.Code)
+				assert.Equal(t, tc.wantURL, w.Header().Get("Location"))
+
+				return
+			}
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+			for _, fragment := range tc.wantPreview {
+				assert.Contains(t, w.Body.String(), fragment)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"net/url"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/qr"
+	"shorty/internal/storage"
+	"strconv"
+)
+
+const handlersOperationQR = "handlers.url.qr"
+
+const (
+	defaultQRSize   = 256
+	minQRSize       = 64
+	maxQRSize       = 2048
+	defaultQRMargin = 4
+	maxQRMargin     = 16
+)
+
+// qrHandler returns a QR code of the short url of the link, the format is png or svg,
+// taken from the format query parameter or the extension of the path
+func (ro *router) qrHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationQR),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	alias := chi.URLParam(r, "alias")
+	domain := domainParam(r)
+
+	format, opts, msg := qrOptions(r)
+	if msg != "" {
+		log.Info("invalid qr request", slog.String("reason", msg))
+		render.JSON(w, r, resp.Error(msg))
+
+		return
+	}
+
+	_, err := ro.storage.GetLink(workspaceID(r), domain, alias)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		log.Info("url not found", slog.String("alias", alias))
+		render.JSON(w, r, resp.Error("url not found for given alias"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to get url by given alias", slog.String("alias", alias), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	var (
+		image       []byte
+		contentType string
+	)
+
+	content := ro.shortURL(r, domain, alias)
+	if format == "svg" {
+		image, err = qr.SVG(content, opts)
+		contentType = "image/svg+xml"
+	} else {
+		image, err = qr.PNG(content, opts)
+		contentType = "image/png"
+	}
+
+	if errors.Is(err, qr.ErrSizeTooSmall) {
+		log.Info("qr size is too small", slog.String("alias", alias), slog.Int("size", opts.Size))
+		render.JSON(w, r, resp.Error("invalid request: "+err.Error()))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to render qr code", slog.String("alias", alias), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(image)))
+	_, err = w.Write(image)
+	if err != nil {
+		log.Debug("failed to write qr code", slo.Err(err))
+	}
+}
+
+// qrOptions reads the format and the rendering options of a qr request,
+// it returns a human-readable error text for a client if a parameter is invalid
+func qrOptions(r *http.Request) (string, qr.Options, string) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format, _ = r.Context().Value(middleware.URLFormatCtxKey).(string)
+	}
+
+	switch format {
+	case "":
+		format = "png"
+	case "png", "svg":
+	default:
+		return "", qr.Options{}, "invalid request: format must be png or svg"
+	}
+
+	opts := qr.Options{Size: defaultQRSize, Level: qr.LevelMedium, Margin: defaultQRMargin}
+
+	if size := query.Get("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < minQRSize || n > maxQRSize {
+			return "", qr.Options{}, fmt.Sprintf("invalid request: size must be between %d and %d", minQRSize, maxQRSize)
+		}
+		opts.Size = n
+	}
+
+	if level := query.Get("level"); level != "" {
+		l, err := qr.ParseLevel(level)
+		if err != nil {
+			return "", qr.Options{}, "invalid request: level must be L, M, Q or H"
+		}
+		opts.Level = l
+	}
+
+	if margin := query.Get("margin"); margin != "" {
+		n, err := strconv.Atoi(margin)
+		if err != nil || n < 0 || n > maxQRMargin {
+			return "", qr.Options{}, fmt.Sprintf("invalid request: margin must be between 0 and %d", maxQRMargin)
+		}
+		opts.Margin = n
+	}
+
+	return format, opts, ""
+}
+
+// shortURL returns the public url of the alias on the domain, the request host is used if no base url is configured.
+// Custom domains serve aliases under the same path as the base url
+func (ro *router) shortURL(r *http.Request, domain string, alias string) string {
+	if ro.baseURL != "" {
+		if domain == "" {
+			return ro.baseURL + "/" + alias
+		}
+
+		base, err := url.Parse(ro.baseURL)
+		if err == nil {
+			base.Host = domain
+
+			return base.String() + "/" + alias
+		}
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	host := r.Host
+	if domain != "" {
+		host = domain
+	}
+
+	return scheme + "://" + host + "/v1/" + alias
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"strings"
+	"testing"
+)
+
+func TestQRHandler(t *testing.T) {
+	tests := map[string]struct {
+		path        string
+		baseURL     string
+		wantErr     error
+		contentType string
+		prepare     func(mockUrlProvider *mocks.MockUrlProvider)
+		check       func(t *testing.T, body []byte)
+	}{
+		"PNG by default": {
+			path:        "/v1/url/launc/qr",
+			contentType: "image/png",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{Alias: "launc"}, nil)
+			},
+			check: func(t *testing.T, body []byte) {
+				img, err := png.Decode(strings.NewReader(string(body)))
+				require.NoError(t, err)
+				assert.Equal(t, defaultQRSize, img.Bounds().Dx())
+			},
+		},
+		"PNG with size": {
+			path:        "/v1/url/launc/qr?size=512&level=h&margin=0",
+			contentType: "image/png",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{Alias: "launc"}, nil)
+			},
+			check: func(t *testing.T, body []byte) {
+				img, err := png.Decode(strings.NewReader(string(body)))
+				require.NoError(t, err)
+				assert.Equal(t, 512, img.Bounds().Dx())
+			},
+		},
+		"SVG by parameter": {
+			path:        "/v1/url/launc/qr?format=svg&size=128",
+			baseURL:     "https://sho.rt/v1/",
+			contentType: "image/svg+xml",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{Alias: "launc"}, nil)
+			},
+			check: func(t *testing.T, body []byte) {
+				assert.Contains(t, string(body), `<svg xmlns="http://www.w3.org/2000/svg" width="128" height="128"`)
+			},
+		},
+		"SVG by extension": {
+			path:        "/v1/url/launc/qr.svg",
+			contentType: "image/svg+xml",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{Alias: "launc"}, nil)
+			},
+		},
+		"Unknown format": {
+			path:    "/v1/url/launc/qr?format=gif",
+			wantErr: errors.New("invalid request: format must be png or svg"),
+		},
+		"Invalid size": {
+			path:    "/v1/url/launc/qr?size=10",
+			wantErr: errors.New("invalid request: size must be between 64 and 2048"),
+		},
+		"Invalid level": {
+			path:    "/v1/url/launc/qr?level=x",
+			wantErr: errors.New("invalid request: level must be L, M, Q or H"),
+		},
+		"Invalid margin": {
+			path:    "/v1/url/launc/qr?margin=-1",
+			wantErr: errors.New("invalid request: margin must be between 0 and 16"),
+		},
+		"Size too small for margin": {
+			path:    "/v1/url/launc/qr?size=64&level=h&margin=16",
+			wantErr: errors.New("invalid request: size is too small for the code: 64 pixels for 65 modules"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{Alias: "launc"}, nil)
+			},
+		},
+		"Not found": {
+			path:    "/v1/url/launc/qr",
+			wantErr: errors.New("url not found for given alias"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "launc").Return(storage.Link{}, storage.ErrURLNotFound)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			r := SetupRouter(mockStorage, config.Config{BaseURL: tc.baseURL}, slog.Default())
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if tc.wantErr != nil {
+				var response Response
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.wantErr.Error(), response.Error)
+
+				return
+			}
+
+			assert.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+			if tc.check != nil {
+				tc.check(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestShortURL(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/url/launc/qr", nil)
+	req.Host = "links.example.com"
+
+	ro := &router{}
+	assert.Equal(t, "http://links.example.com/v1/launc", ro.shortURL(req, "", "launc"))
+	assert.Equal(t, "http://go.brand.com/v1/launc", ro.shortURL(req, "go.brand.com", "launc"))
+
+	ro.baseURL = "https://sho.rt/go"
+	assert.Equal(t, "https://sho.rt/go/launc", ro.shortURL(req, "", "launc"))
+	assert.Equal(t, "https://go.brand.com/go/launc", ro.shortURL(req, "go.brand.com", "launc"))
+}
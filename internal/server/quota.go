@@ -0,0 +1,126 @@
+package server
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"shorty/internal/config"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/storage"
+	"strconv"
+	"time"
+)
+
+// QuotaLimit is the usage of one link quota of the caller, Limit is 0 and Remaining is absent if it is unlimited
+type QuotaLimit struct {
+	Limit     int64      `json:"limit"`
+	Used      int64      `json:"used"`
+	Remaining *int64     `json:"remaining,omitempty"`
+	ResetAt   *time.Time `json:"reset_at,omitempty"`
+}
+
+type QuotaResponse struct {
+	resp.Response
+	Daily *QuotaLimit `json:"daily,omitempty"`
+	Total *QuotaLimit `json:"total,omitempty"`
+}
+
+const handlersOperationQuota = "handlers.me.quota"
+
+func (ro *router) quotaHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationQuota),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	now := time.Now()
+	usage, err := ro.storage.GetQuotaUsage(caller(r), now)
+	if err != nil {
+		log.Error("failed to get quota usage", slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	reset := quotaReset(now)
+	daily := quotaLimit(ro.userQuota.Daily, usage.Daily)
+	daily.ResetAt = &reset
+	total := quotaLimit(ro.userQuota.Total, usage.Total)
+
+	render.JSON(w, r, QuotaResponse{
+		Response: resp.OK(),
+		Daily:    &daily,
+		Total:    &total,
+	})
+}
+
+func quotaLimit(limit int64, used int64) QuotaLimit {
+	quota := QuotaLimit{Limit: limit, Used: used}
+	if limit > 0 {
+		remaining := max(limit-used, 0)
+		quota.Remaining = &remaining
+	}
+
+	return quota
+}
+
+func linkQuota(cfg config.UserQuota) storage.LinkQuota {
+	return storage.LinkQuota{Daily: cfg.DailyLinks, Total: cfg.TotalLinks}
+}
+
+// quotaReset returns the start of the next quota day, quotas are reset at midnight UTC
+func quotaReset(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
+
+// reserveLinks counts count links the caller is about to create against its quota,
+// it writes an error response and returns false if they do not fit.
+// Links are counted while no quota is configured too, so a quota enabled later covers them
+func (ro *router) reserveLinks(w http.ResponseWriter, r *http.Request, count int64, now time.Time) bool {
+	if count == 0 {
+		return true
+	}
+
+	owner := caller(r)
+	usage, err := ro.storage.ReserveLinks(owner, count, ro.userQuota, now)
+	if errors.Is(err, storage.ErrUserQuotaExceeded) {
+		ro.log.Info("user link quota exceeded", slog.String("user", owner), slog.Int64("links", count))
+
+		if ro.userQuota.Total > 0 && usage.Total+count > ro.userQuota.Total {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.ErrorCode(resp.CodeUserQuotaExceeded, "total link quota exceeded"))
+
+			return false
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(quotaReset(now).Sub(now).Seconds())+1))
+		render.Status(r, http.StatusTooManyRequests)
+		render.JSON(w, r, resp.ErrorCode(resp.CodeUserQuotaExceeded, "daily link quota exceeded"))
+
+		return false
+	}
+
+	if err != nil {
+		ro.log.Error("failed to reserve links", slog.String("user", owner), slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to save url"))
+
+		return false
+	}
+
+	return true
+}
+
+// releaseLinks gives back reserved links of the caller that were not created
+func (ro *router) releaseLinks(r *http.Request, count int64, now time.Time) {
+	if count == 0 {
+		return
+	}
+
+	err := ro.storage.ReleaseLinks(caller(r), count, now)
+	if err != nil {
+		ro.log.Error("failed to release links", slog.String("user", caller(r)), slo.Err(err))
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+)
+
+// expectLinkCounts accepts the link usage that is recorded on every link creation
+func expectLinkCounts(mockUrlProvider *mocks.MockUrlProvider) {
+	mockUrlProvider.EXPECT().ReserveLinks(gomock.Any(), gomock.Any(), storage.LinkQuota{}, gomock.Any()).Return(storage.QuotaUsage{}, nil).AnyTimes()
+	mockUrlProvider.EXPECT().ReleaseLinks(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+}
+
+func TestSaveHandler_UserQuota(t *testing.T) {
+	quota := storage.LinkQuota{Daily: 10, Total: 100}
+
+	tests := map[string]struct {
+		wantCode       int
+		wantErr        string
+		wantRetryAfter bool
+		prepare        func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Within quota": {
+			wantCode: http.StatusOK,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveLinks("alice", int64(1), quota, gomock.Any()).Return(storage.QuotaUsage{Daily: 1, Total: 1}, nil)
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).Return(int64(1), nil)
+			},
+		},
+		"Daily quota exceeded": {
+			wantCode:       http.StatusTooManyRequests,
+			wantErr:        "daily link quota exceeded",
+			wantRetryAfter: true,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveLinks("alice", int64(1), quota, gomock.Any()).Return(storage.QuotaUsage{Daily: 10, Total: 50}, storage.ErrUserQuotaExceeded)
+			},
+		},
+		"Total quota exceeded": {
+			wantCode: http.StatusForbidden,
+			wantErr:  "total link quota exceeded",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveLinks("alice", int64(1), quota, gomock.Any()).Return(storage.QuotaUsage{Daily: 2, Total: 100}, storage.ErrUserQuotaExceeded)
+			},
+		},
+		"Save failed": {
+			wantCode: http.StatusOK,
+			wantErr:  "url already exists",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveLinks("alice", int64(1), quota, gomock.Any()).Return(storage.QuotaUsage{Daily: 1, Total: 1}, nil)
+				mockUrlProvider.EXPECT().SaveLink(gomock.Any()).Return(int64(0), storage.ErrURLAlreadyExists)
+				mockUrlProvider.EXPECT().ReleaseLinks("alice", int64(1), gomock.Any()).Return(nil)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			tc.prepare(mockStorage)
+
+			cfg := config.Config{
+				HTTPServer: config.HTTPServer{User: "alice", Password: "secret"},
+				UserQuota:  config.UserQuota{DailyLinks: 10, TotalLinks: 100},
+			}
+			r := SetupRouter(mockStorage, cfg, slog.Default())
+			req := httptest.NewRequest(http.MethodPost, "/v1/url", bytes.NewReader([]byte(`{"url": "https://example.com", "alias": "promo"}`)))
+			req.SetBasicAuth("alice", "secret")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tc.wantCode, w.Code)
+			assert.Equal(t, tc.wantRetryAfter, w.Header().Get("Retry-After") != "")
+
+			var response Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.wantErr, response.Error)
+
+			if tc.wantCode != http.StatusOK {
+				assert.Equal(t, resp.CodeUserQuotaExceeded, response.Code)
+			}
+		})
+	}
+}
+
+func TestSaveHandler_UserQuotaDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockUrlProvider(ctrl)
+	//links are counted without a quota, so a quota enabled later covers them
+	mockStorage.EXPECT().ReserveLinks("alice", int64(1), storage.LinkQuota{}, gomock.Any()).Return(storage.QuotaUsage{Daily: 1, Total: 1}, nil)
+	mockStorage.EXPECT().SaveLink(gomock.Any()).Return(int64(1), nil)
+
+	cfg := config.Config{HTTPServer: config.HTTPServer{User: "alice", Password: "secret"}}
+	r := SetupRouter(mockStorage, cfg, slog.Default())
+	req := httptest.NewRequest(http.MethodPost, "/v1/url", bytes.NewReader([]byte(`{"url": "https://example.com", "alias": "promo"}`)))
+	req.SetBasicAuth("alice", "secret")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Empty(t, response.Error)
+}
+
+func TestSaveBatchHandler_UserQuota(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockUrlProvider(ctrl)
+	mockStorage.EXPECT().ReserveLinks("", int64(2), storage.LinkQuota{Total: 10}, gomock.Any()).Return(storage.QuotaUsage{Total: 2}, nil)
+	mockStorage.EXPECT().SaveLinks(gomock.Any(), false).
+		Return([]storage.SaveResult{{ID: 1}, {Err: storage.ErrURLAlreadyExists}}, nil)
+	//the link that was not saved is given back
+	mockStorage.EXPECT().ReleaseLinks("", int64(1), gomock.Any()).Return(nil)
+
+	r := SetupRouter(mockStorage, config.Config{UserQuota: config.UserQuota{TotalLinks: 10}}, slog.Default())
+	body := `{"items": [{"url": "https://example.com/a", "alias": "aaaaa"}, {"url": "https://example.com/b", "alias": "bbbbb"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/url/batch", bytes.NewReader([]byte(body)))
+	req.SetBasicAuth("", "")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestImportHandler_UserQuota(t *testing.T) {
+	quota := storage.LinkQuota{Total: 10}
+	body := "{\"alias\":\"aaaaa\",\"url\":\"https://example.com/a\"}\n{\"alias\":\"bbbbb\",\"url\":\"https://example.com/b\"}\n"
+
+	tests := map[string]struct {
+		wantCode int
+		wantErr  string
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Skipped links are given back": {
+			wantCode: http.StatusOK,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveLinks("", int64(2), quota, gomock.Any()).Return(storage.QuotaUsage{Total: 2}, nil)
+				mockUrlProvider.EXPECT().ImportLinks(gomock.Len(2), storage.ConflictSkip).Return(storage.ImportResult{Created: 1, Skipped: 1}, nil)
+				mockUrlProvider.EXPECT().ReleaseLinks("", int64(1), gomock.Any()).Return(nil)
+			},
+		},
+		"Failed import is given back": {
+			wantCode: http.StatusOK,
+			wantErr:  `link "aaaaa": url already exists`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveLinks("", int64(2), quota, gomock.Any()).Return(storage.QuotaUsage{Total: 2}, nil)
+				mockUrlProvider.EXPECT().ImportLinks(gomock.Len(2), storage.ConflictSkip).Return(storage.ImportResult{},
+					&storage.LinkError{Alias: "aaaaa", Err: storage.ErrURLAlreadyExists})
+				mockUrlProvider.EXPECT().ReleaseLinks("", int64(2), gomock.Any()).Return(nil)
+			},
+		},
+		"Total quota exceeded": {
+			wantCode: http.StatusForbidden,
+			wantErr:  "total link quota exceeded",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ReserveLinks("", int64(2), quota, gomock.Any()).Return(storage.QuotaUsage{Total: 9}, storage.ErrUserQuotaExceeded)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			tc.prepare(mockStorage)
+
+			r := SetupRouter(mockStorage, config.Config{UserQuota: config.UserQuota{TotalLinks: 10}}, slog.Default())
+			req := httptest.NewRequest(http.MethodPost, "/v1/import?on_conflict=skip", bytes.NewReader([]byte(body)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tc.wantCode, w.Code)
+
+			var response Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.wantErr, response.Error)
+		})
+	}
+}
+
+func TestQuotaHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockUrlProvider(ctrl)
+	mockStorage.EXPECT().GetQuotaUsage("", gomock.Any()).Return(storage.QuotaUsage{Daily: 4, Total: 40}, nil)
+
+	r := SetupRouter(mockStorage, config.Config{UserQuota: config.UserQuota{DailyLinks: 3}}, slog.Default())
+	req := httptest.NewRequest(http.MethodGet, "/v1/me/quota", nil)
+	req.SetBasicAuth("", "")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response QuotaResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.NotNil(t, response.Daily)
+	require.NotNil(t, response.Daily.Remaining)
+	assert.Equal(t, int64(0), *response.Daily.Remaining)
+	assert.NotNil(t, response.Daily.ResetAt)
+	require.NotNil(t, response.Total)
+	assert.Equal(t, int64(40), response.Total.Used)
+	assert.Nil(t, response.Total.Remaining, "the total is unlimited")
+}
@@ -6,19 +6,45 @@ import (
 	"log/slog"
 	"net/http"
 	"shorty/internal/config"
+	"shorty/internal/pkg/credcache"
+	"shorty/internal/pkg/lockout"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/urlnorm"
+	"shorty/internal/pkg/urlpolicy"
 	mwLogger "shorty/internal/server/middleware/logger"
+	"shorty/internal/storage"
+	"strings"
+	"time"
 )
 
 type router struct {
 	storage UrlProvider
 	log     *slog.Logger
+	lockout *lockout.Lockout
+	geo     CountryLocator
+	//idempotencyWindow is how long responses to requests with an Idempotency-Key are kept
+	idempotencyWindow time.Duration
+	normalization     urlnorm.Options
+	policy            *urlpolicy.Policy
+	chains            chains
+	//baseURL is the public prefix of short urls without a trailing slash, empty if not configured
+	baseURL string
+	//previewAll shows the preview page before the redirect of every link
+	previewAll bool
+	//adminUser and adminPassword are the credentials of the configured user of the default workspace
+	adminUser     string
+	adminPassword string
+	//userQuota caps the links every api user creates
+	userQuota storage.LinkQuota
+	//credentials remembers verified credentials of workspace users
+	credentials *credcache.Cache[principal]
 }
 
-func SetupRouter(storage UrlProvider, cfg config.Config, log *slog.Logger) http.Handler {
-	ro := &router{
-		storage: storage,
-		log:     log,
-	}
+// Option enables an optional dependency of the router
+type Option func(ro *router)
+
+func SetupRouter(storage UrlProvider, cfg config.Config, log *slog.Logger, opts ...Option) http.Handler {
+	ro := newRouter(storage, cfg, log, opts...)
 
 	r := chi.NewRouter()
 	r.Use(
@@ -29,22 +55,115 @@ func SetupRouter(storage UrlProvider, cfg config.Config, log *slog.Logger) http.
 		middleware.URLFormat, // /{alias}
 	)
 
+	//the root of a custom domain leads to its default url
+	r.Get("/", ro.rootHandler)
+
 	r.Route("/v1", func(r chi.Router) {
-		ro.registerHandlers(r, cfg)
+		r.Group(ro.registerVisitorHandlers)
+		r.Group(ro.registerHandlers)
 	})
 
 	return r
 }
 
-func (ro *router) registerHandlers(r chi.Router, cfg config.Config) {
-	r.Use(middleware.BasicAuth("shorty", map[string]string{
-		cfg.HTTPServer.User: cfg.HTTPServer.Password,
-	}))
+func newRouter(storage UrlProvider, cfg config.Config, log *slog.Logger, opts ...Option) *router {
+	ro := &router{
+		storage: storage,
+		log:     log,
+		lockout: lockout.New(cfg.Lockout.MaxAttempts, cfg.Lockout.Window, cfg.Lockout.Duration),
+
+		idempotencyWindow: cfg.Idempotency.Window,
+		normalization:     urlnorm.Options{StripTracking: cfg.URLNormalization.StripTrackingParams},
+		baseURL:           strings.TrimSuffix(cfg.BaseURL, "/"),
+		previewAll:        cfg.Preview.AllLinks,
+		adminUser:         cfg.HTTPServer.User,
+		adminPassword:     cfg.HTTPServer.Password,
+		userQuota:         linkQuota(cfg.UserQuota),
+		credentials:       credcache.New[principal](cfg.HTTPServer.CredentialsTTL),
+	}
 
+	redirectChains, err := newChains(cfg.BaseURL, cfg.RedirectChains.MaxDepth)
+	if err != nil {
+		log.Error("redirect chain checks are disabled", slo.Err(err))
+	}
+	ro.chains = redirectChains
+
+	for _, opt := range opts {
+		opt(ro)
+	}
+
+	if ro.policy == nil {
+		ro.policy = urlpolicy.New(urlpolicy.Options{})
+	}
+
+	return ro
+}
+
+// registerVisitorHandlers serves short links to visitors without credentials,
+// the domain of the request selects the workspace the alias is looked up in
+func (ro *router) registerVisitorHandlers(r chi.Router) {
 	r.Get("/{alias}", ro.redirectHandler)
+	r.Get("/{alias}/*", ro.redirectHandler)
+	//password form of protected links
+	r.Post("/{alias}", ro.redirectHandler)
+	r.Post("/{alias}/*", ro.redirectHandler)
+}
+
+func (ro *router) registerHandlers(r chi.Router) {
+	//requests are served in the workspace of the authenticated user
+	r.Use(ro.authenticate)
+
 	r.Route("/url", func(r chi.Router) {
 		r.Post("/", ro.saveAliasHandler)
+		r.Get("/", ro.listLinksHandler)
+		r.Post("/batch", ro.saveBatchHandler)
+		r.Get("/{alias}", ro.getLinkHandler)
+		r.Get("/{alias}/qr", ro.qrHandler)
+		r.Get("/{alias}/rules", ro.getRulesHandler)
+		r.Put("/{alias}/rules", ro.setRulesHandler)
+		r.Put("/{alias}/tags", ro.setLinkTagsHandler)
+		r.Put("/{alias}/folder", ro.setLinkFolderHandler)
 		r.Delete("/{alias}", ro.deleteAliasHandler)
 		r.Patch("/{alias}", ro.updateAliasHandler)
 	})
+	r.Get("/export", ro.exportHandler)
+	r.Post("/import", ro.importHandler)
+	r.Route("/domains", func(r chi.Router) {
+		r.Post("/", ro.saveDomainHandler)
+		r.Get("/", ro.listDomainsHandler)
+		r.Get("/{host}", ro.getDomainHandler)
+		r.Put("/{host}", ro.updateDomainHandler)
+		r.Delete("/{host}", ro.deleteDomainHandler)
+	})
+	r.Route("/templates", func(r chi.Router) {
+		r.Post("/", ro.saveTemplateHandler)
+		r.Get("/", ro.listTemplatesHandler)
+		r.Get("/{name}", ro.getTemplateHandler)
+		r.Put("/{name}", ro.updateTemplateHandler)
+		r.Delete("/{name}", ro.deleteTemplateHandler)
+	})
+	r.Route("/tags", func(r chi.Router) {
+		r.Post("/", ro.saveTagHandler)
+		r.Get("/", ro.listTagsHandler)
+		r.Put("/{name}", ro.renameTagHandler)
+		r.Delete("/{name}", ro.deleteTagHandler)
+	})
+	r.Route("/folders", func(r chi.Router) {
+		r.Post("/", ro.saveFolderHandler)
+		r.Get("/", ro.listFoldersHandler)
+		r.Get("/{id}", ro.getFolderHandler)
+		r.Put("/{id}", ro.updateFolderHandler)
+		r.Delete("/{id}", ro.deleteFolderHandler)
+	})
+	r.Get("/me/quota", ro.quotaHandler)
+	r.Route("/workspaces", func(r chi.Router) {
+		r.Use(requireAdmin)
+		r.Post("/", ro.saveWorkspaceHandler)
+		r.Get("/", ro.listWorkspacesHandler)
+		r.Get("/{id}", ro.getWorkspaceHandler)
+		r.Put("/{id}", ro.updateWorkspaceHandler)
+		r.Post("/{id}/users", ro.saveUserHandler)
+		r.Get("/{id}/users", ro.listUsersHandler)
+		r.Delete("/{id}/users/{name}", ro.deleteUserHandler)
+	})
 }
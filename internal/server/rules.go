@@ -0,0 +1,188 @@
+package server
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/useragent"
+	"shorty/internal/storage"
+)
+
+// Rule sends visitors of a platform to its own target
+type Rule struct {
+	OS     string `json:"os,omitempty" validate:"omitempty,oneof=ios android windows macos chromeos linux other"`
+	Device string `json:"device,omitempty" validate:"omitempty,oneof=mobile tablet desktop"`
+	Target string `json:"target" validate:"required,url"`
+}
+
+type RulesRequest struct {
+	Rules []Rule `json:"rules" validate:"dive"`
+}
+
+type RulesResponse struct {
+	resp.Response
+	Rules []Rule `json:"rules"`
+}
+
+const (
+	handlersOperationGetRules = "handlers.rules.get"
+	handlersOperationSetRules = "handlers.rules.set"
+)
+
+func (ro *router) getRulesHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationGetRules),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	alias := chi.URLParam(r, "alias")
+	link, err := ro.storage.GetLink(workspaceID(r), domainParam(r), alias)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		log.Info("url not found", slog.String("alias", alias))
+		render.JSON(w, r, resp.Error("url not found for given alias"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to get url by given alias", slog.String("alias", alias), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	render.JSON(w, r, RulesResponse{
+		Response: resp.OK(),
+		Rules:    rulesFromStorage(link.Rules),
+	})
+}
+
+func (ro *router) setRulesHandler(w http.ResponseWriter, r *http.Request) {
+	var req RulesRequest
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationSetRules),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	alias := chi.URLParam(r, "alias")
+
+	err := render.DecodeJSON(r.Body, &req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return
+	}
+
+	err = validator.New().Struct(req)
+	if err != nil {
+		validateErr := err.(validator.ValidationErrors)
+		log.Error("invalid request", slo.Err(err))
+		render.JSON(w, r, resp.ValidationError(validateErr))
+
+		return
+	}
+
+	if msg := validateRules(req.Rules); msg != "" {
+		log.Info("invalid rules", slog.String("alias", alias), slog.String("reason", msg))
+		render.JSON(w, r, resp.Error(msg))
+
+		return
+	}
+
+	for _, rule := range req.Rules {
+		if msg := ro.checkTargets(rule.Target); msg != "" {
+			render.JSON(w, r, resp.Error(msg))
+
+			return
+		}
+
+		if msg := ro.checkChain(workspaceID(r), domainParam(r), alias, rule.Target); msg != "" {
+			render.JSON(w, r, resp.Error(msg))
+
+			return
+		}
+	}
+
+	err = ro.storage.SetRules(workspaceID(r), domainParam(r), alias, rulesToStorage(req.Rules))
+	if errors.Is(err, storage.ErrURLNotFound) {
+		log.Info("url not found", slog.String("alias", alias))
+		render.JSON(w, r, resp.Error("url not found for given alias"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to set rules", slog.String("alias", alias), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	log.Info("rules successfully set", slog.String("alias", alias), slog.Int("rules", len(req.Rules)))
+	render.JSON(w, r, RulesResponse{
+		Response: resp.OK(),
+		Rules:    rulesFromStorage(rulesToStorage(req.Rules)),
+	})
+}
+
+// validateRules returns a human-readable error text for a client, or an empty string if the rules are valid
+func validateRules(rules []Rule) string {
+	for _, rule := range rules {
+		//a rule without conditions would hide the default target of the link
+		if rule.OS == "" && rule.Device == "" {
+			return "invalid request: rule must match an os or a device"
+		}
+	}
+
+	return ""
+}
+
+// matchRule returns the target of the first rule matching the visitor platform
+func matchRule(rules []storage.Rule, r *http.Request) (string, bool) {
+	if len(rules) == 0 {
+		return "", false
+	}
+
+	agent := useragent.Parse(r.UserAgent())
+	for _, rule := range rules {
+		if (rule.OS == "" || rule.OS == agent.OS) && (rule.Device == "" || rule.Device == agent.Device) {
+			return rule.Target, true
+		}
+	}
+
+	return "", false
+}
+
+func rulesToStorage(rules []Rule) []storage.Rule {
+	var result []storage.Rule
+	for _, rule := range rules {
+		result = append(result, storage.Rule{OS: rule.OS, Device: rule.Device, Target: rule.Target})
+	}
+
+	return result
+}
+
+func rulesFromStorage(rules []storage.Rule) []Rule {
+	result := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, Rule{OS: rule.OS, Device: rule.Device, Target: rule.Target})
+	}
+
+	return result
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestRulesHandlers(t *testing.T) {
+	tests := map[string]struct {
+		method   string
+		input    string
+		wantErr  error
+		expected string
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Get: success": {
+			method:   http.MethodGet,
+			expected: `{"status":"ok","rules":[{"os":"ios","target":"https://apps.apple.com/app/id1"}]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "myapp").Return(storage.Link{
+					Rules: []storage.Rule{{OS: "ios", Target: "https://apps.apple.com/app/id1"}},
+				}, nil)
+			},
+		},
+		"Get: no rules": {
+			method:   http.MethodGet,
+			expected: `{"status":"ok","rules":[]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "myapp").Return(storage.Link{}, nil)
+			},
+		},
+		"Get: not found": {
+			method:  http.MethodGet,
+			wantErr: errors.New("url not found for given alias"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "myapp").Return(storage.Link{}, storage.ErrURLNotFound)
+			},
+		},
+		"Set: success": {
+			method:   http.MethodPut,
+			input:    `{"rules": [{"os": "android", "device": "mobile", "target": "https://play.google.com"}]}`,
+			expected: `{"status":"ok","rules":[{"os":"android","device":"mobile","target":"https://play.google.com"}]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SetRules(storage.DefaultWorkspace, "", "myapp", []storage.Rule{
+					{OS: "android", Device: "mobile", Target: "https://play.google.com"},
+				}).Return(nil)
+			},
+		},
+		"Set: clear": {
+			method:   http.MethodPut,
+			input:    `{"rules": []}`,
+			expected: `{"status":"ok","rules":[]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SetRules(storage.DefaultWorkspace, "", "myapp", nil).Return(nil)
+			},
+		},
+		"Set: invalid target": {
+			method:  http.MethodPut,
+			input:   `{"rules": [{"os": "ios", "target": "not a url"}]}`,
+			wantErr: errors.New("\"Target\" is not a valid URL"),
+		},
+		"Set: unknown device": {
+			method:  http.MethodPut,
+			input:   `{"rules": [{"device": "watch", "target": "https://example.com"}]}`,
+			wantErr: errors.New("\"Device\" field is not valid"),
+		},
+		"Set: not found": {
+			method:  http.MethodPut,
+			input:   `{"rules": [{"os": "ios", "target": "https://example.com"}]}`,
+			wantErr: errors.New("url not found for given alias"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SetRules(storage.DefaultWorkspace, "", "myapp", gomock.Any()).Return(storage.ErrURLNotFound)
+			},
+		},
+		"Set: empty request": {
+			method:  http.MethodPut,
+			wantErr: errors.New("empty request"),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(tc.method, "/v1/url/myapp/rules", bytes.NewReader([]byte(tc.input)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if tc.wantErr != nil {
+				var response RulesResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.wantErr.Error(), response.Error)
+			} else {
+				assert.JSONEq(t, tc.expected, w.Body.String())
+			}
+		})
+	}
+}
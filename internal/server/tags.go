@@ -0,0 +1,272 @@
+package server
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/tagname"
+	"shorty/internal/storage"
+)
+
+// Tag labels links, names are case-insensitive and stored in lower case
+type Tag struct {
+	Name string `json:"name" validate:"required,max=50"`
+	//Links is the number of links with the tag
+	Links int64 `json:"links"`
+}
+
+type TagResponse struct {
+	resp.Response
+	Tag *Tag `json:"tag,omitempty"`
+}
+
+type TagsResponse struct {
+	resp.Response
+	Tags []Tag `json:"tags"`
+}
+
+// LinkTagsRequest replaces the tags of a link, an empty list removes all of them
+type LinkTagsRequest struct {
+	Tags []string `json:"tags" validate:"max=20,dive,required,max=50"`
+}
+
+type LinkTagsResponse struct {
+	resp.Response
+	Tags []string `json:"tags"`
+}
+
+const (
+	handlersOperationSaveTag     = "handlers.tag.save"
+	handlersOperationListTags    = "handlers.tag.list"
+	handlersOperationRenameTag   = "handlers.tag.rename"
+	handlersOperationDeleteTag   = "handlers.tag.delete"
+	handlersOperationSetLinkTags = "handlers.url.tags"
+)
+
+func (ro *router) saveTagHandler(w http.ResponseWriter, r *http.Request) {
+	var req Tag
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationSaveTag),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	if !decodeTag(w, r, log, &req) {
+		return
+	}
+
+	_, err := ro.storage.SaveTag(storage.Tag{WorkspaceID: workspaceID(r), Name: req.Name})
+	if errors.Is(err, storage.ErrTagAlreadyExists) {
+		log.Info("tag already exists", slog.String("name", req.Name))
+		render.JSON(w, r, resp.Error("tag already exists"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to save tag", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to save tag"))
+
+		return
+	}
+
+	log.Info("tag successfully saved", slog.String("name", req.Name))
+	render.JSON(w, r, TagResponse{
+		Response: resp.OK(),
+		Tag:      &req,
+	})
+}
+
+func (ro *router) listTagsHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationListTags),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	tags, err := ro.storage.ListTags(workspaceID(r))
+	if err != nil {
+		log.Error("failed to list tags", slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	result := make([]Tag, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, Tag{Name: tag.Name, Links: tag.Links})
+	}
+
+	render.JSON(w, r, TagsResponse{
+		Response: resp.OK(),
+		Tags:     result,
+	})
+}
+
+// renameTagHandler renames the tag in the path to the name in the body, links keep the tag
+func (ro *router) renameTagHandler(w http.ResponseWriter, r *http.Request) {
+	var req Tag
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationRenameTag),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	if !decodeTag(w, r, log, &req) {
+		return
+	}
+
+	name := tagname.Normalize(chi.URLParam(r, "name"))
+	err := ro.storage.RenameTag(workspaceID(r), name, req.Name)
+	if errors.Is(err, storage.ErrTagNotFound) {
+		log.Info("tag not found", slog.String("name", name))
+		render.JSON(w, r, resp.Error("tag not found"))
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrTagAlreadyExists) {
+		log.Info("tag already exists", slog.String("name", req.Name))
+		render.JSON(w, r, resp.Error("tag already exists"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to rename tag", slog.String("name", name), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	log.Info("tag successfully renamed", slog.String("name", name), slog.String("new_name", req.Name))
+	render.JSON(w, r, TagResponse{
+		Response: resp.OK(),
+		Tag:      &Tag{Name: req.Name},
+	})
+}
+
+func (ro *router) deleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationDeleteTag),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	name := tagname.Normalize(chi.URLParam(r, "name"))
+	err := ro.storage.DeleteTag(workspaceID(r), name)
+	if errors.Is(err, storage.ErrTagNotFound) {
+		log.Info("tag not found", slog.String("name", name))
+		render.JSON(w, r, resp.Error("tag not found"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to delete tag", slog.String("name", name), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	log.Info("tag successfully deleted", slog.String("name", name))
+	render.JSON(w, r, resp.OK())
+}
+
+func (ro *router) setLinkTagsHandler(w http.ResponseWriter, r *http.Request) {
+	var req LinkTagsRequest
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationSetLinkTags),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	alias := chi.URLParam(r, "alias")
+
+	err := render.DecodeJSON(r.Body, &req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return
+	}
+
+	tags := tagname.NormalizeAll(req.Tags)
+	err = validator.New().Struct(LinkTagsRequest{Tags: tags})
+	if err != nil {
+		validateErr := err.(validator.ValidationErrors)
+		log.Error("invalid request", slo.Err(err))
+		render.JSON(w, r, resp.ValidationError(validateErr))
+
+		return
+	}
+
+	err = ro.storage.SetTags(workspaceID(r), domainParam(r), alias, tags)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		log.Info("url not found", slog.String("alias", alias))
+		render.JSON(w, r, resp.Error("url not found for given alias"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to set tags", slog.String("alias", alias), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	if tags == nil {
+		tags = []string{}
+	}
+
+	log.Info("tags successfully set", slog.String("alias", alias), slog.Int("tags", len(tags)))
+	render.JSON(w, r, LinkTagsResponse{
+		Response: resp.OK(),
+		Tags:     tags,
+	})
+}
+
+// decodeTag decodes and validates a tag from the request body and normalizes its name,
+// it writes an error response and returns false if the tag is not valid
+func decodeTag(w http.ResponseWriter, r *http.Request, log *slog.Logger, req *Tag) bool {
+	err := render.DecodeJSON(r.Body, req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+
+		return false
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return false
+	}
+
+	req.Name = tagname.Normalize(req.Name)
+	req.Links = 0
+	err = validator.New().Struct(req)
+	if err != nil {
+		validateErr := err.(validator.ValidationErrors)
+		log.Error("invalid request", slo.Err(err))
+		render.JSON(w, r, resp.ValidationError(validateErr))
+
+		return false
+	}
+
+	return true
+}
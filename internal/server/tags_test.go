@@ -0,0 +1,195 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestTagHandlers(t *testing.T) {
+	tests := map[string]struct {
+		method   string
+		path     string
+		input    string
+		wantErr  error
+		expected string
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Save: name is normalized": {
+			method:   http.MethodPost,
+			path:     "/v1/tags",
+			input:    `{"name": " Launch "}`,
+			expected: `{"status":"ok","tag":{"name":"launch","links":0}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveTag(storage.Tag{Name: "launch"}).Return(int64(1), nil)
+			},
+		},
+		"Save: empty name": {
+			method:  http.MethodPost,
+			path:    "/v1/tags",
+			input:   `{"name": "  "}`,
+			wantErr: errors.New("\"Name\" field is mandatory"),
+		},
+		"Save: already exists": {
+			method:  http.MethodPost,
+			path:    "/v1/tags",
+			input:   `{"name": "launch"}`,
+			wantErr: errors.New("tag already exists"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveTag(gomock.Any()).Return(int64(0), storage.ErrTagAlreadyExists)
+			},
+		},
+		"List": {
+			method:   http.MethodGet,
+			path:     "/v1/tags",
+			expected: `{"status":"ok","tags":[{"name":"launch","links":3}]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ListTags(storage.DefaultWorkspace).Return([]storage.Tag{{ID: 1, Name: "launch", Links: 3}}, nil)
+			},
+		},
+		"Rename: success": {
+			method:   http.MethodPut,
+			path:     "/v1/tags/Launch",
+			input:    `{"name": "launch-2030"}`,
+			expected: `{"status":"ok","tag":{"name":"launch-2030","links":0}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().RenameTag(storage.DefaultWorkspace, "launch", "launch-2030").Return(nil)
+			},
+		},
+		"Rename: not found": {
+			method:  http.MethodPut,
+			path:    "/v1/tags/launch",
+			input:   `{"name": "spring"}`,
+			wantErr: errors.New("tag not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().RenameTag(storage.DefaultWorkspace, "launch", "spring").Return(storage.ErrTagNotFound)
+			},
+		},
+		"Delete: not found": {
+			method:  http.MethodDelete,
+			path:    "/v1/tags/launch",
+			wantErr: errors.New("tag not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().DeleteTag(storage.DefaultWorkspace, "launch").Return(storage.ErrTagNotFound)
+			},
+		},
+		"Set link tags: duplicates are dropped": {
+			method:   http.MethodPut,
+			path:     "/v1/url/promo/tags",
+			input:    `{"tags": ["Spring", "launch", "spring"]}`,
+			expected: `{"status":"ok","tags":["launch","spring"]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SetTags(storage.DefaultWorkspace, "", "promo", []string{"launch", "spring"}).Return(nil)
+			},
+		},
+		"Set link tags: empty tag": {
+			method:  http.MethodPut,
+			path:    "/v1/url/promo/tags",
+			input:   `{"tags": ["launch", " "]}`,
+			wantErr: errors.New("\"Tags[0]\" field is mandatory"),
+		},
+		"Set link tags: url not found": {
+			method:  http.MethodPut,
+			path:    "/v1/url/promo/tags",
+			input:   `{"tags": []}`,
+			wantErr: errors.New("url not found for given alias"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SetTags(storage.DefaultWorkspace, "", "promo", nil).Return(storage.ErrURLNotFound)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.input)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if tc.wantErr != nil {
+				var response TagResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.wantErr.Error(), response.Error)
+			} else {
+				assert.JSONEq(t, tc.expected, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestListLinksHandler_Organization(t *testing.T) {
+	tests := map[string]struct {
+		query   string
+		filter  storage.LinkFilter
+		wantErr string
+	}{
+		"Tags": {
+			query:  "?tag=Launch&tag=spring",
+			filter: storage.LinkFilter{Limit: defaultListLimit, Tags: []string{"launch", "spring"}},
+		},
+		"Folder": {
+			query:  "?folder=3",
+			filter: storage.LinkFilter{Limit: defaultListLimit, FolderID: 3},
+		},
+		"Empty tag": {
+			query:   "?tag=",
+			wantErr: "invalid request: tag must not be empty",
+		},
+		"Invalid folder": {
+			query:   "?folder=root",
+			wantErr: "invalid request: folder must be a folder id",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			if tc.wantErr == "" {
+				mockStorage.EXPECT().ListLinks(tc.filter).
+					Return([]storage.Link{{Alias: "promo", URL: "https://example.com", Tags: []string{"launch"}, FolderID: 3}}, nil)
+			}
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(http.MethodGet, "/v1/url"+tc.query, nil)
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response ListResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.wantErr, response.Error)
+
+			if tc.wantErr == "" {
+				require.Len(t, response.Links, 1)
+				assert.Equal(t, []string{"launch"}, response.Links[0].Tags)
+				assert.Equal(t, int64(3), response.Links[0].FolderID)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/utm"
+	"shorty/internal/storage"
+)
+
+type Template struct {
+	Name     string `json:"name" validate:"required"`
+	Source   string `json:"utm_source,omitempty"`
+	Medium   string `json:"utm_medium,omitempty"`
+	Campaign string `json:"utm_campaign,omitempty"`
+	Term     string `json:"utm_term,omitempty"`
+	Content  string `json:"utm_content,omitempty"`
+}
+
+type TemplateResponse struct {
+	resp.Response
+	Template *Template `json:"template,omitempty"`
+}
+
+type TemplatesResponse struct {
+	resp.Response
+	Templates []Template `json:"templates"`
+}
+
+const (
+	handlersOperationSaveTemplate   = "handlers.template.save"
+	handlersOperationGetTemplate    = "handlers.template.get"
+	handlersOperationListTemplates  = "handlers.template.list"
+	handlersOperationUpdateTemplate = "handlers.template.update"
+	handlersOperationDeleteTemplate = "handlers.template.delete"
+)
+
+func (ro *router) saveTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var req Template
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationSaveTemplate),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	if !decodeTemplate(w, r, log, &req) {
+		return
+	}
+
+	_, err := ro.storage.SaveTemplate(req.toStorage(workspaceID(r)))
+	if errors.Is(err, storage.ErrTemplateAlreadyExists) {
+		log.Info("template already exists", slog.String("name", req.Name))
+		render.JSON(w, r, resp.Error("template already exists"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to save template", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to save template"))
+
+		return
+	}
+
+	log.Info("template successfully saved", slog.String("name", req.Name))
+	render.JSON(w, r, TemplateResponse{
+		Response: resp.OK(),
+		Template: &req,
+	})
+}
+
+func (ro *router) getTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationGetTemplate),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	name := chi.URLParam(r, "name")
+	template, err := ro.storage.GetTemplate(workspaceID(r), name)
+	if errors.Is(err, storage.ErrTemplateNotFound) {
+		log.Info("template not found", slog.String("name", name))
+		render.JSON(w, r, resp.Error("template not found"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to get template", slog.String("name", name), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	result := templateFromStorage(template)
+	render.JSON(w, r, TemplateResponse{
+		Response: resp.OK(),
+		Template: &result,
+	})
+}
+
+func (ro *router) listTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationListTemplates),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	templates, err := ro.storage.ListTemplates(workspaceID(r))
+	if err != nil {
+		log.Error("failed to list templates", slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	result := make([]Template, 0, len(templates))
+	for _, template := range templates {
+		result = append(result, templateFromStorage(template))
+	}
+
+	render.JSON(w, r, TemplatesResponse{
+		Response:  resp.OK(),
+		Templates: result,
+	})
+}
+
+func (ro *router) updateTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var req Template
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationUpdateTemplate),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	//the name in the path identifies the template, renaming is not supported
+	req.Name = chi.URLParam(r, "name")
+	if !decodeTemplate(w, r, log, &req) {
+		return
+	}
+
+	if req.Name != chi.URLParam(r, "name") {
+		log.Info("template name can not be changed", slog.String("name", req.Name))
+		render.JSON(w, r, resp.Error("invalid request: template name can not be changed"))
+
+		return
+	}
+
+	err := ro.storage.UpdateTemplate(req.toStorage(workspaceID(r)))
+	if errors.Is(err, storage.ErrTemplateNotFound) {
+		log.Info("template not found", slog.String("name", req.Name))
+		render.JSON(w, r, resp.Error("template not found"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to update template", slog.String("name", req.Name), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	log.Info("template successfully updated", slog.String("name", req.Name))
+	render.JSON(w, r, TemplateResponse{
+		Response: resp.OK(),
+		Template: &req,
+	})
+}
+
+func (ro *router) deleteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationDeleteTemplate),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	name := chi.URLParam(r, "name")
+	err := ro.storage.DeleteTemplate(workspaceID(r), name)
+	if errors.Is(err, storage.ErrTemplateNotFound) {
+		log.Info("template not found", slog.String("name", name))
+		render.JSON(w, r, resp.Error("template not found"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to delete template", slog.String("name", name), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	log.Info("template successfully deleted", slog.String("name", name))
+	render.JSON(w, r, resp.OK())
+}
+
+// decodeTemplate decodes and validates a template from the request body,
+// it writes an error response and returns false if the template is not valid
+func decodeTemplate(w http.ResponseWriter, r *http.Request, log *slog.Logger, req *Template) bool {
+	err := render.DecodeJSON(r.Body, req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+
+		return false
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return false
+	}
+
+	err = validator.New().Struct(req)
+	if err != nil {
+		validateErr := err.(validator.ValidationErrors)
+		log.Error("invalid request", slo.Err(err))
+		render.JSON(w, r, resp.ValidationError(validateErr))
+
+		return false
+	}
+
+	if len(templateParams(req.toStorage(storage.DefaultWorkspace))) == 0 {
+		log.Info("template has no utm parameters", slog.String("name", req.Name))
+		render.JSON(w, r, resp.Error("invalid request: template has no utm parameters"))
+
+		return false
+	}
+
+	return true
+}
+
+func (t Template) toStorage(workspaceID int64) storage.Template {
+	return storage.Template{
+		WorkspaceID: workspaceID,
+		Name:        t.Name,
+		Source:      t.Source,
+		Medium:      t.Medium,
+		Campaign:    t.Campaign,
+		Term:        t.Term,
+		Content:     t.Content,
+	}
+}
+
+func templateFromStorage(t storage.Template) Template {
+	return Template{
+		Name:     t.Name,
+		Source:   t.Source,
+		Medium:   t.Medium,
+		Campaign: t.Campaign,
+		Term:     t.Term,
+		Content:  t.Content,
+	}
+}
+
+// templateParams returns non-empty utm parameters of a template
+func templateParams(t storage.Template) []utm.Param {
+	all := []utm.Param{
+		{Key: "utm_source", Value: t.Source},
+		{Key: "utm_medium", Value: t.Medium},
+		{Key: "utm_campaign", Value: t.Campaign},
+		{Key: "utm_term", Value: t.Term},
+		{Key: "utm_content", Value: t.Content},
+	}
+
+	var params []utm.Param
+	for _, param := range all {
+		if param.Value != "" {
+			params = append(params, param)
+		}
+	}
+
+	return params
+}
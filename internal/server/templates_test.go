@@ -0,0 +1,162 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestTemplateHandlers(t *testing.T) {
+	spring := storage.Template{ID: 1, Name: "spring", Source: "newsletter", Campaign: "spring"}
+
+	tests := map[string]struct {
+		method   string
+		path     string
+		input    string
+		wantErr  error
+		expected string
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Save: success": {
+			method:   http.MethodPost,
+			path:     "/v1/templates",
+			input:    `{"name": "spring", "utm_source": "newsletter", "utm_campaign": "spring"}`,
+			expected: `{"status":"ok","template":{"name":"spring","utm_source":"newsletter","utm_campaign":"spring"}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveTemplate(storage.Template{Name: "spring", Source: "newsletter", Campaign: "spring"}).Return(int64(1), nil)
+			},
+		},
+		"Save: missing name": {
+			method:  http.MethodPost,
+			path:    "/v1/templates",
+			input:   `{"utm_source": "newsletter"}`,
+			wantErr: errors.New("\"Name\" field is mandatory"),
+		},
+		"Save: no utm parameters": {
+			method:  http.MethodPost,
+			path:    "/v1/templates",
+			input:   `{"name": "spring"}`,
+			wantErr: errors.New("invalid request: template has no utm parameters"),
+		},
+		"Save: already exists": {
+			method:  http.MethodPost,
+			path:    "/v1/templates",
+			input:   `{"name": "spring", "utm_source": "newsletter"}`,
+			wantErr: errors.New("template already exists"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveTemplate(gomock.Any()).Return(int64(0), storage.ErrTemplateAlreadyExists)
+			},
+		},
+		"Get: success": {
+			method:   http.MethodGet,
+			path:     "/v1/templates/spring",
+			expected: `{"status":"ok","template":{"name":"spring","utm_source":"newsletter","utm_campaign":"spring"}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetTemplate(storage.DefaultWorkspace, "spring").Return(spring, nil)
+			},
+		},
+		"Get: not found": {
+			method:  http.MethodGet,
+			path:    "/v1/templates/spring",
+			wantErr: errors.New("template not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetTemplate(storage.DefaultWorkspace, "spring").Return(storage.Template{}, storage.ErrTemplateNotFound)
+			},
+		},
+		"List: success": {
+			method:   http.MethodGet,
+			path:     "/v1/templates",
+			expected: `{"status":"ok","templates":[{"name":"spring","utm_source":"newsletter","utm_campaign":"spring"}]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ListTemplates(storage.DefaultWorkspace).Return([]storage.Template{spring}, nil)
+			},
+		},
+		"List: empty": {
+			method:   http.MethodGet,
+			path:     "/v1/templates",
+			expected: `{"status":"ok","templates":[]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ListTemplates(storage.DefaultWorkspace).Return(nil, nil)
+			},
+		},
+		"Update: success": {
+			method:   http.MethodPut,
+			path:     "/v1/templates/spring",
+			input:    `{"utm_source": "blog"}`,
+			expected: `{"status":"ok","template":{"name":"spring","utm_source":"blog"}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().UpdateTemplate(storage.Template{Name: "spring", Source: "blog"}).Return(nil)
+			},
+		},
+		"Update: rename": {
+			method:  http.MethodPut,
+			path:    "/v1/templates/spring",
+			input:   `{"name": "summer", "utm_source": "blog"}`,
+			wantErr: errors.New("invalid request: template name can not be changed"),
+		},
+		"Update: not found": {
+			method:  http.MethodPut,
+			path:    "/v1/templates/spring",
+			input:   `{"utm_source": "blog"}`,
+			wantErr: errors.New("template not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().UpdateTemplate(gomock.Any()).Return(fmt.Errorf("%s: %w", "storage.sqlite.UpdateTemplate", storage.ErrTemplateNotFound))
+			},
+		},
+		"Delete: success": {
+			method:   http.MethodDelete,
+			path:     "/v1/templates/spring",
+			expected: `{"status":"ok"}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().DeleteTemplate(storage.DefaultWorkspace, "spring").Return(nil)
+			},
+		},
+		"Delete: internal error": {
+			method:  http.MethodDelete,
+			path:    "/v1/templates/spring",
+			wantErr: errors.New("internal error"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().DeleteTemplate(storage.DefaultWorkspace, "spring").Return(errors.New("unexpected error"))
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.input)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if tc.wantErr != nil {
+				var response TemplateResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.wantErr.Error(), response.Error)
+			} else {
+				assert.JSONEq(t, tc.expected, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"net/http"
+	"shorty/internal/config"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/pkg/tagname"
+	"shorty/internal/pkg/urlnorm"
+	"shorty/internal/storage"
+	"shorty/internal/storage/transfer"
+	"time"
+)
+
+type ImportResponse struct {
+	resp.Response
+	Created     int `json:"created"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+}
+
+const (
+	handlersOperationExport = "handlers.links.export"
+	handlersOperationImport = "handlers.links.import"
+)
+
+// passwordHashesParam asks the export for the password hashes of protected links, only the admin gets them
+const passwordHashesParam = "password_hashes"
+
+// exportHandler streams every link of the workspace as CSV or NDJSON, the format is taken from the format query parameter
+func (ro *router) exportHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationExport),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	format, err := transferFormat(r)
+	if err != nil {
+		log.Info("invalid export format", slo.Err(err))
+		render.JSON(w, r, resp.Error("invalid request: format must be csv or ndjson"))
+
+		return
+	}
+
+	passwordHashes := r.URL.Query().Get(passwordHashesParam) == "true"
+	if caller, _ := r.Context().Value(principalKey{}).(principal); passwordHashes && !caller.admin {
+		log.Info("password hashes requested by a workspace user")
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, resp.Error("forbidden: password hashes are exported for the admin only"))
+
+		return
+	}
+
+	//large exports outlive the write timeout of the server
+	err = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	if err != nil {
+		log.Debug("failed to lift write deadline", slo.Err(err))
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, format))
+
+	writer := transfer.NewWriter(w, format)
+	if passwordHashes {
+		writer.IncludePasswordHashes()
+	}
+	count := 0
+	err = ro.storage.EachLink(workspaceID(r), func(link storage.Link) error {
+		count++
+
+		return writer.Write(link)
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	//the status is already sent, a failed export ends up truncated
+	if err != nil {
+		log.Error("failed to export links", slog.Int("exported", count), slo.Err(err))
+
+		return
+	}
+
+	log.Info("links exported", slog.Int("count", count))
+}
+
+// importHandler saves the links of a CSV or NDJSON body in a single transaction,
+// the on_conflict query parameter decides what happens to taken aliases
+func (ro *router) importHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationImport),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	format, err := transferFormat(r)
+	if err != nil {
+		log.Info("invalid import format", slo.Err(err))
+		render.JSON(w, r, resp.Error("invalid request: format must be csv or ndjson"))
+
+		return
+	}
+
+	conflict := r.URL.Query().Get("on_conflict")
+	if conflict == "" {
+		conflict = string(storage.ConflictFail)
+	}
+
+	policy, err := transfer.ParseConflictPolicy(conflict)
+	if err != nil {
+		log.Info("invalid conflict policy", slo.Err(err))
+		render.JSON(w, r, resp.Error("invalid request: on_conflict must be skip, overwrite or fail"))
+
+		return
+	}
+
+	links, err := transfer.ReadAll(r.Body, format)
+	if errors.Is(err, transfer.ErrInvalidRecord) {
+		log.Info("invalid import record", slo.Err(err))
+		render.JSON(w, r, resp.Error("invalid request: "+err.Error()))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to read import", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return
+	}
+
+	if len(links) == 0 {
+		log.Info("import is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+
+		return
+	}
+
+	workspace := workspaceID(r)
+	for i := range links {
+		links[i].WorkspaceID = workspace
+		links[i].Owner = caller(r)
+	}
+
+	if msg := ro.prepareImport(links); msg != "" {
+		render.JSON(w, r, resp.Error(msg))
+
+		return
+	}
+
+	//every link may be created, links that are overwritten, skipped or not imported are given back
+	now := time.Now()
+	if !ro.reserveLinks(w, r, int64(len(links)), now) {
+		return
+	}
+
+	result, err := ro.storage.ImportLinks(links, policy)
+	ro.releaseLinks(r, int64(len(links)-result.Created), now)
+	if err != nil {
+		var linkErr *storage.LinkError
+		switch {
+		case errors.As(err, &linkErr) && errors.Is(err, storage.ErrURLAlreadyExists):
+			log.Info("import conflict", slog.String("alias", linkErr.Alias))
+			render.JSON(w, r, resp.Error(fmt.Sprintf("link %q: url already exists", linkErr.Alias)))
+		case errors.As(err, &linkErr) && errors.Is(err, storage.ErrTemplateNotFound):
+			log.Info("import template not found", slog.String("alias", linkErr.Alias))
+			render.JSON(w, r, resp.Error(fmt.Sprintf("link %q: template not found", linkErr.Alias)))
+		case errors.As(err, &linkErr) && errors.Is(err, storage.ErrDomainNotFound):
+			log.Info("import domain not found", slog.String("alias", linkErr.Alias))
+			render.JSON(w, r, resp.Error(fmt.Sprintf("link %q: domain not found", linkErr.Alias)))
+		case errors.As(err, &linkErr) && errors.Is(err, storage.ErrLinkQuotaExceeded):
+			log.Info("import exceeds workspace link quota", slog.String("alias", linkErr.Alias))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(fmt.Sprintf("link %q: workspace link quota exceeded", linkErr.Alias)))
+		default:
+			log.Error("failed to import links", slo.Err(err))
+			render.JSON(w, r, resp.Error("failed to import links"))
+		}
+
+		return
+	}
+
+	log.Info("links imported", slog.Int("created", result.Created), slog.Int("overwritten", result.Overwritten),
+		slog.Int("skipped", result.Skipped))
+
+	render.JSON(w, r, ImportResponse{
+		Response:    resp.OK(),
+		Created:     result.Created,
+		Overwritten: result.Overwritten,
+		Skipped:     result.Skipped,
+	})
+}
+
+// prepareImport validates imported links and normalizes their urls like created links and runs the destination checks
+// of link creation on them, it returns a human-readable error text for a client if a link may not be imported
+func (ro *router) prepareImport(links []storage.Link) string {
+	for i := range links {
+		link := &links[i]
+
+		link.Tags = tagname.NormalizeAll(link.Tags)
+		locales, countries, msg := ro.validateRequest(requestFromLink(*link))
+		if msg != "" {
+			return fmt.Sprintf("link %q: %s", link.Alias, msg)
+		}
+		link.Locales, link.Countries = locales, countries
+
+		if link.PasswordHash != "" {
+			if _, err := bcrypt.Cost([]byte(link.PasswordHash)); err != nil {
+				ro.log.Info("invalid imported password hash", slog.String("alias", link.Alias), slo.Err(err))
+
+				return fmt.Sprintf("link %q: invalid request: password_hash is not a bcrypt hash", link.Alias)
+			}
+		}
+
+		normalized, err := urlnorm.Normalize(link.URL, ro.normalization)
+		if err != nil {
+			ro.log.Info("failed to normalize url", slog.String("url", link.URL), slo.Err(err))
+
+			return fmt.Sprintf("link %q: \"URL\" is not a valid URL", link.Alias)
+		}
+
+		//exported links carry their original url
+		if link.OriginalURL == "" {
+			link.OriginalURL = link.URL
+		}
+		link.URL = normalized
+
+		msg = ro.checkDestinations(*link)
+		if msg == "" {
+			msg = ro.checkChain(link.WorkspaceID, link.Domain, link.Alias, linkTargets(*link)...)
+		}
+
+		if msg != "" {
+			return fmt.Sprintf("link %q: %s", link.Alias, msg)
+		}
+	}
+
+	return ""
+}
+
+// requestFromLink builds the creation request of an imported link, so the link is validated like a created one
+func requestFromLink(link storage.Link) Request {
+	req := Request{
+		URL:           link.URL,
+		Alias:         link.Alias,
+		Domain:        link.Domain,
+		MergeQuery:    link.MergeQuery,
+		AppendPath:    link.AppendPath,
+		MaxClicks:     link.MaxClicks,
+		Title:         link.Title,
+		Preview:       link.Preview,
+		OGTitle:       link.OpenGraph.Title,
+		OGDescription: link.OpenGraph.Description,
+		OGImage:       link.OpenGraph.Image,
+		Tags:          link.Tags,
+		FallbackURL:   link.FallbackURL,
+		Locales:       link.Locales,
+		Countries:     link.Countries,
+	}
+
+	if link.Template != nil {
+		req.Template = link.Template.Name
+	}
+
+	if !link.NotBefore.IsZero() {
+		req.NotBefore = &link.NotBefore
+	}
+
+	if !link.NotAfter.IsZero() {
+		req.NotAfter = &link.NotAfter
+	}
+
+	if len(link.Rules) > 0 {
+		req.Rules = rulesFromStorage(link.Rules)
+	}
+
+	for _, variant := range link.Variants {
+		req.Variants = append(req.Variants, Variant{Target: variant.Target, Weight: variant.Weight})
+	}
+
+	return req
+}
+
+// Importer imports links outside of an http request with the normalization and the checks of the import api
+type Importer struct {
+	ro *router
+}
+
+func NewImporter(storage UrlProvider, cfg config.Config, log *slog.Logger, opts ...Option) *Importer {
+	return &Importer{ro: newRouter(storage, cfg, log, opts...)}
+}
+
+// ImportLinks saves the links in a single transaction like the import api, nothing is saved if a link fails the checks
+func (i *Importer) ImportLinks(links []storage.Link, policy storage.ConflictPolicy) (storage.ImportResult, error) {
+	if msg := i.ro.prepareImport(links); msg != "" {
+		return storage.ImportResult{}, errors.New(msg)
+	}
+
+	return i.ro.storage.ImportLinks(links, policy)
+}
+
+// transferFormat returns the format of the format query parameter, NDJSON by default
+func transferFormat(r *http.Request) (transfer.Format, error) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		return transfer.NDJSON, nil
+	}
+
+	return transfer.ParseFormat(name)
+}
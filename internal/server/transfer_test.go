@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/urlpolicy"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportHandler(t *testing.T) {
+	links := []storage.Link{
+		{ID: 1, Alias: "first", URL: "https://example.com/1"},
+		{ID: 2, Alias: "secnd", URL: "https://example.com/2", MaxClicks: 3, ClicksLeft: 1},
+	}
+
+	tests := map[string]struct {
+		query       string
+		contentType string
+		body        string
+	}{
+		"NDJSON by default": {
+			contentType: "application/x-ndjson",
+			body: `{"alias":"first","url":"https://example.com/1"}` + "\n" +
+				`{"alias":"secnd","url":"https://example.com/2","max_clicks":3,"clicks_left":1}` + "\n",
+		},
+		"CSV": {
+			query:       "?format=csv",
+			contentType: "text/csv",
+			body: "alias,url,original_url,merge_query,append_path,template,password_hash,max_clicks,clicks_left,not_before,not_after,fallback_url,rules,variants,locales,countries,title,preview,og_title,og_description,og_image,domain,tags\n" +
+				"first,https://example.com/1,,false,false,,,0,,,,,,,,,,false,,,,,\n" +
+				"secnd,https://example.com/2,,false,false,,,3,1,,,,,,,,,false,,,,,\n",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			mockStorage.EXPECT().EachLink(storage.DefaultWorkspace, gomock.Any()).DoAndReturn(func(workspaceID int64, fn func(storage.Link) error) error {
+				for _, link := range links {
+					if err := fn(link); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			})
+
+			r := &router{
+				storage: mockStorage,
+				log:     slog.Default(),
+			}
+
+			chiRouter := chi.NewRouter()
+			chiRouter.Get("/v1/export", r.exportHandler)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/export"+tc.query, nil)
+			w := httptest.NewRecorder()
+			chiRouter.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tc.body, w.Body.String())
+		})
+	}
+}
+
+func TestExportHandler_PasswordHashes(t *testing.T) {
+	link := storage.Link{ID: 1, Alias: "secret", URL: "https://example.com", PasswordHash: "$2a$10$hash"}
+
+	tests := map[string]struct {
+		query        string
+		caller       principal
+		expectedCode int
+		body         string
+	}{
+		"Left out by default": {
+			caller:       principal{workspaceID: storage.DefaultWorkspace, admin: true},
+			expectedCode: http.StatusOK,
+			body:         `{"alias":"secret","url":"https://example.com"}` + "\n",
+		},
+		"Admin": {
+			query:        "?password_hashes=true",
+			caller:       principal{workspaceID: storage.DefaultWorkspace, admin: true},
+			expectedCode: http.StatusOK,
+			body:         `{"alias":"secret","url":"https://example.com","password_hash":"$2a$10$hash"}` + "\n",
+		},
+		"Workspace user": {
+			query:        "?password_hashes=true",
+			caller:       principal{workspaceID: storage.DefaultWorkspace},
+			expectedCode: http.StatusForbidden,
+			body:         `{"status":"error","error":"forbidden: password hashes are exported for the admin only"}` + "\n",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			mockStorage.EXPECT().EachLink(storage.DefaultWorkspace, gomock.Any()).DoAndReturn(func(workspaceID int64, fn func(storage.Link) error) error {
+				return fn(link)
+			}).MaxTimes(1)
+
+			r := &router{
+				storage: mockStorage,
+				log:     slog.Default(),
+			}
+
+			chiRouter := chi.NewRouter()
+			chiRouter.Get("/v1/export", r.exportHandler)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/export"+tc.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), principalKey{}, tc.caller))
+			w := httptest.NewRecorder()
+			chiRouter.ServeHTTP(w, req)
+
+			require.Equal(t, tc.expectedCode, w.Code)
+			assert.Equal(t, tc.body, w.Body.String())
+		})
+	}
+}
+
+func TestImportHandler(t *testing.T) {
+	tests := map[string]struct {
+		query        string
+		body         string
+		expectedResp ImportResponse
+		prepare      func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Success: csv with skip": {
+			query:        "?format=csv&on_conflict=skip",
+			body:         "alias,url\nfirst,HTTPS://Example.com:443/1\nsecnd,https://example.com/2\n",
+			expectedResp: ImportResponse{Response: resp.OK(), Created: 1, Skipped: 1},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ImportLinks([]storage.Link{
+					{Alias: "first", URL: "https://example.com/1", OriginalURL: "HTTPS://Example.com:443/1"},
+					{Alias: "secnd", URL: "https://example.com/2", OriginalURL: "https://example.com/2"},
+				}, storage.ConflictSkip).Return(storage.ImportResult{Created: 1, Skipped: 1}, nil)
+			},
+		},
+		"Conflict": {
+			body:         `{"alias":"taken","url":"https://example.com"}`,
+			expectedResp: ImportResponse{Response: resp.Error(`link "taken": url already exists`)},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ImportLinks(gomock.Len(1), storage.ConflictFail).Return(storage.ImportResult{},
+					fmt.Errorf("storage.sqlite.ImportLinks: %w", &storage.LinkError{Alias: "taken", Err: storage.ErrURLAlreadyExists}))
+			},
+		},
+		"Invalid record": {
+			body:         `{"alias":"bad","url":"javascript"}`,
+			expectedResp: ImportResponse{Response: resp.Error(`link "bad": "URL" is not a valid URL`)},
+			prepare:      func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Unknown policy": {
+			query:        "?on_conflict=merge",
+			body:         `{"alias":"a","url":"https://example.com"}`,
+			expectedResp: ImportResponse{Response: resp.Error("invalid request: on_conflict must be skip, overwrite or fail")},
+			prepare:      func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Unknown format": {
+			query:        "?format=xml",
+			expectedResp: ImportResponse{Response: resp.Error("invalid request: format must be csv or ndjson")},
+			prepare:      func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+		"Empty": {
+			expectedResp: ImportResponse{Response: resp.Error("empty request")},
+			prepare:      func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			expectLinkCounts(mockStorage)
+			tc.prepare(mockStorage)
+
+			r := &router{
+				storage: mockStorage,
+				log:     slog.Default(),
+			}
+
+			chiRouter := chi.NewRouter()
+			chiRouter.Post("/v1/import", r.importHandler)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/import"+tc.query, strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			chiRouter.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response ImportResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.expectedResp, response)
+		})
+	}
+}
+
+func TestImporter(t *testing.T) {
+	policy := urlpolicy.New(urlpolicy.Options{DeniedDomains: []string{"*.evil.com"}})
+	cfg := config.Config{BaseURL: "https://sho.rt/v1", RedirectChains: config.RedirectChains{MaxDepth: 3}}
+	hash, err := bcrypt.GenerateFromPassword([]byte("letmein"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		links   []storage.Link
+		wantErr string
+		prepare func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Normalized": {
+			links: []storage.Link{{Alias: "first", URL: "HTTPS://Example.com:443/1"}},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ImportLinks([]storage.Link{
+					{Alias: "first", URL: "https://example.com/1", OriginalURL: "HTTPS://Example.com:443/1"},
+				}, storage.ConflictFail).Return(storage.ImportResult{Created: 1}, nil)
+			},
+		},
+		"Denied domain": {
+			links:   []storage.Link{{Alias: "evil", URL: "https://www.evil.com"}},
+			wantErr: `link "evil": invalid request: destination is not allowed: domain is denied: "www.evil.com"`,
+		},
+		"Self reference": {
+			links:   []storage.Link{{Alias: "loopy", URL: "https://sho.rt/v1/loopy"}},
+			wantErr: `link "loopy": invalid request: link points to itself`,
+		},
+		"Password hash": {
+			links: []storage.Link{{Alias: "secret", URL: "https://example.com/", PasswordHash: string(hash)}},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ImportLinks([]storage.Link{
+					{Alias: "secret", URL: "https://example.com/", OriginalURL: "https://example.com/", PasswordHash: string(hash)},
+				}, storage.ConflictFail).Return(storage.ImportResult{Created: 1}, nil)
+			},
+		},
+		"Invalid password hash": {
+			links:   []storage.Link{{Alias: "secret", URL: "https://example.com", PasswordHash: "letmein"}},
+			wantErr: `link "secret": invalid request: password_hash is not a bcrypt hash`,
+		},
+		"Rule without os or device": {
+			links:   []storage.Link{{Alias: "ruled", URL: "https://example.com", Rules: []storage.Rule{{Target: "https://example.com/app"}}}},
+			wantErr: `link "ruled": invalid request: rule must match an os or a device`,
+		},
+		"Unknown rule os": {
+			links:   []storage.Link{{Alias: "ruled", URL: "https://example.com", Rules: []storage.Rule{{OS: "beos", Target: "https://example.com/app"}}}},
+			wantErr: `link "ruled": "OS" field is not valid`,
+		},
+		"Unknown locale": {
+			links:   []storage.Link{{Alias: "intl", URL: "https://example.com", Locales: map[string]string{"klingon-ish": "https://example.com/tlh"}}},
+			wantErr: `link "intl": invalid request: unknown locale "klingon-ish"`,
+		},
+		"Empty activation window": {
+			links: []storage.Link{{Alias: "window", URL: "https://example.com",
+				NotBefore: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), NotAfter: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}},
+			wantErr: `link "window": invalid request: not_after must be later than not_before`,
+		},
+		"Blank tag": {
+			links:   []storage.Link{{Alias: "tagged", URL: "https://example.com", Tags: []string{"launch", " "}}},
+			wantErr: `link "tagged": "Tags[0]" field is mandatory`,
+		},
+		"Canonical locales and countries": {
+			links: []storage.Link{{Alias: "intl", URL: "https://example.com",
+				Locales: map[string]string{"de-de": "https://example.com/de"}, Countries: map[string]string{"fr": "https://example.fr"}}},
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ImportLinks([]storage.Link{{Alias: "intl", URL: "https://example.com/", OriginalURL: "https://example.com",
+					Locales: map[string]string{"de-DE": "https://example.com/de"}, Countries: map[string]string{"FR": "https://example.fr"}}},
+					storage.ConflictFail).Return(storage.ImportResult{Created: 1}, nil)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			importer := NewImporter(mockStorage, cfg, slog.Default(), WithDestinationPolicy(policy))
+			_, err := importer.ImportLinks(tc.links, storage.ConflictFail)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
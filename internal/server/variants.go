@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"shorty/internal/pkg/random"
+	"shorty/internal/storage"
+	"time"
+)
+
+// Variant is one of the weighted targets of an A/B split link
+type Variant struct {
+	Target string `json:"target" validate:"required,url"`
+	Weight int    `json:"weight" validate:"gte=1"`
+	Clicks *int64 `json:"clicks,omitempty"`
+}
+
+const (
+	// visitorCookie keeps the visitor key, so that repeated visits get the same variant
+	visitorCookie    = "shorty_vid"
+	visitorKeyLength = 16
+	visitorCookieAge = 365 * 24 * time.Hour
+)
+
+// pickVariant deterministically assigns the visitor to one of the link variants.
+// The visitor key comes from a cookie, a new key is set for visitors without one.
+func pickVariant(w http.ResponseWriter, r *http.Request, link storage.Link) (storage.Variant, bool) {
+	total := 0
+	for _, variant := range link.Variants {
+		total += variant.Weight
+	}
+
+	if total <= 0 {
+		return storage.Variant{}, false
+	}
+
+	//the alias is part of the hash, so a visitor is not always in the first bucket of every link
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(link.Alias + "|" + visitorKey(w, r)))
+	point := int(hash.Sum64() % uint64(total))
+
+	for _, variant := range link.Variants {
+		if point < variant.Weight {
+			return variant, true
+		}
+		point -= variant.Weight
+	}
+
+	return storage.Variant{}, false
+}
+
+// visitorKey returns the key of the visitor from the cookie,
+// or sets a cookie with a new key derived from the client address and user agent.
+// Clients that do not keep cookies get the same derived key on every visit.
+func visitorKey(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(visitorCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	key := clientKey(r)
+	http.SetCookie(w, &http.Cookie{
+		Name:     visitorCookie,
+		Value:    key,
+		Path:     "/",
+		MaxAge:   int(visitorCookieAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return key
+}
+
+// clientKey hashes the client address and user agent into a cookie-safe key
+func clientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if host == "" {
+		return random.GenerateRandomString(visitorKeyLength)
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(host + "|" + r.UserAgent()))
+
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+func variantsToStorage(variants []Variant) []storage.Variant {
+	var result []storage.Variant
+	for _, variant := range variants {
+		result = append(result, storage.Variant{Target: variant.Target, Weight: variant.Weight})
+	}
+
+	return result
+}
+
+func variantsFromStorage(variants []storage.Variant) []Variant {
+	result := make([]Variant, 0, len(variants))
+	for _, variant := range variants {
+		clicks := variant.Clicks
+		result = append(result, Variant{Target: variant.Target, Weight: variant.Weight, Clicks: &clicks})
+	}
+
+	return result
+}
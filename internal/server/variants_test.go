@@ -0,0 +1,125 @@
+package server
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"testing"
+)
+
+var splitLink = storage.Link{
+	Alias: "split",
+	URL:   "https://example.com",
+	Variants: []storage.Variant{
+		{ID: 1, Target: "https://example.com/a", Weight: 1},
+		{ID: 2, Target: "https://example.com/b", Weight: 3},
+	},
+}
+
+func TestPickVariant_Sticky(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/split", nil)
+	w := httptest.NewRecorder()
+
+	first, ok := pickVariant(w, req, splitLink)
+	require.True(t, ok)
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, visitorCookie, cookies[0].Name)
+
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/split", nil)
+		req.AddCookie(cookies[0])
+		w := httptest.NewRecorder()
+
+		next, ok := pickVariant(w, req, splitLink)
+		require.True(t, ok)
+		assert.Equal(t, first, next, "repeat visits get the same variant")
+		assert.Empty(t, w.Result().Cookies(), "the cookie is not set again")
+	}
+}
+
+func TestPickVariant_WithoutCookie(t *testing.T) {
+	pick := func() storage.Variant {
+		req := httptest.NewRequest(http.MethodGet, "/v1/split", nil)
+		req.RemoteAddr = "203.0.113.7:4321"
+		req.Header.Set("User-Agent", "curl/8.4.0")
+
+		variant, ok := pickVariant(httptest.NewRecorder(), req, splitLink)
+		require.True(t, ok)
+
+		return variant
+	}
+
+	assert.Equal(t, pick(), pick(), "clients without cookies are assigned by their address and user agent")
+}
+
+func TestPickVariant_Weights(t *testing.T) {
+	counts := map[int64]int{}
+	for i := 0; i < 4000; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/split", nil)
+		req.AddCookie(&http.Cookie{Name: visitorCookie, Value: fmt.Sprintf("visitor-%d", i)})
+
+		variant, ok := pickVariant(httptest.NewRecorder(), req, splitLink)
+		require.True(t, ok)
+		counts[variant.ID]++
+	}
+
+	//1:3 split with some tolerance
+	assert.InDelta(t, 1000, counts[1], 150)
+	assert.InDelta(t, 3000, counts[2], 150)
+}
+
+func TestRedirectHandler_Variants(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockUrlProvider(ctrl)
+	expectDefaultDomain(mockStorage)
+	mockStorage.EXPECT().GetLink(storage.DefaultWorkspace, "", "split").Return(splitLink, nil)
+	mockStorage.EXPECT().CountVariantClick(int64(2)).Return(nil)
+
+	r := &router{
+		storage: mockStorage,
+		log:     slog.Default(),
+	}
+
+	chiRouter := chi.NewRouter()
+	chiRouter.Get("/v1/{alias}", r.redirectHandler)
+
+	//the key is chosen to land in the second bucket
+	req := httptest.NewRequest(http.MethodGet, "/v1/split", nil)
+	req.AddCookie(&http.Cookie{Name: visitorCookie, Value: variantBKey(t)})
+
+	w := httptest.NewRecorder()
+	chiRouter.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com/b", w.Header().Get("Location"))
+}
+
+// variantBKey finds a visitor key that is assigned to the second variant of splitLink
+func variantBKey(t *testing.T) string {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("visitor-%d", i)
+		req := httptest.NewRequest(http.MethodGet, "/v1/split", nil)
+		req.AddCookie(&http.Cookie{Name: visitorCookie, Value: key})
+
+		if variant, _ := pickVariant(httptest.NewRecorder(), req, splitLink); variant.ID == 2 {
+			return key
+		}
+	}
+
+	t.Fatal("no visitor key for the second variant")
+
+	return ""
+}
@@ -0,0 +1,566 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/logger/slo"
+	"shorty/internal/storage"
+	"strconv"
+	"time"
+)
+
+type Workspace struct {
+	ID   int64  `json:"id,omitempty"`
+	Name string `json:"name" validate:"required,max=100"`
+	//MaxLinks caps the number of links of the workspace, 0 if unlimited
+	MaxLinks int64 `json:"max_links,omitempty" validate:"gte=0"`
+	//MaxRequests caps the api requests of the workspace users per UTC day, 0 if unlimited
+	MaxRequests int64 `json:"max_requests,omitempty" validate:"gte=0"`
+	//PreviewAll shows the preview page before the redirect of every link of the workspace
+	PreviewAll bool `json:"preview_all,omitempty"`
+}
+
+type WorkspaceResponse struct {
+	resp.Response
+	Workspace *Workspace `json:"workspace,omitempty"`
+}
+
+type WorkspacesResponse struct {
+	resp.Response
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+// User is an api user of a workspace, the password is only accepted on creation
+type User struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Password string `json:"password,omitempty" validate:"required,min=8"`
+}
+
+// LogValue keeps the user password out of the logs
+func (u User) LogValue() slog.Value {
+	type user User //no LogValue method, prevents recursion
+
+	if u.Password != "" {
+		u.Password = "[redacted]"
+	}
+
+	return slog.AnyValue(user(u))
+}
+
+type UserResponse struct {
+	resp.Response
+	User *User `json:"user,omitempty"`
+}
+
+type UsersResponse struct {
+	resp.Response
+	Users []User `json:"users"`
+}
+
+const (
+	handlersOperationSaveWorkspace   = "handlers.workspace.save"
+	handlersOperationGetWorkspace    = "handlers.workspace.get"
+	handlersOperationListWorkspaces  = "handlers.workspace.list"
+	handlersOperationUpdateWorkspace = "handlers.workspace.update"
+	handlersOperationSaveUser        = "handlers.user.save"
+	handlersOperationListUsers       = "handlers.user.list"
+	handlersOperationDeleteUser      = "handlers.user.delete"
+)
+
+// principalKey is the context key of the authenticated caller
+type principalKey struct{}
+
+// principal is the authenticated caller of an api request
+type principal struct {
+	workspaceID int64
+	//admin manages the workspaces and their users, it is the configured user of the default workspace
+	admin bool
+	//maxRequests is the daily request quota of the workspace, 0 if unlimited
+	maxRequests int64
+}
+
+// workspaceID returns the workspace of the caller, the default workspace for unauthenticated requests
+func workspaceID(r *http.Request) int64 {
+	caller, _ := r.Context().Value(principalKey{}).(principal)
+
+	return caller.workspaceID
+}
+
+// authenticate checks the basic auth credentials of a request against the configured user
+// and the users of the workspaces, and counts the request against the quota of the workspace
+func (ro *router) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, password, ok := r.BasicAuth()
+		if !ok {
+			unauthorized(w)
+
+			return
+		}
+
+		caller, err := ro.principal(name, password)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			unauthorized(w)
+
+			return
+		}
+
+		if err != nil {
+			ro.log.Error("failed to authenticate user", slog.String("user", name), slo.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+
+			return
+		}
+
+		if !ro.checkRequestQuota(w, r, caller) {
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), principalKey{}, caller)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// principal returns the caller with the credentials, storage.ErrUserNotFound if they do not match any user.
+// Verified credentials of workspace users are remembered, the password hash is not checked on every request
+func (ro *router) principal(name string, password string) (principal, error) {
+	if subtle.ConstantTimeCompare([]byte(name), []byte(ro.adminUser)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(ro.adminPassword)) == 1 {
+		return principal{workspaceID: storage.DefaultWorkspace, admin: true}, nil
+	}
+
+	if caller, ok := ro.credentials.Get(name, password); ok {
+		return caller, nil
+	}
+
+	user, err := ro.storage.GetUser(name)
+	if err != nil {
+		return principal{}, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if err != nil {
+		return principal{}, storage.ErrUserNotFound
+	}
+
+	caller := principal{workspaceID: user.WorkspaceID}
+	if user.WorkspaceID != storage.DefaultWorkspace {
+		workspace, err := ro.storage.GetWorkspace(user.WorkspaceID)
+		if err != nil {
+			return principal{}, fmt.Errorf("get workspace: %w", err)
+		}
+		caller.maxRequests = workspace.MaxRequests
+	}
+
+	ro.credentials.Put(name, password, caller)
+
+	return caller, nil
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Add("WWW-Authenticate", `Basic realm="shorty"`)
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+// checkRequestQuota counts the request of a caller from a workspace with a daily request quota,
+// it writes an error response and returns false if the quota of the day is used up
+func (ro *router) checkRequestQuota(w http.ResponseWriter, r *http.Request, caller principal) bool {
+	if caller.maxRequests == 0 {
+		return true
+	}
+
+	workspaceID := caller.workspaceID
+	now := time.Now()
+	requests, err := ro.storage.CountRequest(workspaceID, now)
+	if err != nil {
+		ro.log.Error("failed to count request", slog.Int64("workspace_id", workspaceID), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return false
+	}
+
+	if requests > caller.maxRequests {
+		ro.log.Info("workspace request quota exceeded", slog.Int64("workspace_id", workspaceID))
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(quotaReset(now).Sub(now).Seconds())+1))
+		render.Status(r, http.StatusTooManyRequests)
+		render.JSON(w, r, resp.Error("workspace request quota exceeded"))
+
+		return false
+	}
+
+	return true
+}
+
+// requireAdmin restricts the management of workspaces to the configured user
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ := r.Context().Value(principalKey{}).(principal)
+		if !caller.admin {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error("forbidden"))
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (ro *router) saveWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
+	var req Workspace
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationSaveWorkspace),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	if !decodeWorkspace(w, r, log, &req) {
+		return
+	}
+
+	id, err := ro.storage.SaveWorkspace(req.toStorage())
+	if errors.Is(err, storage.ErrWorkspaceAlreadyExists) {
+		log.Info("workspace already exists", slog.String("name", req.Name))
+		render.JSON(w, r, resp.Error("workspace already exists"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to save workspace", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to save workspace"))
+
+		return
+	}
+
+	log.Info("workspace successfully saved", slog.Int64("id", id))
+	req.ID = id
+	render.JSON(w, r, WorkspaceResponse{
+		Response:  resp.OK(),
+		Workspace: &req,
+	})
+}
+
+func (ro *router) getWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationGetWorkspace),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	workspace, ok := ro.workspaceParam(w, r, log)
+	if !ok {
+		return
+	}
+
+	result := workspaceFromStorage(workspace)
+	render.JSON(w, r, WorkspaceResponse{
+		Response:  resp.OK(),
+		Workspace: &result,
+	})
+}
+
+func (ro *router) listWorkspacesHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationListWorkspaces),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	workspaces, err := ro.storage.ListWorkspaces()
+	if err != nil {
+		log.Error("failed to list workspaces", slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	result := make([]Workspace, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		result = append(result, workspaceFromStorage(workspace))
+	}
+
+	render.JSON(w, r, WorkspacesResponse{
+		Response:   resp.OK(),
+		Workspaces: result,
+	})
+}
+
+func (ro *router) updateWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
+	var req Workspace
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationUpdateWorkspace),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Info("invalid workspace id", slog.String("id", chi.URLParam(r, "id")))
+		render.JSON(w, r, resp.Error("invalid request: workspace id must be a number"))
+
+		return
+	}
+
+	if !decodeWorkspace(w, r, log, &req) {
+		return
+	}
+	req.ID = id
+
+	err = ro.storage.UpdateWorkspace(req.toStorage())
+	if errors.Is(err, storage.ErrWorkspaceNotFound) {
+		log.Info("workspace not found", slog.Int64("id", id))
+		render.JSON(w, r, resp.Error("workspace not found"))
+
+		return
+	}
+
+	if errors.Is(err, storage.ErrWorkspaceAlreadyExists) {
+		log.Info("workspace already exists", slog.String("name", req.Name))
+		render.JSON(w, r, resp.Error("workspace already exists"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to update workspace", slog.Int64("id", id), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	//cached callers carry the request quota of their workspace
+	ro.credentials.Flush()
+
+	log.Info("workspace successfully updated", slog.Int64("id", id))
+	render.JSON(w, r, WorkspaceResponse{
+		Response:  resp.OK(),
+		Workspace: &req,
+	})
+}
+
+func (ro *router) saveUserHandler(w http.ResponseWriter, r *http.Request) {
+	var req User
+
+	log := ro.log.With(
+		slog.String("operation", handlersOperationSaveUser),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	workspace, ok := ro.workspaceParam(w, r, log)
+	if !ok {
+		return
+	}
+
+	err := render.DecodeJSON(r.Body, &req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return
+	}
+
+	err = validator.New().Struct(req)
+	if err != nil {
+		validateErr := err.(validator.ValidationErrors)
+		log.Error("invalid request", slo.Err(err))
+		render.JSON(w, r, resp.ValidationError(validateErr))
+
+		return
+	}
+
+	//the configured user would shadow a stored one with the same name
+	if req.Name == ro.adminUser {
+		log.Info("user already exists", slog.String("name", req.Name))
+		render.JSON(w, r, resp.Error("user already exists"))
+
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("failed to hash password", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to save user"))
+
+		return
+	}
+
+	_, err = ro.storage.SaveUser(storage.User{WorkspaceID: workspace.ID, Name: req.Name, PasswordHash: string(hash)})
+	if errors.Is(err, storage.ErrUserAlreadyExists) {
+		log.Info("user already exists", slog.String("name", req.Name))
+		render.JSON(w, r, resp.Error("user already exists"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to save user", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to save user"))
+
+		return
+	}
+
+	log.Info("user successfully saved", slog.String("name", req.Name), slog.Int64("workspace_id", workspace.ID))
+	render.JSON(w, r, UserResponse{
+		Response: resp.OK(),
+		User:     &User{Name: req.Name},
+	})
+}
+
+func (ro *router) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationListUsers),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	workspace, ok := ro.workspaceParam(w, r, log)
+	if !ok {
+		return
+	}
+
+	users, err := ro.storage.ListUsers(workspace.ID)
+	if err != nil {
+		log.Error("failed to list users", slog.Int64("workspace_id", workspace.ID), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	result := make([]User, 0, len(users))
+	for _, user := range users {
+		result = append(result, User{Name: user.Name})
+	}
+
+	render.JSON(w, r, UsersResponse{
+		Response: resp.OK(),
+		Users:    result,
+	})
+}
+
+func (ro *router) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	log := ro.log.With(
+		slog.String("operation", handlersOperationDeleteUser),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	workspace, ok := ro.workspaceParam(w, r, log)
+	if !ok {
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	err := ro.storage.DeleteUser(workspace.ID, name)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		log.Info("user not found", slog.String("name", name))
+		render.JSON(w, r, resp.Error("user not found"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to delete user", slog.String("name", name), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return
+	}
+
+	ro.credentials.Invalidate(name)
+
+	log.Info("user successfully deleted", slog.String("name", name), slog.Int64("workspace_id", workspace.ID))
+	render.JSON(w, r, resp.OK())
+}
+
+// workspaceParam returns the workspace with the id in the path,
+// it writes an error response and returns false if the workspace is not found
+func (ro *router) workspaceParam(w http.ResponseWriter, r *http.Request, log *slog.Logger) (storage.Workspace, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Info("invalid workspace id", slog.String("id", chi.URLParam(r, "id")))
+		render.JSON(w, r, resp.Error("invalid request: workspace id must be a number"))
+
+		return storage.Workspace{}, false
+	}
+
+	workspace, err := ro.storage.GetWorkspace(id)
+	if errors.Is(err, storage.ErrWorkspaceNotFound) {
+		log.Info("workspace not found", slog.Int64("id", id))
+		render.JSON(w, r, resp.Error("workspace not found"))
+
+		return storage.Workspace{}, false
+	}
+
+	if err != nil {
+		log.Error("failed to get workspace", slog.Int64("id", id), slo.Err(err))
+		render.JSON(w, r, resp.Error("internal error"))
+
+		return storage.Workspace{}, false
+	}
+
+	return workspace, true
+}
+
+// decodeWorkspace decodes and validates a workspace from the request body,
+// it writes an error response and returns false if the workspace is not valid
+func decodeWorkspace(w http.ResponseWriter, r *http.Request, log *slog.Logger, req *Workspace) bool {
+	err := render.DecodeJSON(r.Body, req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		render.JSON(w, r, resp.Error("empty request"))
+
+		return false
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", slo.Err(err))
+		render.JSON(w, r, resp.Error("failed to decode request"))
+
+		return false
+	}
+
+	err = validator.New().Struct(req)
+	if err != nil {
+		validateErr := err.(validator.ValidationErrors)
+		log.Error("invalid request", slo.Err(err))
+		render.JSON(w, r, resp.ValidationError(validateErr))
+
+		return false
+	}
+
+	return true
+}
+
+func (ws Workspace) toStorage() storage.Workspace {
+	return storage.Workspace{
+		ID:          ws.ID,
+		Name:        ws.Name,
+		MaxLinks:    ws.MaxLinks,
+		MaxRequests: ws.MaxRequests,
+		PreviewAll:  ws.PreviewAll,
+	}
+}
+
+func workspaceFromStorage(ws storage.Workspace) Workspace {
+	return Workspace{
+		ID:          ws.ID,
+		Name:        ws.Name,
+		MaxLinks:    ws.MaxLinks,
+		MaxRequests: ws.MaxRequests,
+		PreviewAll:  ws.PreviewAll,
+	}
+}
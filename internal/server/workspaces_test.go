@@ -0,0 +1,563 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"shorty/internal/config"
+	"shorty/internal/server/mocks"
+	"shorty/internal/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkspaceHandlers(t *testing.T) {
+	marketing := storage.Workspace{ID: 7, Name: "marketing", MaxLinks: 100}
+
+	tests := map[string]struct {
+		method   string
+		path     string
+		input    string
+		wantErr  error
+		expected string
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Save: success": {
+			method:   http.MethodPost,
+			path:     "/v1/workspaces",
+			input:    `{"name": "marketing", "max_links": 100}`,
+			expected: `{"status":"ok","workspace":{"id":7,"name":"marketing","max_links":100}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveWorkspace(storage.Workspace{Name: "marketing", MaxLinks: 100}).Return(int64(7), nil)
+			},
+		},
+		"Save: negative quota": {
+			method:  http.MethodPost,
+			path:    "/v1/workspaces",
+			input:   `{"name": "marketing", "max_requests": -1}`,
+			wantErr: errors.New("\"MaxRequests\" field is not valid"),
+		},
+		"Save: already exists": {
+			method:  http.MethodPost,
+			path:    "/v1/workspaces",
+			input:   `{"name": "marketing"}`,
+			wantErr: errors.New("workspace already exists"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().SaveWorkspace(gomock.Any()).Return(int64(0), storage.ErrWorkspaceAlreadyExists)
+			},
+		},
+		"Get: success": {
+			method:   http.MethodGet,
+			path:     "/v1/workspaces/7",
+			expected: `{"status":"ok","workspace":{"id":7,"name":"marketing","max_links":100}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetWorkspace(int64(7)).Return(marketing, nil)
+			},
+		},
+		"Get: invalid id": {
+			method:  http.MethodGet,
+			path:    "/v1/workspaces/marketing",
+			wantErr: errors.New("invalid request: workspace id must be a number"),
+		},
+		"Get: not found": {
+			method:  http.MethodGet,
+			path:    "/v1/workspaces/8",
+			wantErr: errors.New("workspace not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetWorkspace(int64(8)).Return(storage.Workspace{}, storage.ErrWorkspaceNotFound)
+			},
+		},
+		"List: success": {
+			method:   http.MethodGet,
+			path:     "/v1/workspaces",
+			expected: `{"status":"ok","workspaces":[{"id":7,"name":"marketing","max_links":100}]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ListWorkspaces().Return([]storage.Workspace{marketing}, nil)
+			},
+		},
+		"Update: success": {
+			method:   http.MethodPut,
+			path:     "/v1/workspaces/7",
+			input:    `{"name": "marketing", "max_requests": 1000, "preview_all": true}`,
+			expected: `{"status":"ok","workspace":{"id":7,"name":"marketing","max_requests":1000,"preview_all":true}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().UpdateWorkspace(storage.Workspace{ID: 7, Name: "marketing", MaxRequests: 1000, PreviewAll: true}).Return(nil)
+			},
+		},
+		"Update: not found": {
+			method:  http.MethodPut,
+			path:    "/v1/workspaces/8",
+			input:   `{"name": "sales"}`,
+			wantErr: errors.New("workspace not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().UpdateWorkspace(gomock.Any()).Return(storage.ErrWorkspaceNotFound)
+			},
+		},
+		"Save user: success": {
+			method:   http.MethodPost,
+			path:     "/v1/workspaces/7/users",
+			input:    `{"name": "alice", "password": "correct horse"}`,
+			expected: `{"status":"ok","user":{"name":"alice"}}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetWorkspace(int64(7)).Return(marketing, nil)
+				mockUrlProvider.EXPECT().SaveUser(gomock.Cond(func(user storage.User) bool {
+					return user.WorkspaceID == 7 && user.Name == "alice" &&
+						bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("correct horse")) == nil
+				})).Return(int64(1), nil)
+			},
+		},
+		"Save user: short password": {
+			method:  http.MethodPost,
+			path:    "/v1/workspaces/7/users",
+			input:   `{"name": "alice", "password": "short"}`,
+			wantErr: errors.New("\"Password\" field is not valid"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetWorkspace(int64(7)).Return(marketing, nil)
+			},
+		},
+		"Save user: already exists": {
+			method:  http.MethodPost,
+			path:    "/v1/workspaces/7/users",
+			input:   `{"name": "alice", "password": "correct horse"}`,
+			wantErr: errors.New("user already exists"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetWorkspace(int64(7)).Return(marketing, nil)
+				mockUrlProvider.EXPECT().SaveUser(gomock.Any()).Return(int64(0), storage.ErrUserAlreadyExists)
+			},
+		},
+		"Save user: unknown workspace": {
+			method:  http.MethodPost,
+			path:    "/v1/workspaces/8/users",
+			input:   `{"name": "alice", "password": "correct horse"}`,
+			wantErr: errors.New("workspace not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetWorkspace(int64(8)).Return(storage.Workspace{}, storage.ErrWorkspaceNotFound)
+			},
+		},
+		"List users: success": {
+			method:   http.MethodGet,
+			path:     "/v1/workspaces/7/users",
+			expected: `{"status":"ok","users":[{"name":"alice"}]}`,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetWorkspace(int64(7)).Return(marketing, nil)
+				mockUrlProvider.EXPECT().ListUsers(int64(7)).Return([]storage.User{{ID: 1, WorkspaceID: 7, Name: "alice", PasswordHash: "hash"}}, nil)
+			},
+		},
+		"Delete user: not found": {
+			method:  http.MethodDelete,
+			path:    "/v1/workspaces/7/users/bob",
+			wantErr: errors.New("user not found"),
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetWorkspace(int64(7)).Return(marketing, nil)
+				mockUrlProvider.EXPECT().DeleteUser(int64(7), "bob").Return(storage.ErrUserNotFound)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.input)))
+			req.SetBasicAuth("", "")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if tc.wantErr != nil {
+				var response WorkspaceResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.wantErr.Error(), response.Error)
+			} else {
+				assert.JSONEq(t, tc.expected, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	alice := storage.User{ID: 1, WorkspaceID: 7, Name: "alice", PasswordHash: string(hash)}
+
+	tests := map[string]struct {
+		path     string
+		user     string
+		password string
+		noAuth   bool
+		wantCode int
+		prepare  func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Configured user": {
+			path:     "/v1/domains",
+			user:     "admin",
+			password: "secret",
+			wantCode: http.StatusOK,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ListDomains(storage.DefaultWorkspace).Return(nil, nil)
+			},
+		},
+		"Workspace user": {
+			path:     "/v1/domains",
+			user:     "alice",
+			password: "correct horse",
+			wantCode: http.StatusOK,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetUser("alice").Return(alice, nil)
+				mockUrlProvider.EXPECT().GetWorkspace(int64(7)).Return(storage.Workspace{ID: 7, Name: "marketing"}, nil)
+				mockUrlProvider.EXPECT().ListDomains(int64(7)).Return(nil, nil)
+			},
+		},
+		"Wrong password": {
+			path:     "/v1/domains",
+			user:     "alice",
+			password: "secret",
+			wantCode: http.StatusUnauthorized,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetUser("alice").Return(alice, nil)
+			},
+		},
+		"Unknown user": {
+			path:     "/v1/domains",
+			user:     "bob",
+			password: "secret",
+			wantCode: http.StatusUnauthorized,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetUser("bob").Return(storage.User{}, storage.ErrUserNotFound)
+			},
+		},
+		"No credentials": {
+			path:     "/v1/domains",
+			noAuth:   true,
+			wantCode: http.StatusUnauthorized,
+		},
+		"Workspace user manages workspaces": {
+			path:     "/v1/workspaces",
+			user:     "alice",
+			password: "correct horse",
+			wantCode: http.StatusForbidden,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetUser("alice").Return(alice, nil)
+				mockUrlProvider.EXPECT().GetWorkspace(int64(7)).Return(storage.Workspace{ID: 7, Name: "marketing"}, nil)
+			},
+		},
+		"Within request quota": {
+			path:     "/v1/domains",
+			user:     "alice",
+			password: "correct horse",
+			wantCode: http.StatusOK,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetUser("alice").Return(alice, nil)
+				mockUrlProvider.EXPECT().GetWorkspace(int64(7)).Return(storage.Workspace{ID: 7, Name: "marketing", MaxRequests: 10}, nil)
+				mockUrlProvider.EXPECT().CountRequest(int64(7), gomock.Any()).Return(int64(10), nil)
+				mockUrlProvider.EXPECT().ListDomains(int64(7)).Return(nil, nil)
+			},
+		},
+		"Request quota exceeded": {
+			path:     "/v1/domains",
+			user:     "alice",
+			password: "correct horse",
+			wantCode: http.StatusTooManyRequests,
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().GetUser("alice").Return(alice, nil)
+				mockUrlProvider.EXPECT().GetWorkspace(int64(7)).Return(storage.Workspace{ID: 7, Name: "marketing", MaxRequests: 10}, nil)
+				mockUrlProvider.EXPECT().CountRequest(int64(7), gomock.Any()).Return(int64(11), nil)
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			if tc.prepare != nil {
+				tc.prepare(mockStorage)
+			}
+
+			cfg := config.Config{HTTPServer: config.HTTPServer{User: "admin", Password: "secret"}}
+			r := SetupRouter(mockStorage, cfg, slog.Default())
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if !tc.noAuth {
+				req.SetBasicAuth(tc.user, tc.password)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tc.wantCode, w.Code)
+
+			if tc.wantCode == http.StatusTooManyRequests {
+				assert.NotEmpty(t, w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestAuthenticate_CachedCredentials(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	alice := storage.User{ID: 1, WorkspaceID: 7, Name: "alice", PasswordHash: string(hash)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockUrlProvider(ctrl)
+	//the user is looked up for the first request and for the wrong password, the workspace once,
+	//every authenticated request is counted
+	mockStorage.EXPECT().GetUser("alice").Return(alice, nil).Times(2)
+	mockStorage.EXPECT().GetWorkspace(int64(7)).Return(storage.Workspace{ID: 7, Name: "marketing", MaxRequests: 10}, nil)
+	mockStorage.EXPECT().CountRequest(int64(7), gomock.Any()).Return(int64(1), nil).Times(2)
+	mockStorage.EXPECT().ListDomains(int64(7)).Return(nil, nil).Times(2)
+
+	cfg := config.Config{HTTPServer: config.HTTPServer{User: "admin", Password: "secret", CredentialsTTL: time.Minute}}
+	r := SetupRouter(mockStorage, cfg, slog.Default())
+
+	for _, password := range []string{"correct horse", "correct horse", "wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/domains", nil)
+		req.SetBasicAuth("alice", password)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if password == "wrong" {
+			assert.Equal(t, http.StatusUnauthorized, w.Code, "other credentials are verified")
+		} else {
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+	}
+}
+
+func TestAuthenticate_DeletedUser(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	alice := storage.User{ID: 1, WorkspaceID: 7, Name: "alice", PasswordHash: string(hash)}
+	marketing := storage.Workspace{ID: 7, Name: "marketing"}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockUrlProvider(ctrl)
+	gomock.InOrder(
+		mockStorage.EXPECT().GetUser("alice").Return(alice, nil),
+		mockStorage.EXPECT().GetUser("alice").Return(storage.User{}, storage.ErrUserNotFound),
+	)
+	mockStorage.EXPECT().GetWorkspace(int64(7)).Return(marketing, nil).Times(2)
+	mockStorage.EXPECT().ListDomains(int64(7)).Return(nil, nil)
+	mockStorage.EXPECT().DeleteUser(int64(7), "alice").Return(nil)
+
+	cfg := config.Config{HTTPServer: config.HTTPServer{User: "admin", Password: "secret", CredentialsTTL: time.Minute}}
+	r := SetupRouter(mockStorage, cfg, slog.Default())
+
+	serve := func(method string, path string, user string, password string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.SetBasicAuth(user, password)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/domains", "alice", "correct horse"))
+	require.Equal(t, http.StatusOK, serve(http.MethodDelete, "/v1/workspaces/7/users/alice", "admin", "secret"))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/v1/domains", "alice", "correct horse"))
+}
+
+func TestAuthenticate_UpdatedWorkspace(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	alice := storage.User{ID: 1, WorkspaceID: 7, Name: "alice", PasswordHash: string(hash)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockUrlProvider(ctrl)
+	mockStorage.EXPECT().GetUser("alice").Return(alice, nil).Times(2)
+	gomock.InOrder(
+		mockStorage.EXPECT().GetWorkspace(int64(7)).Return(storage.Workspace{ID: 7, Name: "marketing"}, nil),
+		mockStorage.EXPECT().GetWorkspace(int64(7)).Return(storage.Workspace{ID: 7, Name: "marketing", MaxRequests: 10}, nil),
+	)
+	mockStorage.EXPECT().UpdateWorkspace(storage.Workspace{ID: 7, Name: "marketing", MaxRequests: 10}).Return(nil)
+	mockStorage.EXPECT().ListDomains(int64(7)).Return(nil, nil)
+	//the new quota applies to the next request
+	mockStorage.EXPECT().CountRequest(int64(7), gomock.Any()).Return(int64(11), nil)
+
+	cfg := config.Config{HTTPServer: config.HTTPServer{User: "admin", Password: "secret", CredentialsTTL: time.Minute}}
+	r := SetupRouter(mockStorage, cfg, slog.Default())
+
+	serve := func(method string, path string, body string, user string, password string) int {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.SetBasicAuth(user, password)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, "/v1/domains", "", "alice", "correct horse"))
+	require.Equal(t, http.StatusOK, serve(http.MethodPut, "/v1/workspaces/7", `{"name": "marketing", "max_requests": 10}`, "admin", "secret"))
+	assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodGet, "/v1/domains", "", "alice", "correct horse"))
+}
+
+func TestSaveHandler_LinkQuota(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockUrlProvider(ctrl)
+	expectLinkCounts(mockStorage)
+	mockStorage.EXPECT().SaveLink(gomock.Any()).Return(int64(0), storage.ErrLinkQuotaExceeded)
+
+	r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+	req := httptest.NewRequest(http.MethodPost, "/v1/url", bytes.NewReader([]byte(`{"url": "https://example.com"}`)))
+	req.SetBasicAuth("", "")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	var response Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "workspace link quota exceeded", response.Error)
+}
+
+func TestRedirectHandler_WorkspacePreview(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	//links of a custom domain are served from the workspace of the domain
+	mockStorage := mocks.NewMockUrlProvider(ctrl)
+	mockStorage.EXPECT().ResolveDomain("go.brand.com").Return(storage.Domain{ID: 1, WorkspaceID: 7, Host: "go.brand.com"}, nil)
+	mockStorage.EXPECT().GetLink(int64(7), "go.brand.com", "promo").
+		Return(storage.Link{WorkspaceID: 7, Domain: "go.brand.com", Alias: "promo", URL: "https://example.com/brand"}, nil)
+	mockStorage.EXPECT().GetWorkspace(int64(7)).Return(storage.Workspace{ID: 7, Name: "marketing", PreviewAll: true}, nil)
+
+	r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+	req := httptest.NewRequest(http.MethodGet, "/v1/promo", nil)
+	req.Host = "go.brand.com"
+	req.SetBasicAuth("", "")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "https://example.com/brand")
+}
+
+func TestRedirectHandler_Visitors(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("letmein"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		method      string
+		host        string
+		path        string
+		body        string
+		userAgent   string
+		credentials bool
+		wantCode    int
+		location    string
+		prepare     func(mockUrlProvider *mocks.MockUrlProvider)
+	}{
+		"Crawler on the default domain": {
+			method:    http.MethodGet,
+			host:      "sho.rt",
+			path:      "/v1/promo",
+			userAgent: "Googlebot/2.1 (+http://www.google.com/bot.html)",
+			wantCode:  http.StatusFound,
+			location:  "https://example.com/default",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("sho.rt").Return(storage.Domain{}, storage.ErrDomainNotFound)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "promo").Return(storage.Link{Alias: "promo", URL: "https://example.com/default"}, nil)
+			},
+		},
+		"Custom domain of a workspace": {
+			method:   http.MethodGet,
+			host:     "go.brand.com",
+			path:     "/v1/promo/spring",
+			wantCode: http.StatusFound,
+			location: "https://example.com/brand/spring",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("go.brand.com").Return(storage.Domain{ID: 1, WorkspaceID: 7, Host: "go.brand.com"}, nil)
+				mockUrlProvider.EXPECT().GetLink(int64(7), "go.brand.com", "promo").
+					Return(storage.Link{WorkspaceID: 7, Domain: "go.brand.com", Alias: "promo", URL: "https://example.com/brand", AppendPath: true}, nil)
+				mockUrlProvider.EXPECT().GetWorkspace(int64(7)).Return(storage.Workspace{ID: 7, Name: "marketing"}, nil).AnyTimes()
+			},
+		},
+		"Password form": {
+			method:   http.MethodPost,
+			host:     "sho.rt",
+			path:     "/v1/secret",
+			body:     "password=letmein",
+			wantCode: http.StatusFound,
+			location: "https://example.com/secret",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("sho.rt").Return(storage.Domain{}, storage.ErrDomainNotFound)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "secret").
+					Return(storage.Link{Alias: "secret", URL: "https://example.com/secret", PasswordHash: string(hash)}, nil)
+			},
+		},
+		"Workspace user on the default domain": {
+			method:      http.MethodGet,
+			host:        "sho.rt",
+			path:        "/v1/promo",
+			credentials: true,
+			wantCode:    http.StatusFound,
+			location:    "https://example.com/default",
+			prepare: func(mockUrlProvider *mocks.MockUrlProvider) {
+				mockUrlProvider.EXPECT().ResolveDomain("sho.rt").Return(storage.Domain{}, storage.ErrDomainNotFound)
+				mockUrlProvider.EXPECT().GetLink(storage.DefaultWorkspace, "", "promo").Return(storage.Link{Alias: "promo", URL: "https://example.com/default"}, nil)
+			},
+		},
+		"Api without credentials": {
+			method:   http.MethodGet,
+			host:     "sho.rt",
+			path:     "/v1/url",
+			wantCode: http.StatusUnauthorized,
+			prepare:  func(mockUrlProvider *mocks.MockUrlProvider) {},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mocks.NewMockUrlProvider(ctrl)
+			tc.prepare(mockStorage)
+
+			r := SetupRouter(mockStorage, config.Config{}, slog.Default())
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Host = tc.host
+			req.Header.Set("User-Agent", tc.userAgent)
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			//the visitor routes do not check the credentials
+			if tc.credentials {
+				req.SetBasicAuth("alice", "secret")
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tc.wantCode, w.Code)
+			assert.Equal(t, tc.location, w.Header().Get("Location"))
+		})
+	}
+}
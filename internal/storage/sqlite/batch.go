@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"fmt"
+	"shorty/internal/storage"
+)
+
+// SaveLinks inserts links in a single transaction and reports the outcome of every link.
+// In atomic mode the first failed link rolls the whole batch back and ErrBatchAborted is returned,
+// the results then hold the errors of the links processed so far
+func (s *Storage) SaveLinks(links []storage.Link, atomic bool) ([]storage.SaveResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: begin transaction: %w", sqliteOperationBatch, err)
+	}
+	defer tx.Rollback()
+
+	results := make([]storage.SaveResult, len(links))
+	for i, link := range links {
+		//a savepoint per link undoes partially inserted rules and variants of a failed link
+		_, err = tx.Exec(`SAVEPOINT link`)
+		if err != nil {
+			return nil, fmt.Errorf("%s: create savepoint: %w", sqliteOperationBatch, err)
+		}
+
+		id, err := saveLink(tx, link)
+		if err != nil {
+			results[i].Err = err
+			if atomic {
+				return results[:i+1], fmt.Errorf("%s: link %d: %w", sqliteOperationBatch, i, storage.ErrBatchAborted)
+			}
+
+			_, err = tx.Exec(`ROLLBACK TO link`)
+			if err != nil {
+				return nil, fmt.Errorf("%s: rollback to savepoint: %w", sqliteOperationBatch, err)
+			}
+		}
+		results[i].ID = id
+
+		_, err = tx.Exec(`RELEASE link`)
+		if err != nil {
+			return nil, fmt.Errorf("%s: release savepoint: %w", sqliteOperationBatch, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: commit transaction: %w", sqliteOperationBatch, err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestSaveLinks(t *testing.T) {
+	s := newTestStorage(t)
+
+	_, err := s.SaveLink(storage.Link{Alias: "taken", URL: "https://example.com/taken"})
+	require.NoError(t, err)
+
+	links := []storage.Link{
+		{Alias: "first", URL: "https://example.com/1", Rules: []storage.Rule{{OS: "ios", Target: "https://apps.apple.com"}}},
+		{Alias: "taken", URL: "https://example.com/2"},
+		{Alias: "third", URL: "https://example.com/3"},
+		{Alias: "first", URL: "https://example.com/4"},
+	}
+
+	t.Run("Atomic", func(t *testing.T) {
+		results, err := s.SaveLinks(links, true)
+		require.ErrorIs(t, err, storage.ErrBatchAborted)
+		require.Len(t, results, 2)
+		assert.NoError(t, results[0].Err)
+		assert.ErrorIs(t, results[1].Err, storage.ErrURLAlreadyExists)
+
+		_, err = s.GetLink(storage.DefaultWorkspace, "", "first")
+		assert.ErrorIs(t, err, storage.ErrURLNotFound)
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		results, err := s.SaveLinks(links, false)
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+		assert.NoError(t, results[0].Err)
+		assert.ErrorIs(t, results[1].Err, storage.ErrURLAlreadyExists)
+		assert.NoError(t, results[2].Err)
+		assert.ErrorIs(t, results[3].Err, storage.ErrURLAlreadyExists)
+
+		first, err := s.GetLink(storage.DefaultWorkspace, "", "first")
+		require.NoError(t, err)
+		assert.Equal(t, results[0].ID, first.ID)
+		assert.Equal(t, "https://example.com/1", first.URL)
+		assert.Len(t, first.Rules, 1)
+
+		taken, err := s.GetLink(storage.DefaultWorkspace, "", "taken")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/taken", taken.URL)
+
+		_, err = s.GetLink(storage.DefaultWorkspace, "", "third")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"fmt"
+)
+
+// CountCountryClick records a redirect of a visitor from the country
+func (s *Storage) CountCountryClick(linkID int64, country string) error {
+	_, err := s.db.Exec(`
+	INSERT INTO country_click(url_id, country, clicks) VALUES(?, ?, 1)
+	ON CONFLICT(url_id, country) DO UPDATE SET clicks = clicks + 1`, linkID, country)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationCountry, err)
+	}
+
+	return nil
+}
+
+// countryClicks returns the redirects per visitor country by link id
+func (s *Storage) countryClicks(urlIDs []int64) (map[int64]map[string]int64, error) {
+	in, args := idList(urlIDs)
+	rows, err := s.db.Query(`SELECT url_id, country, clicks FROM country_click WHERE url_id IN `+in, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query country clicks: %w", err)
+	}
+	defer rows.Close()
+
+	clicks := make(map[int64]map[string]int64)
+	for rows.Next() {
+		var (
+			urlID   int64
+			country string
+			count   int64
+		)
+		err = rows.Scan(&urlID, &country, &count)
+		if err != nil {
+			return nil, fmt.Errorf("scan country clicks: %w", err)
+		}
+
+		if clicks[urlID] == nil {
+			clicks[urlID] = make(map[string]int64)
+		}
+		clicks[urlID][country] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate country clicks: %w", err)
+	}
+
+	return clicks, nil
+}
@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"shorty/internal/storage"
+	"time"
+)
+
+func (s *Storage) SaveDomain(domain storage.Domain) (int64, error) {
+	statement, err := s.db.Prepare(`INSERT INTO domain(workspace_id, host, default_url, created_at, updated_at) VALUES(?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: prepare statement: %w", sqliteOperationSaveDomain, err)
+	}
+
+	timestamp := time.Now().Unix()
+	result, err := statement.Exec(domain.WorkspaceID, domain.Host, domain.DefaultURL, timestamp, timestamp)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", sqliteOperationSaveDomain, storage.ErrDomainAlreadyExists)
+		}
+		return 0, fmt.Errorf("%s: %w", sqliteOperationSaveDomain, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id %w", sqliteOperationSaveDomain, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetDomain(workspaceID int64, host string) (storage.Domain, error) {
+	var domain storage.Domain
+
+	statement, err := s.db.Prepare(`SELECT id, workspace_id, host, default_url FROM domain WHERE workspace_id = ? AND host = ?`)
+	if err != nil {
+		return storage.Domain{}, fmt.Errorf("%s: prepare statement: %w", sqliteOperationGetDomain, err)
+	}
+
+	err = statement.QueryRow(workspaceID, host).Scan(&domain.ID, &domain.WorkspaceID, &domain.Host, &domain.DefaultURL)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.Domain{}, storage.ErrDomainNotFound
+		}
+		return storage.Domain{}, fmt.Errorf("%s: execute statement %w", sqliteOperationGetDomain, err)
+	}
+
+	return domain, nil
+}
+
+// ResolveDomain returns the domain with the host in any workspace, it serves requests sent to the domain
+func (s *Storage) ResolveDomain(host string) (storage.Domain, error) {
+	var domain storage.Domain
+
+	statement, err := s.db.Prepare(`SELECT id, workspace_id, host, default_url FROM domain WHERE host = ?`)
+	if err != nil {
+		return storage.Domain{}, fmt.Errorf("%s: prepare statement: %w", sqliteOperationResolveDomain, err)
+	}
+
+	err = statement.QueryRow(host).Scan(&domain.ID, &domain.WorkspaceID, &domain.Host, &domain.DefaultURL)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.Domain{}, storage.ErrDomainNotFound
+		}
+		return storage.Domain{}, fmt.Errorf("%s: execute statement %w", sqliteOperationResolveDomain, err)
+	}
+
+	return domain, nil
+}
+
+func (s *Storage) ListDomains(workspaceID int64) ([]storage.Domain, error) {
+	rows, err := s.db.Query(`SELECT id, workspace_id, host, default_url FROM domain WHERE workspace_id = ? ORDER BY host`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement %w", sqliteOperationListDomains, err)
+	}
+	defer rows.Close()
+
+	var domains []storage.Domain
+	for rows.Next() {
+		var domain storage.Domain
+		err = rows.Scan(&domain.ID, &domain.WorkspaceID, &domain.Host, &domain.DefaultURL)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row %w", sqliteOperationListDomains, err)
+		}
+		domains = append(domains, domain)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows %w", sqliteOperationListDomains, err)
+	}
+
+	return domains, nil
+}
+
+func (s *Storage) UpdateDomain(domain storage.Domain) error {
+	statement, err := s.db.Prepare(`UPDATE domain SET default_url = ?, updated_at = ? WHERE workspace_id = ? AND host = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", sqliteOperationUpdateDomain, err)
+	}
+
+	result, err := statement.Exec(domain.DefaultURL, time.Now().Unix(), domain.WorkspaceID, domain.Host)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationUpdateDomain, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationUpdateDomain, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationUpdateDomain, storage.ErrDomainNotFound)
+	}
+
+	return nil
+}
+
+// DeleteDomain removes a domain, it returns storage.ErrDomainInUse if links are still saved on it
+func (s *Storage) DeleteDomain(workspaceID int64, host string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", sqliteOperationDeleteDomain, err)
+	}
+	defer tx.Rollback()
+
+	var links int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM url WHERE workspace_id = ? AND domain = ?`, workspaceID, host).Scan(&links)
+	if err != nil {
+		return fmt.Errorf("%s: count links %w", sqliteOperationDeleteDomain, err)
+	}
+
+	if links > 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationDeleteDomain, storage.ErrDomainInUse)
+	}
+
+	result, err := tx.Exec(`DELETE FROM domain WHERE workspace_id = ? AND host = ?`, workspaceID, host)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationDeleteDomain, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationDeleteDomain, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationDeleteDomain, storage.ErrDomainNotFound)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", sqliteOperationDeleteDomain, err)
+	}
+
+	return nil
+}
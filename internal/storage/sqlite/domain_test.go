@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestDomains(t *testing.T) {
+	s := newTestStorage(t)
+
+	_, err := s.SaveDomain(storage.Domain{Host: "go.brand.com", DefaultURL: "https://brand.com"})
+	require.NoError(t, err)
+	_, err = s.SaveDomain(storage.Domain{Host: "go.brand.com"})
+	assert.ErrorIs(t, err, storage.ErrDomainAlreadyExists)
+
+	require.NoError(t, s.UpdateDomain(storage.Domain{Host: "go.brand.com", DefaultURL: "https://brand.com/home"}))
+	assert.ErrorIs(t, s.UpdateDomain(storage.Domain{Host: "unknown.com"}), storage.ErrDomainNotFound)
+
+	domain, err := s.GetDomain(storage.DefaultWorkspace, "go.brand.com")
+	require.NoError(t, err)
+	assert.Equal(t, "https://brand.com/home", domain.DefaultURL)
+
+	_, err = s.GetDomain(storage.DefaultWorkspace, "unknown.com")
+	assert.ErrorIs(t, err, storage.ErrDomainNotFound)
+
+	_, err = s.SaveDomain(storage.Domain{Host: "a.brand.com"})
+	require.NoError(t, err)
+	domains, err := s.ListDomains(storage.DefaultWorkspace)
+	require.NoError(t, err)
+	require.Len(t, domains, 2)
+	assert.Equal(t, "a.brand.com", domains[0].Host)
+
+	_, err = s.SaveLink(storage.Link{Domain: "go.brand.com", Alias: "promo", URL: "https://example.com"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.DeleteDomain(storage.DefaultWorkspace, "go.brand.com"), storage.ErrDomainInUse)
+
+	require.NoError(t, s.DeleteURL(storage.DefaultWorkspace, "go.brand.com", "promo"))
+	require.NoError(t, s.DeleteDomain(storage.DefaultWorkspace, "go.brand.com"))
+	assert.ErrorIs(t, s.DeleteDomain(storage.DefaultWorkspace, "go.brand.com"), storage.ErrDomainNotFound)
+}
+
+func TestSaveLink_AliasesPerDomain(t *testing.T) {
+	s := newTestStorage(t)
+
+	_, err := s.SaveLink(storage.Link{Alias: "promo", URL: "https://example.com/default"})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Domain: "go.brand.com", Alias: "promo", URL: "https://example.com/brand"})
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Domain: "go.brand.com", Alias: "promo", URL: "https://example.com/other"})
+	assert.ErrorIs(t, err, storage.ErrURLAlreadyExists)
+
+	link, err := s.GetLink(storage.DefaultWorkspace, "", "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/default", link.URL)
+
+	link, err = s.GetLink(storage.DefaultWorkspace, "go.brand.com", "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "go.brand.com", link.Domain)
+	assert.Equal(t, "https://example.com/brand", link.URL)
+
+	_, err = s.GetLink(storage.DefaultWorkspace, "other.com", "promo")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestMigrate_ScopesLegacyAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+	CREATE TABLE url(
+		id INTEGER PRIMARY KEY,
+		alias TEXT NOT NULL UNIQUE,
+		url TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+	    updated_at INTEGER NOT NULL
+		);
+	INSERT INTO url(alias, url, created_at, updated_at) VALUES('promo', 'https://example.com', 0, 0);`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	s, err := New(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.db.Close() })
+
+	link, err := s.GetLink(storage.DefaultWorkspace, "", "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", link.URL)
+
+	_, err = s.SaveLink(storage.Link{Domain: "go.brand.com", Alias: "promo", URL: "https://example.com/brand"})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "promo", URL: "https://example.com/again"})
+	assert.ErrorIs(t, err, storage.ErrURLAlreadyExists)
+
+	//a migrated database is not rebuilt again
+	require.NoError(t, migrate(s.db))
+	link, err = s.GetLink(storage.DefaultWorkspace, "go.brand.com", "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/brand", link.URL)
+}
@@ -0,0 +1,244 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"shorty/internal/storage"
+	"time"
+)
+
+// SaveFolder creates a folder, it returns storage.ErrFolderNotFound if the parent is not a folder of the workspace
+func (s *Storage) SaveFolder(folder storage.Folder) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", sqliteOperationSaveFolder, err)
+	}
+	defer tx.Rollback()
+
+	err = checkFolder(tx, folder.WorkspaceID, folder.ParentID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", sqliteOperationSaveFolder, err)
+	}
+
+	timestamp := time.Now().Unix()
+	result, err := tx.Exec(`INSERT INTO folder(workspace_id, parent_id, name, created_at, updated_at) VALUES(?, ?, ?, ?, ?)`,
+		folder.WorkspaceID, nullID(folder.ParentID), folder.Name, timestamp, timestamp)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", sqliteOperationSaveFolder, storage.ErrFolderAlreadyExists)
+		}
+		return 0, fmt.Errorf("%s: %w", sqliteOperationSaveFolder, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id %w", sqliteOperationSaveFolder, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", sqliteOperationSaveFolder, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetFolder(workspaceID int64, id int64) (storage.Folder, error) {
+	var (
+		folder   storage.Folder
+		parentID sql.NullInt64
+	)
+
+	err := s.db.QueryRow(`SELECT id, workspace_id, parent_id, name FROM folder WHERE workspace_id = ? AND id = ?`, workspaceID, id).
+		Scan(&folder.ID, &folder.WorkspaceID, &parentID, &folder.Name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.Folder{}, storage.ErrFolderNotFound
+		}
+		return storage.Folder{}, fmt.Errorf("%s: execute statement %w", sqliteOperationGetFolder, err)
+	}
+	folder.ParentID = parentID.Int64
+
+	return folder, nil
+}
+
+// ListFolders returns all folders of the workspace in the order of creation, parents come before their subfolders
+// unless a folder was moved
+func (s *Storage) ListFolders(workspaceID int64) ([]storage.Folder, error) {
+	rows, err := s.db.Query(`SELECT id, workspace_id, parent_id, name FROM folder WHERE workspace_id = ? ORDER BY id`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement %w", sqliteOperationListFolders, err)
+	}
+	defer rows.Close()
+
+	var folders []storage.Folder
+	for rows.Next() {
+		var (
+			folder   storage.Folder
+			parentID sql.NullInt64
+		)
+		err = rows.Scan(&folder.ID, &folder.WorkspaceID, &parentID, &folder.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row %w", sqliteOperationListFolders, err)
+		}
+		folder.ParentID = parentID.Int64
+		folders = append(folders, folder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows %w", sqliteOperationListFolders, err)
+	}
+
+	return folders, nil
+}
+
+// UpdateFolder renames a folder and moves it under another parent,
+// it returns storage.ErrFolderCycle if the parent is the folder itself or one of its subfolders
+func (s *Storage) UpdateFolder(folder storage.Folder) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", sqliteOperationUpdateFolder, err)
+	}
+	defer tx.Rollback()
+
+	err = checkFolder(tx, folder.WorkspaceID, folder.ParentID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sqliteOperationUpdateFolder, err)
+	}
+
+	if folder.ParentID != 0 {
+		var nested bool
+		err = tx.QueryRow(`
+		WITH RECURSIVE ancestor(id) AS (
+			SELECT ?
+			UNION
+			SELECT f.parent_id FROM folder f JOIN ancestor a ON f.id = a.id WHERE f.parent_id IS NOT NULL
+		)
+		SELECT EXISTS(SELECT 1 FROM ancestor WHERE id = ?)`, folder.ParentID, folder.ID).Scan(&nested)
+		if err != nil {
+			return fmt.Errorf("%s: check ancestors %w", sqliteOperationUpdateFolder, err)
+		}
+
+		if nested {
+			return fmt.Errorf("%s: %w", sqliteOperationUpdateFolder, storage.ErrFolderCycle)
+		}
+	}
+
+	result, err := tx.Exec(`UPDATE folder SET parent_id = ?, name = ?, updated_at = ? WHERE workspace_id = ? AND id = ?`,
+		nullID(folder.ParentID), folder.Name, time.Now().Unix(), folder.WorkspaceID, folder.ID)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return fmt.Errorf("%s: %w", sqliteOperationUpdateFolder, storage.ErrFolderAlreadyExists)
+		}
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationUpdateFolder, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationUpdateFolder, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationUpdateFolder, storage.ErrFolderNotFound)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", sqliteOperationUpdateFolder, err)
+	}
+
+	return nil
+}
+
+// DeleteFolder removes an empty folder, it returns storage.ErrFolderNotEmpty if the folder has links or subfolders
+func (s *Storage) DeleteFolder(workspaceID int64, id int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", sqliteOperationDeleteFolder, err)
+	}
+	defer tx.Rollback()
+
+	err = checkFolder(tx, workspaceID, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sqliteOperationDeleteFolder, err)
+	}
+
+	var used bool
+	err = tx.QueryRow(`
+	SELECT EXISTS(SELECT 1 FROM folder WHERE parent_id = ?) OR EXISTS(SELECT 1 FROM url WHERE folder_id = ?)`, id, id).Scan(&used)
+	if err != nil {
+		return fmt.Errorf("%s: check contents %w", sqliteOperationDeleteFolder, err)
+	}
+
+	if used {
+		return fmt.Errorf("%s: %w", sqliteOperationDeleteFolder, storage.ErrFolderNotEmpty)
+	}
+
+	_, err = tx.Exec(`DELETE FROM folder WHERE workspace_id = ? AND id = ?`, workspaceID, id)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationDeleteFolder, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", sqliteOperationDeleteFolder, err)
+	}
+
+	return nil
+}
+
+// SetFolder files a link in a folder of its workspace, folder id 0 takes the link out of its folder
+func (s *Storage) SetFolder(workspaceID int64, domain string, alias string, folderID int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", sqliteOperationSetFolder, err)
+	}
+	defer tx.Rollback()
+
+	err = checkFolder(tx, workspaceID, folderID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sqliteOperationSetFolder, err)
+	}
+
+	result, err := tx.Exec(`UPDATE url SET folder_id = ?, updated_at = ? WHERE workspace_id = ? AND domain = ? AND alias = ?`,
+		nullID(folderID), time.Now().Unix(), workspaceID, domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationSetFolder, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationSetFolder, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationSetFolder, storage.ErrURLNotFound)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", sqliteOperationSetFolder, err)
+	}
+
+	return nil
+}
+
+// checkFolder returns storage.ErrFolderNotFound if a non-zero folder id is not a folder of the workspace
+func checkFolder(tx *sql.Tx, workspaceID int64, id int64) error {
+	if id == 0 {
+		return nil
+	}
+
+	var exists bool
+	err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM folder WHERE workspace_id = ? AND id = ?)`, workspaceID, id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("check folder: %w", err)
+	}
+
+	if !exists {
+		return storage.ErrFolderNotFound
+	}
+
+	return nil
+}
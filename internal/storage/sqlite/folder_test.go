@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestFolders(t *testing.T) {
+	s := newTestStorage(t)
+
+	projects, err := s.SaveFolder(storage.Folder{Name: "projects"})
+	require.NoError(t, err)
+	acme, err := s.SaveFolder(storage.Folder{ParentID: projects, Name: "acme"})
+	require.NoError(t, err)
+	archive, err := s.SaveFolder(storage.Folder{Name: "archive"})
+	require.NoError(t, err)
+
+	//names are unique among the subfolders of a parent
+	_, err = s.SaveFolder(storage.Folder{Name: "projects"})
+	assert.ErrorIs(t, err, storage.ErrFolderAlreadyExists)
+	_, err = s.SaveFolder(storage.Folder{ParentID: archive, Name: "acme"})
+	require.NoError(t, err)
+	_, err = s.SaveFolder(storage.Folder{ParentID: 100, Name: "lost"})
+	assert.ErrorIs(t, err, storage.ErrFolderNotFound)
+
+	assert.ErrorIs(t, s.UpdateFolder(storage.Folder{ID: projects, ParentID: acme, Name: "projects"}), storage.ErrFolderCycle)
+	assert.ErrorIs(t, s.UpdateFolder(storage.Folder{ID: projects, ParentID: projects, Name: "projects"}), storage.ErrFolderCycle)
+	assert.ErrorIs(t, s.UpdateFolder(storage.Folder{ID: acme, ParentID: archive, Name: "acme"}), storage.ErrFolderAlreadyExists)
+	require.NoError(t, s.UpdateFolder(storage.Folder{ID: acme, ParentID: archive, Name: "acme-2030"}))
+
+	folder, err := s.GetFolder(storage.DefaultWorkspace, acme)
+	require.NoError(t, err)
+	assert.Equal(t, storage.Folder{ID: acme, ParentID: archive, Name: "acme-2030"}, folder)
+
+	folders, err := s.ListFolders(storage.DefaultWorkspace)
+	require.NoError(t, err)
+	assert.Len(t, folders, 4)
+
+	_, err = s.SaveLink(storage.Link{Alias: "promo", URL: "https://example.com", FolderID: acme})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "lost", URL: "https://example.com", FolderID: 100})
+	assert.ErrorIs(t, err, storage.ErrFolderNotFound)
+	_, err = s.SaveLink(storage.Link{Alias: "blog", URL: "https://example.com/blog"})
+	require.NoError(t, err)
+
+	links, err := s.ListLinks(storage.LinkFilter{Limit: 10, FolderID: acme})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "promo", links[0].Alias)
+	assert.Equal(t, acme, links[0].FolderID)
+
+	assert.ErrorIs(t, s.DeleteFolder(storage.DefaultWorkspace, acme), storage.ErrFolderNotEmpty)
+	assert.ErrorIs(t, s.DeleteFolder(storage.DefaultWorkspace, archive), storage.ErrFolderNotEmpty)
+
+	require.NoError(t, s.SetFolder(storage.DefaultWorkspace, "", "promo", 0))
+	assert.ErrorIs(t, s.SetFolder(storage.DefaultWorkspace, "", "promo", 100), storage.ErrFolderNotFound)
+	assert.ErrorIs(t, s.SetFolder(storage.DefaultWorkspace, "", "other", projects), storage.ErrURLNotFound)
+
+	require.NoError(t, s.DeleteFolder(storage.DefaultWorkspace, acme))
+	assert.ErrorIs(t, s.DeleteFolder(storage.DefaultWorkspace, acme), storage.ErrFolderNotFound)
+}
+
+func TestFolders_WorkspaceScope(t *testing.T) {
+	s := newTestStorage(t)
+
+	marketing, err := s.SaveWorkspace(storage.Workspace{Name: "marketing"})
+	require.NoError(t, err)
+
+	projects, err := s.SaveFolder(storage.Folder{Name: "projects"})
+	require.NoError(t, err)
+
+	_, err = s.GetFolder(marketing, projects)
+	assert.ErrorIs(t, err, storage.ErrFolderNotFound)
+	_, err = s.SaveFolder(storage.Folder{WorkspaceID: marketing, ParentID: projects, Name: "acme"})
+	assert.ErrorIs(t, err, storage.ErrFolderNotFound)
+	_, err = s.SaveLink(storage.Link{WorkspaceID: marketing, Alias: "promo", URL: "https://example.com", FolderID: projects})
+	assert.ErrorIs(t, err, storage.ErrFolderNotFound)
+
+	//top level names are scoped by workspace
+	_, err = s.SaveFolder(storage.Folder{WorkspaceID: marketing, Name: "projects"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,18 @@
+package sqlite
+
+import (
+	"fmt"
+	"shorty/internal/storage"
+)
+
+// SaveLinkHealth records the result of the last liveness check of a link
+func (s *Storage) SaveLinkHealth(linkID int64, health storage.Health) error {
+	_, err := s.db.Exec(`
+	UPDATE url SET check_status = ?, check_latency = ?, check_error = ?, checked_at = ? WHERE id = ?`,
+		health.Status, health.Latency.Milliseconds(), health.Error, nullTime(health.CheckedAt), linkID)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationHealth, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shorty/internal/storage"
+	"testing"
+	"time"
+)
+
+func TestSaveLinkHealth_ListLinks(t *testing.T) {
+	s := newTestStorage(t)
+
+	ids := make(map[string]int64)
+	for _, alias := range []string{"alive", "gone1", "uncheck", "downx"} {
+		id, err := s.SaveLink(storage.Link{Alias: alias, URL: "https://example.com/" + alias})
+		require.NoError(t, err)
+		ids[alias] = id
+	}
+
+	checkedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.SaveLinkHealth(ids["alive"], storage.Health{Status: 200, Latency: 35 * time.Millisecond, CheckedAt: checkedAt}))
+	require.NoError(t, s.SaveLinkHealth(ids["gone1"], storage.Health{Status: 404, Latency: 12 * time.Millisecond, CheckedAt: checkedAt}))
+	require.NoError(t, s.SaveLinkHealth(ids["downx"], storage.Health{Error: "connection refused", CheckedAt: checkedAt}))
+
+	link, err := s.GetLink(storage.DefaultWorkspace, "", "alive")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Health{Status: 200, Latency: 35 * time.Millisecond, CheckedAt: checkedAt}, link.Health)
+	assert.False(t, link.Health.Broken())
+
+	links, err := s.ListLinks(storage.LinkFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alive", "gone1", "uncheck", "downx"}, aliases(links))
+
+	links, err = s.ListLinks(storage.LinkFilter{Offset: 1, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gone1", "uncheck"}, aliases(links))
+
+	links, err = s.ListLinks(storage.LinkFilter{Limit: 10, Broken: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gone1", "downx"}, aliases(links))
+	for _, link := range links {
+		assert.True(t, link.Health.Broken(), link.Alias)
+	}
+}
+
+func aliases(links []storage.Link) []string {
+	var result []string
+	for _, link := range links {
+		result = append(result, link.Alias)
+	}
+
+	return result
+}
@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"shorty/internal/storage"
+	"time"
+)
+
+// ReserveIdempotencyKey marks the key of the workspace as taken by a request in progress, keys created before expireBefore are forgotten.
+// If the key is already taken, the existing record is returned with storage.ErrIdempotencyKeyExists
+func (s *Storage) ReserveIdempotencyKey(workspaceID int64, key string, fingerprint string, expireBefore time.Time) (storage.IdempotencyRecord, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.IdempotencyRecord{}, fmt.Errorf("%s: begin transaction: %w", sqliteOperationReserveKey, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM idempotency_record WHERE created_at < ?`, expireBefore.Unix())
+	if err != nil {
+		return storage.IdempotencyRecord{}, fmt.Errorf("%s: delete expired keys %w", sqliteOperationReserveKey, err)
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(`INSERT INTO idempotency_record(workspace_id, key, fingerprint, created_at) VALUES(?, ?, ?, ?)`, workspaceID, key, fingerprint, now.Unix())
+	if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		var (
+			record    = storage.IdempotencyRecord{Key: key}
+			response  sql.NullString
+			createdAt int64
+		)
+		err = tx.QueryRow(`SELECT fingerprint, response, created_at FROM idempotency_record WHERE workspace_id = ? AND key = ?`, workspaceID, key).
+			Scan(&record.Fingerprint, &response, &createdAt)
+		if err != nil {
+			return storage.IdempotencyRecord{}, fmt.Errorf("%s: execute statement %w", sqliteOperationReserveKey, err)
+		}
+
+		if response.Valid {
+			record.Response = []byte(response.String)
+		}
+		record.CreatedAt = time.Unix(createdAt, 0).UTC()
+
+		return record, fmt.Errorf("%s: %w", sqliteOperationReserveKey, storage.ErrIdempotencyKeyExists)
+	}
+
+	if err != nil {
+		return storage.IdempotencyRecord{}, fmt.Errorf("%s: execute statement %w", sqliteOperationReserveKey, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return storage.IdempotencyRecord{}, fmt.Errorf("%s: commit transaction: %w", sqliteOperationReserveKey, err)
+	}
+
+	return storage.IdempotencyRecord{Key: key, Fingerprint: fingerprint, CreatedAt: time.Unix(now.Unix(), 0).UTC()}, nil
+}
+
+// CompleteIdempotencyKey saves the response of the request that reserved the key
+func (s *Storage) CompleteIdempotencyKey(workspaceID int64, key string, response []byte) error {
+	_, err := s.db.Exec(`UPDATE idempotency_record SET response = ? WHERE workspace_id = ? AND key = ?`, string(response), workspaceID, key)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationCompleteKey, err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets a key whose request did not complete, so that it can be retried
+func (s *Storage) ReleaseIdempotencyKey(workspaceID int64, key string) error {
+	_, err := s.db.Exec(`DELETE FROM idempotency_record WHERE workspace_id = ? AND key = ? AND response IS NULL`, workspaceID, key)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationReleaseKey, err)
+	}
+
+	return nil
+}
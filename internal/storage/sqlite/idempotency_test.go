@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shorty/internal/storage"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	s := newTestStorage(t)
+	window := time.Now().Add(-time.Hour)
+
+	_, err := s.ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-1", "body", window)
+	require.NoError(t, err)
+
+	record, err := s.ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-1", "body", window)
+	require.ErrorIs(t, err, storage.ErrIdempotencyKeyExists)
+	assert.Equal(t, "body", record.Fingerprint)
+	assert.Nil(t, record.Response, "request is in progress")
+
+	require.NoError(t, s.CompleteIdempotencyKey(storage.DefaultWorkspace, "retry-1", []byte(`{"alias":"abcde"}`)))
+	record, err = s.ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-1", "other", window)
+	require.ErrorIs(t, err, storage.ErrIdempotencyKeyExists)
+	assert.Equal(t, "body", record.Fingerprint)
+	assert.Equal(t, `{"alias":"abcde"}`, string(record.Response))
+
+	//completed keys are kept
+	require.NoError(t, s.ReleaseIdempotencyKey(storage.DefaultWorkspace, "retry-1"))
+	_, err = s.ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-1", "body", window)
+	require.ErrorIs(t, err, storage.ErrIdempotencyKeyExists)
+
+	//keys of failed requests are released
+	_, err = s.ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-2", "body", window)
+	require.NoError(t, err)
+	require.NoError(t, s.ReleaseIdempotencyKey(storage.DefaultWorkspace, "retry-2"))
+	_, err = s.ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-2", "body", window)
+	require.NoError(t, err)
+
+	//expired keys are forgotten
+	_, err = s.ReserveIdempotencyKey(storage.DefaultWorkspace, "retry-1", "other", time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"shorty/internal/storage"
+	"time"
+)
+
+// ReserveLinks counts count links created by the owner on the day of now,
+// it returns storage.ErrUserQuotaExceeded and counts nothing if they do not fit the quota.
+// The returned usage is the usage after the reservation, or the current usage if the quota is exceeded.
+// Concurrent reservations are serialized by the immediate transaction, see dsn
+func (s *Storage) ReserveLinks(owner string, count int64, quota storage.LinkQuota, now time.Time) (storage.QuotaUsage, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.QuotaUsage{}, fmt.Errorf("%s: begin transaction: %w", sqliteOperationReserveLinks, err)
+	}
+	defer tx.Rollback()
+
+	day := now.UTC().Format(time.DateOnly)
+	usage, err := quotaUsage(tx, owner, day)
+	if err != nil {
+		return storage.QuotaUsage{}, fmt.Errorf("%s: %w", sqliteOperationReserveLinks, err)
+	}
+
+	if quota.Daily > 0 && usage.Daily+count > quota.Daily || quota.Total > 0 && usage.Total+count > quota.Total {
+		return usage, fmt.Errorf("%s: %w", sqliteOperationReserveLinks, storage.ErrUserQuotaExceeded)
+	}
+
+	_, err = tx.Exec(`
+	INSERT INTO user_link_count(owner, day, links) VALUES(?, ?, ?)
+	ON CONFLICT(owner, day) DO UPDATE SET links = links + excluded.links`, owner, day, count)
+	if err != nil {
+		return storage.QuotaUsage{}, fmt.Errorf("%s: execute statement %w", sqliteOperationReserveLinks, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return storage.QuotaUsage{}, fmt.Errorf("%s: commit transaction: %w", sqliteOperationReserveLinks, err)
+	}
+
+	usage.Daily += count
+	usage.Total += count
+
+	return usage, nil
+}
+
+// ReleaseLinks returns count links reserved on the day of now that were not created
+func (s *Storage) ReleaseLinks(owner string, count int64, now time.Time) error {
+	_, err := s.db.Exec(`
+	UPDATE user_link_count SET links = MAX(links - ?, 0) WHERE owner = ? AND day = ?`,
+		count, owner, now.UTC().Format(time.DateOnly))
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationReleaseLinks, err)
+	}
+
+	return nil
+}
+
+// GetQuotaUsage returns the number of links created by the owner on the day of now and in total
+func (s *Storage) GetQuotaUsage(owner string, now time.Time) (storage.QuotaUsage, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.QuotaUsage{}, fmt.Errorf("%s: begin transaction: %w", sqliteOperationQuotaUsage, err)
+	}
+	defer tx.Rollback()
+
+	usage, err := quotaUsage(tx, owner, now.UTC().Format(time.DateOnly))
+	if err != nil {
+		return storage.QuotaUsage{}, fmt.Errorf("%s: %w", sqliteOperationQuotaUsage, err)
+	}
+
+	return usage, nil
+}
+
+func quotaUsage(tx *sql.Tx, owner string, day string) (storage.QuotaUsage, error) {
+	var usage storage.QuotaUsage
+
+	err := tx.QueryRow(`
+	SELECT COALESCE(SUM(CASE WHEN day = ? THEN links ELSE 0 END), 0), COALESCE(SUM(links), 0)
+	FROM user_link_count WHERE owner = ?`, day, owner).Scan(&usage.Daily, &usage.Total)
+	if err != nil {
+		return storage.QuotaUsage{}, fmt.Errorf("count user links: %w", err)
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shorty/internal/storage"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReserveLinks(t *testing.T) {
+	s := newTestStorage(t)
+
+	quota := storage.LinkQuota{Daily: 3, Total: 5}
+	day := time.Date(2030, 1, 1, 23, 0, 0, 0, time.UTC)
+
+	usage, err := s.ReserveLinks("alice", 2, quota, day)
+	require.NoError(t, err)
+	assert.Equal(t, storage.QuotaUsage{Daily: 2, Total: 2}, usage)
+
+	usage, err = s.ReserveLinks("alice", 2, quota, day)
+	require.ErrorIs(t, err, storage.ErrUserQuotaExceeded)
+	assert.Equal(t, storage.QuotaUsage{Daily: 2, Total: 2}, usage)
+
+	//other users have their own counters
+	_, err = s.ReserveLinks("bob", 3, quota, day)
+	require.NoError(t, err)
+
+	require.NoError(t, s.ReleaseLinks("alice", 1, day))
+	_, err = s.ReserveLinks("alice", 2, quota, day)
+	require.NoError(t, err)
+
+	//the daily counter is reset at midnight UTC, the total is kept
+	next := day.Add(2 * time.Hour)
+	usage, err = s.GetQuotaUsage("alice", next)
+	require.NoError(t, err)
+	assert.Equal(t, storage.QuotaUsage{Daily: 0, Total: 3}, usage)
+
+	_, err = s.ReserveLinks("alice", 3, quota, next)
+	require.ErrorIs(t, err, storage.ErrUserQuotaExceeded)
+	usage, err = s.ReserveLinks("alice", 2, quota, next)
+	require.NoError(t, err)
+	assert.Equal(t, storage.QuotaUsage{Daily: 2, Total: 5}, usage)
+
+	//zero caps are open
+	_, err = s.ReserveLinks("alice", 100, storage.LinkQuota{}, next)
+	require.NoError(t, err)
+}
+
+func TestReserveLinks_Concurrent(t *testing.T) {
+	s := newTestStorage(t)
+
+	quota := storage.LinkQuota{Daily: 25}
+	now := time.Now()
+
+	var (
+		wg       sync.WaitGroup
+		reserved atomic.Int64
+		exceeded atomic.Int64
+	)
+
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := s.ReserveLinks("alice", 1, quota, now)
+			switch {
+			case err == nil:
+				reserved.Add(1)
+			case errors.Is(err, storage.ErrUserQuotaExceeded):
+				exceeded.Add(1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(25), reserved.Load())
+	assert.Equal(t, int64(15), exceeded.Load())
+
+	usage, err := s.GetQuotaUsage("alice", now)
+	require.NoError(t, err)
+	assert.Equal(t, storage.QuotaUsage{Daily: 25, Total: 25}, usage)
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"shorty/internal/storage"
+)
+
+// SetRules replaces the platform rules of a link
+func (s *Storage) SetRules(workspaceID int64, domain string, alias string, rules []storage.Rule) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", sqliteOperationRules, err)
+	}
+	defer tx.Rollback()
+
+	var urlID int64
+	err = tx.QueryRow(`SELECT id FROM url WHERE workspace_id = ? AND domain = ? AND alias = ?`, workspaceID, domain, alias).Scan(&urlID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%s: %w", sqliteOperationRules, storage.ErrURLNotFound)
+		}
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationRules, err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM rule WHERE url_id = ?`, urlID)
+	if err != nil {
+		return fmt.Errorf("%s: delete rules %w", sqliteOperationRules, err)
+	}
+
+	err = insertRules(tx, urlID, rules)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sqliteOperationRules, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", sqliteOperationRules, err)
+	}
+
+	return nil
+}
+
+func insertRules(tx *sql.Tx, urlID int64, rules []storage.Rule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	statement, err := tx.Prepare(`INSERT INTO rule(url_id, position, os, device, target) VALUES(?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer statement.Close()
+
+	for position, rule := range rules {
+		_, err = statement.Exec(urlID, position, rule.OS, rule.Device, rule.Target)
+		if err != nil {
+			return fmt.Errorf("insert rule: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rules returns the platform rules of the links by link id in evaluation order
+func (s *Storage) rules(urlIDs []int64) (map[int64][]storage.Rule, error) {
+	in, args := idList(urlIDs)
+	rows, err := s.db.Query(`SELECT url_id, os, device, target FROM rule WHERE url_id IN `+in+` ORDER BY url_id, position`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make(map[int64][]storage.Rule)
+	for rows.Next() {
+		var (
+			urlID int64
+			rule  storage.Rule
+		)
+		err = rows.Scan(&urlID, &rule.OS, &rule.Device, &rule.Target)
+		if err != nil {
+			return nil, fmt.Errorf("scan rule: %w", err)
+		}
+		rules[urlID] = append(rules[urlID], rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rules: %w", err)
+	}
+
+	return rules, nil
+}
@@ -1,11 +1,15 @@
 package sqlite
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/mattn/go-sqlite3"
 	_ "github.com/mattn/go-sqlite3" //sqlite3 driver
 	"shorty/internal/storage"
+	"strings"
 	"time"
 )
 
@@ -14,15 +18,70 @@ type Storage struct {
 }
 
 const (
-	sqliteOperationNew    = "storage.sqlite.New"
-	sqliteOperationSave   = "storage.sqlite.SaveURL"
-	sqliteOperationGet    = "storage.sqlite.GetURL"
-	sqliteOperationUpdate = "storage.sqlite.UpdateAlias"
-	sqliteOperationDelete = "storage.sqlite.DeleteURL"
+	sqliteOperationNew     = "storage.sqlite.New"
+	sqliteOperationSave    = "storage.sqlite.SaveLink"
+	sqliteOperationBatch   = "storage.sqlite.SaveLinks"
+	sqliteOperationGet     = "storage.sqlite.GetLink"
+	sqliteOperationFind    = "storage.sqlite.FindLinkByURL"
+	sqliteOperationUpdate  = "storage.sqlite.UpdateAlias"
+	sqliteOperationDelete  = "storage.sqlite.DeleteURL"
+	sqliteOperationClick   = "storage.sqlite.ConsumeClick"
+	sqliteOperationRules   = "storage.sqlite.SetRules"
+	sqliteOperationVariant = "storage.sqlite.CountVariantClick"
+	sqliteOperationCountry = "storage.sqlite.CountCountryClick"
+	sqliteOperationEach    = "storage.sqlite.EachLink"
+	sqliteOperationImport  = "storage.sqlite.ImportLinks"
+	sqliteOperationList    = "storage.sqlite.ListLinks"
+	sqliteOperationHealth  = "storage.sqlite.SaveLinkHealth"
+
+	sqliteOperationSaveTemplate   = "storage.sqlite.SaveTemplate"
+	sqliteOperationGetTemplate    = "storage.sqlite.GetTemplate"
+	sqliteOperationListTemplates  = "storage.sqlite.ListTemplates"
+	sqliteOperationUpdateTemplate = "storage.sqlite.UpdateTemplate"
+	sqliteOperationDeleteTemplate = "storage.sqlite.DeleteTemplate"
+
+	sqliteOperationReserveKey  = "storage.sqlite.ReserveIdempotencyKey"
+	sqliteOperationCompleteKey = "storage.sqlite.CompleteIdempotencyKey"
+	sqliteOperationReleaseKey  = "storage.sqlite.ReleaseIdempotencyKey"
+
+	sqliteOperationSaveDomain    = "storage.sqlite.SaveDomain"
+	sqliteOperationGetDomain     = "storage.sqlite.GetDomain"
+	sqliteOperationListDomains   = "storage.sqlite.ListDomains"
+	sqliteOperationUpdateDomain  = "storage.sqlite.UpdateDomain"
+	sqliteOperationDeleteDomain  = "storage.sqlite.DeleteDomain"
+	sqliteOperationResolveDomain = "storage.sqlite.ResolveDomain"
+
+	sqliteOperationSaveWorkspace   = "storage.sqlite.SaveWorkspace"
+	sqliteOperationGetWorkspace    = "storage.sqlite.GetWorkspace"
+	sqliteOperationListWorkspaces  = "storage.sqlite.ListWorkspaces"
+	sqliteOperationUpdateWorkspace = "storage.sqlite.UpdateWorkspace"
+	sqliteOperationCountRequest    = "storage.sqlite.CountRequest"
+
+	sqliteOperationSaveUser   = "storage.sqlite.SaveUser"
+	sqliteOperationGetUser    = "storage.sqlite.GetUser"
+	sqliteOperationListUsers  = "storage.sqlite.ListUsers"
+	sqliteOperationDeleteUser = "storage.sqlite.DeleteUser"
+
+	sqliteOperationReserveLinks = "storage.sqlite.ReserveLinks"
+	sqliteOperationReleaseLinks = "storage.sqlite.ReleaseLinks"
+	sqliteOperationQuotaUsage   = "storage.sqlite.GetQuotaUsage"
+
+	sqliteOperationSaveTag   = "storage.sqlite.SaveTag"
+	sqliteOperationListTags  = "storage.sqlite.ListTags"
+	sqliteOperationRenameTag = "storage.sqlite.RenameTag"
+	sqliteOperationDeleteTag = "storage.sqlite.DeleteTag"
+	sqliteOperationSetTags   = "storage.sqlite.SetTags"
+
+	sqliteOperationSaveFolder   = "storage.sqlite.SaveFolder"
+	sqliteOperationGetFolder    = "storage.sqlite.GetFolder"
+	sqliteOperationListFolders  = "storage.sqlite.ListFolders"
+	sqliteOperationUpdateFolder = "storage.sqlite.UpdateFolder"
+	sqliteOperationDeleteFolder = "storage.sqlite.DeleteFolder"
+	sqliteOperationSetFolder    = "storage.sqlite.SetFolder"
 )
 
 func New(dbPath string) (*Storage, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open("sqlite3", dsn(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", sqliteOperationNew, err)
 	}
@@ -30,7 +89,7 @@ func New(dbPath string) (*Storage, error) {
 	statement, err := db.Prepare(`
 	CREATE TABLE IF NOT EXISTS url(
 		id INTEGER PRIMARY KEY,
-		alias TEXT NOT NULL UNIQUE,
+		alias TEXT NOT NULL,
 		url TEXT NOT NULL,
 		created_at INTEGER NOT NULL,
 	    updated_at INTEGER NOT NULL
@@ -45,79 +104,810 @@ func New(dbPath string) (*Storage, error) {
 		return nil, fmt.Errorf("%s: %w", sqliteOperationNew, err)
 	}
 
+	err = migrate(db)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", sqliteOperationNew, err)
+	}
+
 	return &Storage{db: db}, nil
 }
 
-func (s *Storage) SaveURL(url string, alias string) (int64, error) {
-	statement, err := s.db.Prepare(`INSERT INTO url(url, alias, created_at, updated_at) VALUES(?, ?, ?, ?)`)
+// tables created after the initial schema
+var tables = []string{
+	`CREATE TABLE IF NOT EXISTS template(
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		utm_source TEXT NOT NULL DEFAULT '',
+		utm_medium TEXT NOT NULL DEFAULT '',
+		utm_campaign TEXT NOT NULL DEFAULT '',
+		utm_term TEXT NOT NULL DEFAULT '',
+		utm_content TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS rule(
+		id INTEGER PRIMARY KEY,
+		url_id INTEGER NOT NULL REFERENCES url(id),
+		position INTEGER NOT NULL,
+		os TEXT NOT NULL DEFAULT '',
+		device TEXT NOT NULL DEFAULT '',
+		target TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_rule_url_id ON rule(url_id, position)`,
+	`CREATE TABLE IF NOT EXISTS variant(
+		id INTEGER PRIMARY KEY,
+		url_id INTEGER NOT NULL REFERENCES url(id),
+		position INTEGER NOT NULL,
+		target TEXT NOT NULL,
+		weight INTEGER NOT NULL,
+		clicks INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_variant_url_id ON variant(url_id, position)`,
+	`CREATE TABLE IF NOT EXISTS country_click(
+		url_id INTEGER NOT NULL REFERENCES url(id),
+		country TEXT NOT NULL,
+		clicks INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (url_id, country)
+	)`,
+	//idempotency keys are unique per workspace
+	`CREATE TABLE IF NOT EXISTS idempotency_record(
+		workspace_id INTEGER NOT NULL,
+		key TEXT NOT NULL,
+		fingerprint TEXT NOT NULL,
+		response TEXT,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (workspace_id, key)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_idempotency_record_created_at ON idempotency_record(created_at)`,
+	`CREATE TABLE IF NOT EXISTS domain(
+		id INTEGER PRIMARY KEY,
+		host TEXT NOT NULL UNIQUE,
+		default_url TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS workspace(
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		max_links INTEGER NOT NULL DEFAULT 0,
+		max_requests INTEGER NOT NULL DEFAULT 0,
+		preview_all INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS api_user(
+		id INTEGER PRIMARY KEY,
+		workspace_id INTEGER NOT NULL REFERENCES workspace(id),
+		name TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_api_user_workspace_id ON api_user(workspace_id)`,
+	`CREATE TABLE IF NOT EXISTS workspace_request(
+		workspace_id INTEGER NOT NULL,
+		day TEXT NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (workspace_id, day)
+	)`,
+	`CREATE TABLE IF NOT EXISTS user_link_count(
+		owner TEXT NOT NULL,
+		day TEXT NOT NULL,
+		links INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (owner, day)
+	)`,
+	`CREATE TABLE IF NOT EXISTS tag(
+		id INTEGER PRIMARY KEY,
+		workspace_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		UNIQUE (workspace_id, name)
+	)`,
+	`CREATE TABLE IF NOT EXISTS link_tag(
+		url_id INTEGER NOT NULL REFERENCES url(id),
+		tag_id INTEGER NOT NULL REFERENCES tag(id),
+		PRIMARY KEY (url_id, tag_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_link_tag_tag_id ON link_tag(tag_id)`,
+	`CREATE TABLE IF NOT EXISTS folder(
+		id INTEGER PRIMARY KEY,
+		workspace_id INTEGER NOT NULL,
+		parent_id INTEGER REFERENCES folder(id),
+		name TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	)`,
+	//top level folders have no parent, NULLs are distinct in unique constraints
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_folder_workspace_parent_name ON folder(workspace_id, IFNULL(parent_id, 0), name)`,
+}
+
+// columns added to the url table after its initial schema
+var urlColumns = []struct {
+	name       string
+	definition string
+}{
+	{name: "merge_query", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{name: "append_path", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{name: "template_id", definition: "INTEGER REFERENCES template(id)"},
+	{name: "password_hash", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "max_clicks", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{name: "clicks_left", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{name: "not_before", definition: "INTEGER"},
+	{name: "not_after", definition: "INTEGER"},
+	{name: "fallback_url", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "locales", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "countries", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "owner", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "url_hash", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "original_url", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "check_status", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{name: "check_latency", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{name: "check_error", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "checked_at", definition: "INTEGER"},
+	{name: "title", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "preview", definition: "INTEGER NOT NULL DEFAULT 0"},
+	{name: "og_title", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "og_description", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "og_image", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "domain", definition: "TEXT NOT NULL DEFAULT ''"},
+	{name: "folder_id", definition: "INTEGER REFERENCES folder(id)"},
+}
+
+// workspaceTables are scoped by the workspace_id column, rows saved before workspaces belong to the default workspace
+var workspaceTables = []string{"url", "template", "domain"}
+
+// indexes on the migrated columns, they are created after the tables are rebuilt
+var indexes = []string{
+	`CREATE INDEX IF NOT EXISTS idx_alias ON url(alias)`,
+	`CREATE INDEX IF NOT EXISTS idx_url_owner_hash ON url(owner, url_hash)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_url_workspace_domain_alias ON url(workspace_id, domain, alias)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_template_workspace_name ON template(workspace_id, name)`,
+	`CREATE INDEX IF NOT EXISTS idx_domain_workspace_id ON domain(workspace_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_url_folder_id ON url(folder_id)`,
+}
+
+// migrate brings the schema of an existing database up to date
+func migrate(db *sql.DB) error {
+	for _, table := range tables {
+		_, err := db.Exec(table)
+		if err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+	}
+
+	for _, column := range urlColumns {
+		err := addColumn(db, "url", column.name, column.definition)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, table := range workspaceTables {
+		err := addColumn(db, table, "workspace_id", "INTEGER NOT NULL DEFAULT 0")
+		if err != nil {
+			return err
+		}
+	}
+
+	//aliases were unique across all domains and template names across the instance
+	err := dropUnique(db, "url", "alias")
+	if err != nil {
+		return fmt.Errorf("scope aliases: %w", err)
+	}
+
+	err = dropUnique(db, "template", "name")
+	if err != nil {
+		return fmt.Errorf("scope template names: %w", err)
+	}
+
+	for _, index := range indexes {
+		_, err := db.Exec(index)
+		if err != nil {
+			return fmt.Errorf("create index: %w", err)
+		}
+	}
+
+	err = backfillURLHashes(db)
+	if err != nil {
+		return fmt.Errorf("backfill url hashes: %w", err)
+	}
+
+	return nil
+}
+
+// addColumn adds a column to a table unless it already exists
+func addColumn(db *sql.DB, table string, name string, definition string) error {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, name).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("check column %s.%s: %w", table, name, err)
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, name, definition))
+	if err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, name, err)
+	}
+
+	return nil
+}
+
+// dropUnique rebuilds a table of an older database without the inline uniqueness of a text column,
+// sqlite cannot drop a column constraint in place. Indexes of the table are dropped with it
+func dropUnique(db *sql.DB, table string, column string) error {
+	var schema string
+	err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&schema)
+	if err != nil {
+		return err
+	}
+
+	unique := column + " TEXT NOT NULL UNIQUE"
+	if !strings.Contains(schema, unique) {
+		return nil
+	}
+
+	//a renamed table is stored as CREATE TABLE "name"(...
+	rebuild := table + "_rebuild"
+	rebuilt := "CREATE TABLE " + rebuild + schema[strings.Index(schema, "("):]
+	rebuilt = strings.Replace(rebuilt, unique, column+" TEXT NOT NULL", 1)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		rebuilt,
+		fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s`, rebuild, table),
+		fmt.Sprintf(`DROP TABLE %s`, table),
+		fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, rebuild, table),
+	}
+	for _, statement := range statements {
+		_, err = tx.Exec(statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// backfillURLHashes hashes the targets of links saved before the url_hash column existed
+func backfillURLHashes(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, url FROM url WHERE url_hash = ''`)
+	if err != nil {
+		return err
+	}
+
+	hashes := make(map[int64]string)
+	for rows.Next() {
+		var (
+			id     int64
+			target string
+		)
+		err = rows.Scan(&id, &target)
+		if err != nil {
+			rows.Close()
+
+			return err
+		}
+		hashes[id] = urlHash(target)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for id, hash := range hashes {
+		_, err = db.Exec(`UPDATE url SET url_hash = ? WHERE id = ?`, hash, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// urlHash is the lookup key of a link target, targets are compared after the hash matches
+func urlHash(target string) string {
+	sum := sha256.Sum256([]byte(target))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Storage) SaveLink(link storage.Link) (int64, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return 0, fmt.Errorf("%s: prepare statement: %w", sqliteOperationSave, err)
+		return 0, fmt.Errorf("%s: begin transaction: %w", sqliteOperationSave, err)
+	}
+	defer tx.Rollback()
+
+	id, err := saveLink(tx, link)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", sqliteOperationSave, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", sqliteOperationSave, err)
+	}
+
+	return id, nil
+}
+
+// saveLink inserts a link with its rules, variants and tags inside a transaction,
+// it returns storage.ErrLinkQuotaExceeded if the workspace of the link has no links left
+// and storage.ErrFolderNotFound if the folder of the link is not a folder of its workspace
+func saveLink(tx *sql.Tx, link storage.Link) (int64, error) {
+	err := checkLinkQuota(tx, link.WorkspaceID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = checkFolder(tx, link.WorkspaceID, link.FolderID)
+	if err != nil {
+		return 0, err
+	}
+
+	statement, err := tx.Prepare(`
+	INSERT INTO url(workspace_id, url, domain, alias, merge_query, append_path, template_id, password_hash, max_clicks, clicks_left,
+	                not_before, not_after, fallback_url, locales, countries, owner, url_hash, original_url, title, preview,
+	                og_title, og_description, og_image, folder_id, created_at, updated_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("prepare statement: %w", err)
+	}
+	defer statement.Close()
+
+	var templateID sql.NullInt64
+	if link.Template != nil {
+		templateID = sql.NullInt64{Int64: link.Template.ID, Valid: true}
+	}
+
+	locales, err := encodeTargets(link.Locales)
+	if err != nil {
+		return 0, err
+	}
+
+	countries, err := encodeTargets(link.Countries)
+	if err != nil {
+		return 0, err
 	}
 
 	timestamp := time.Now().Unix()
-	result, err := statement.Exec(url, alias, timestamp, timestamp)
+	result, err := statement.Exec(link.WorkspaceID, link.URL, link.Domain, link.Alias, link.MergeQuery, link.AppendPath, templateID, link.PasswordHash,
+		link.MaxClicks, link.MaxClicks, nullTime(link.NotBefore), nullTime(link.NotAfter), link.FallbackURL, locales,
+		countries, link.Owner, urlHash(link.URL), link.OriginalURL, link.Title, link.Preview,
+		link.OpenGraph.Title, link.OpenGraph.Description, link.OpenGraph.Image, nullID(link.FolderID), timestamp, timestamp)
 	if err != nil {
 		//cast to internal sqlite type and check if constraint was violated
 		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
 
-			//if an url was added with an alias that was previously saved, then we throw an error
-			return 0, fmt.Errorf("%s: %w", sqliteOperationSave, storage.ErrURLAlreadyExists)
+			//if an url was added with an alias that was previously saved on its domain in the workspace, then we throw an error
+			return 0, storage.ErrURLAlreadyExists
 		}
-		return 0, fmt.Errorf("%s: %w", sqliteOperationSave, err)
+		return 0, err
 	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
-		return 0, fmt.Errorf("%s: failed to get last insert id %w", sqliteOperationSave, err)
+		return 0, fmt.Errorf("failed to get last insert id %w", err)
+	}
+
+	err = insertRules(tx, id, link.Rules)
+	if err != nil {
+		return 0, err
+	}
+
+	err = insertVariants(tx, id, link.Variants)
+	if err != nil {
+		return 0, err
+	}
+
+	err = insertTags(tx, link.WorkspaceID, id, link.Tags)
+	if err != nil {
+		return 0, err
 	}
 
 	return id, nil
 }
 
-func (s *Storage) GetURL(alias string) (string, error) {
-	var resultURL string
+// checkLinkQuota returns storage.ErrLinkQuotaExceeded if the workspace already has its maximum number of links
+func checkLinkQuota(tx *sql.Tx, workspaceID int64) error {
+	if workspaceID == storage.DefaultWorkspace {
+		return nil
+	}
+
+	var maxLinks int64
+	err := tx.QueryRow(`SELECT max_links FROM workspace WHERE id = ?`, workspaceID).Scan(&maxLinks)
+	if err == sql.ErrNoRows {
+		return storage.ErrWorkspaceNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("get workspace quota: %w", err)
+	}
+
+	if maxLinks == 0 {
+		return nil
+	}
+
+	var links int64
+	err = tx.QueryRow(`SELECT COUNT(*) FROM url WHERE workspace_id = ?`, workspaceID).Scan(&links)
+	if err != nil {
+		return fmt.Errorf("count workspace links: %w", err)
+	}
+
+	if links >= maxLinks {
+		return storage.ErrLinkQuotaExceeded
+	}
+
+	return nil
+}
+
+// linkQuery selects links with their templates, callers append the conditions
+const linkQuery = `
+	SELECT u.id, u.workspace_id, u.domain, u.alias, u.url, u.merge_query, u.append_path, u.password_hash, u.max_clicks, u.clicks_left,
+	       u.not_before, u.not_after, u.fallback_url, u.locales, u.countries, u.owner, u.original_url,
+	       u.check_status, u.check_latency, u.check_error, u.checked_at, u.title, u.preview,
+	       u.og_title, u.og_description, u.og_image, u.folder_id,
+	       t.id, t.name, t.utm_source, t.utm_medium, t.utm_campaign, t.utm_term, t.utm_content
+	FROM url u
+	LEFT JOIN template t ON t.id = u.template_id`
 
-	statement, err := s.db.Prepare(`SELECT url FROM url WHERE alias = ?`)
+func (s *Storage) GetLink(workspaceID int64, domain string, alias string) (storage.Link, error) {
+	statement, err := s.db.Prepare(linkQuery + ` WHERE u.workspace_id = ? AND u.domain = ? AND u.alias = ?`)
 	if err != nil {
-		return "", fmt.Errorf("%s: prepare statement: %w", sqliteOperationGet, err)
+		return storage.Link{}, fmt.Errorf("%s: prepare statement: %w", sqliteOperationGet, err)
 	}
 
-	err = statement.QueryRow(alias).Scan(&resultURL)
+	link, err := scanLink(statement.QueryRow(workspaceID, domain, alias))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", storage.ErrURLNotFound
+			return storage.Link{}, storage.ErrURLNotFound
 		}
-		return "", fmt.Errorf("%s: execute statement %w", sqliteOperationGet, err)
+		return storage.Link{}, fmt.Errorf("%s: execute statement %w", sqliteOperationGet, err)
+	}
+
+	err = s.linkDetails(&link)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", sqliteOperationGet, err)
+	}
+
+	return link, nil
+}
+
+// FindLinkByURL returns the latest link of the owner on the domain of the workspace with exactly the target url
+func (s *Storage) FindLinkByURL(workspaceID int64, domain string, owner string, target string) (storage.Link, error) {
+	statement, err := s.db.Prepare(linkQuery + ` WHERE u.owner = ? AND u.url_hash = ? AND u.url = ? AND u.workspace_id = ? AND u.domain = ? ORDER BY u.id DESC LIMIT 1`)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: prepare statement: %w", sqliteOperationFind, err)
+	}
+
+	link, err := scanLink(statement.QueryRow(owner, urlHash(target), target, workspaceID, domain))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.Link{}, storage.ErrURLNotFound
+		}
+		return storage.Link{}, fmt.Errorf("%s: execute statement %w", sqliteOperationFind, err)
+	}
+
+	err = s.linkDetails(&link)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", sqliteOperationFind, err)
+	}
+
+	return link, nil
+}
+
+// ListLinks returns a page of links of the workspace in the order of creation
+func (s *Storage) ListLinks(filter storage.LinkFilter) ([]storage.Link, error) {
+	query := linkQuery + ` WHERE u.workspace_id = ?`
+	args := []any{filter.WorkspaceID}
+	if filter.Broken {
+		query += ` AND u.checked_at IS NOT NULL AND (u.check_error != '' OR u.check_status >= 400)`
+	}
+
+	for _, tag := range filter.Tags {
+		query += ` AND u.id IN (SELECT lt.url_id FROM link_tag lt JOIN tag g ON g.id = lt.tag_id WHERE g.workspace_id = ? AND g.name = ?)`
+		args = append(args, filter.WorkspaceID, tag)
+	}
+
+	if filter.FolderID != 0 {
+		query += ` AND u.folder_id = ?`
+		args = append(args, filter.FolderID)
+	}
+
+	links, err := s.queryLinks(query+` ORDER BY u.id LIMIT ? OFFSET ?`, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", sqliteOperationList, err)
+	}
+
+	err = s.pageDetails(links)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", sqliteOperationList, err)
+	}
+
+	return links, nil
+}
+
+// scanner is implemented by sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanLink reads a row of linkQuery
+func scanLink(row scanner) (storage.Link, error) {
+	var (
+		link      storage.Link
+		template  nullTemplate
+		notBefore sql.NullInt64
+		notAfter  sql.NullInt64
+		locales   string
+		countries string
+		latency   int64
+		checkedAt sql.NullInt64
+		folderID  sql.NullInt64
+	)
+
+	err := row.Scan(
+		&link.ID, &link.WorkspaceID, &link.Domain, &link.Alias, &link.URL, &link.MergeQuery, &link.AppendPath, &link.PasswordHash, &link.MaxClicks, &link.ClicksLeft,
+		&notBefore, &notAfter, &link.FallbackURL, &locales, &countries, &link.Owner, &link.OriginalURL,
+		&link.Health.Status, &latency, &link.Health.Error, &checkedAt, &link.Title, &link.Preview,
+		&link.OpenGraph.Title, &link.OpenGraph.Description, &link.OpenGraph.Image, &folderID,
+		&template.id, &template.name, &template.source, &template.medium, &template.campaign, &template.term, &template.content,
+	)
+	if err != nil {
+		return storage.Link{}, err
+	}
+
+	link.Template = template.toTemplate()
+	link.NotBefore = fromNullTime(notBefore)
+	link.NotAfter = fromNullTime(notAfter)
+	link.Health.Latency = time.Duration(latency) * time.Millisecond
+	link.Health.CheckedAt = fromNullTime(checkedAt)
+	link.FolderID = folderID.Int64
+
+	link.Locales, err = decodeTargets(locales)
+	if err != nil {
+		return storage.Link{}, err
+	}
+
+	link.Countries, err = decodeTargets(countries)
+	if err != nil {
+		return storage.Link{}, err
+	}
+
+	return link, nil
+}
+
+// linkDetails loads the rules, variants, tags and click counters of a link
+func (s *Storage) linkDetails(link *storage.Link) error {
+	page := []storage.Link{*link}
+	err := s.pageDetails(page)
+	if err != nil {
+		return err
+	}
+	*link = page[0]
+
+	return nil
+}
+
+// pageDetails loads the rules, variants, country clicks and tags of a page of links with a query per table
+func (s *Storage) pageDetails(links []storage.Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.ID)
+	}
+
+	rules, err := s.rules(ids)
+	if err != nil {
+		return err
+	}
+
+	variants, err := s.variants(ids)
+	if err != nil {
+		return err
+	}
+
+	countryClicks, err := s.countryClicks(ids)
+	if err != nil {
+		return err
+	}
+
+	tags, err := s.tags(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range links {
+		id := links[i].ID
+		links[i].Rules = rules[id]
+		links[i].Variants = variants[id]
+		links[i].CountryClicks = countryClicks[id]
+		links[i].Tags = tags[id]
 	}
 
-	return resultURL, nil
+	return nil
+}
+
+// ConsumeClick atomically takes one click from a limited link,
+// it returns storage.ErrLinkExhausted if no clicks are left
+func (s *Storage) ConsumeClick(workspaceID int64, domain string, alias string) error {
+	statement, err := s.db.Prepare(`
+	UPDATE url SET clicks_left = clicks_left - 1
+	WHERE workspace_id = ? AND domain = ? AND alias = ? AND max_clicks > 0 AND clicks_left > 0`)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", sqliteOperationClick, err)
+	}
+
+	result, err := statement.Exec(workspaceID, domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationClick, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationClick, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationClick, storage.ErrLinkExhausted)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteURL(workspaceID int64, domain string, alias string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", sqliteOperationDelete, err)
+	}
+	defer tx.Rollback()
+
+	err = deleteLink(tx, workspaceID, domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sqliteOperationDelete, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", sqliteOperationDelete, err)
+	}
+
+	return nil
 }
 
-func (s *Storage) DeleteURL(alias string) error {
-	statement, err := s.db.Prepare(`DELETE FROM url WHERE alias = ?`)
+// deleteLink deletes a link together with its rules, variants, tags and click counters inside a transaction
+func deleteLink(tx *sql.Tx, workspaceID int64, domain string, alias string) error {
+	_, err := tx.Exec(`DELETE FROM rule WHERE url_id IN (SELECT id FROM url WHERE workspace_id = ? AND domain = ? AND alias = ?)`, workspaceID, domain, alias)
 	if err != nil {
-		return fmt.Errorf("%s: prepare statement: %w", sqliteOperationDelete, err)
+		return fmt.Errorf("delete rules %w", err)
 	}
 
-	_, err = statement.Exec(alias)
+	_, err = tx.Exec(`DELETE FROM variant WHERE url_id IN (SELECT id FROM url WHERE workspace_id = ? AND domain = ? AND alias = ?)`, workspaceID, domain, alias)
 	if err != nil {
-		return fmt.Errorf("%s: execute statement %w", sqliteOperationDelete, err)
+		return fmt.Errorf("delete variants %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM country_click WHERE url_id IN (SELECT id FROM url WHERE workspace_id = ? AND domain = ? AND alias = ?)`, workspaceID, domain, alias)
+	if err != nil {
+		return fmt.Errorf("delete country clicks %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM link_tag WHERE url_id IN (SELECT id FROM url WHERE workspace_id = ? AND domain = ? AND alias = ?)`, workspaceID, domain, alias)
+	if err != nil {
+		return fmt.Errorf("delete tags %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM url WHERE workspace_id = ? AND domain = ? AND alias = ?`, workspaceID, domain, alias)
+	if err != nil {
+		return fmt.Errorf("execute statement %w", err)
 	}
 
 	return nil
 }
 
-func (s *Storage) UpdateAlias(oldAlias string, newAlias string) error {
-	statement, err := s.db.Prepare(`UPDATE url SET alias = ?, updated_at = ? WHERE alias = ?`)
+func (s *Storage) UpdateAlias(workspaceID int64, domain string, oldAlias string, newAlias string) error {
+	statement, err := s.db.Prepare(`UPDATE url SET alias = ?, updated_at = ? WHERE workspace_id = ? AND domain = ? AND alias = ?`)
 	if err != nil {
 		return fmt.Errorf("%s: prepare statement: %w", sqliteOperationUpdate, err)
 	}
 
 	timestamp := time.Now().Unix()
-	_, err = statement.Exec(newAlias, timestamp, oldAlias)
+	result, err := statement.Exec(newAlias, timestamp, workspaceID, domain, oldAlias)
 	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return fmt.Errorf("%s: %w", sqliteOperationUpdate, storage.ErrURLAlreadyExists)
+		}
 		return fmt.Errorf("%s: execute statement %w", sqliteOperationUpdate, err)
 	}
 
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationUpdate, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationUpdate, storage.ErrURLNotFound)
+	}
+
 	return nil
 }
+
+// nullTime stores a zero time as NULL and any other time as unix seconds
+func nullTime(t time.Time) sql.NullInt64 {
+	if t.IsZero() {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: t.Unix(), Valid: true}
+}
+
+// idList returns the placeholder list of an IN clause for the ids and its arguments
+func idList(ids []int64) (string, []any) {
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	return "(" + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + ")", args
+}
+
+// dsn opens transactions with BEGIN IMMEDIATE, transactions that read before they write would otherwise
+// fail with "database is locked" instead of waiting for a concurrent writer
+func dsn(dbPath string) string {
+	if strings.Contains(dbPath, "?") {
+		return dbPath + "&_txlock=immediate"
+	}
+
+	return dbPath + "?_txlock=immediate"
+}
+
+// nullID stores a zero id of an optional reference as NULL
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+func fromNullTime(n sql.NullInt64) time.Time {
+	if !n.Valid {
+		return time.Time{}
+	}
+
+	return time.Unix(n.Int64, 0).UTC()
+}
+
+// encodeTargets stores a key to target map of a link as json, an empty map as an empty string
+func encodeTargets(targets map[string]string) (string, error) {
+	if len(targets) == 0 {
+		return "", nil
+	}
+
+	encoded, err := json.Marshal(targets)
+	if err != nil {
+		return "", fmt.Errorf("encode targets: %w", err)
+	}
+
+	return string(encoded), nil
+}
+
+func decodeTargets(encoded string) (map[string]string, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	var targets map[string]string
+	err := json.Unmarshal([]byte(encoded), &targets)
+	if err != nil {
+		return nil, fmt.Errorf("decode targets: %w", err)
+	}
+
+	return targets, nil
+}
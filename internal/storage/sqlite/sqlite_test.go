@@ -0,0 +1,265 @@
+package sqlite
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"shorty/internal/storage"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.db.Close() })
+
+	return s
+}
+
+func TestConsumeClick_Concurrent(t *testing.T) {
+	const maxClicks = 5
+
+	s := newTestStorage(t)
+	_, err := s.SaveLink(storage.Link{Alias: "onbrd", URL: "https://example.com", MaxClicks: maxClicks})
+	require.NoError(t, err)
+
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int64
+		exhausted atomic.Int64
+	)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := s.ConsumeClick(storage.DefaultWorkspace, "", "onbrd")
+			switch {
+			case err == nil:
+				succeeded.Add(1)
+			case errors.Is(err, storage.ErrLinkExhausted):
+				exhausted.Add(1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(maxClicks), succeeded.Load())
+	assert.Equal(t, int64(50-maxClicks), exhausted.Load())
+
+	link, err := s.GetLink(storage.DefaultWorkspace, "", "onbrd")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), link.ClicksLeft)
+}
+
+func TestConsumeClick_Unlimited(t *testing.T) {
+	s := newTestStorage(t)
+	_, err := s.SaveLink(storage.Link{Alias: "unlim", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	err = s.ConsumeClick(storage.DefaultWorkspace, "", "unlim")
+	assert.ErrorIs(t, err, storage.ErrLinkExhausted, "unlimited links have no clicks to take")
+}
+
+func TestSaveLink_RoundTrip(t *testing.T) {
+	s := newTestStorage(t)
+
+	templateID, err := s.SaveTemplate(storage.Template{Name: "spring", Source: "newsletter"})
+	require.NoError(t, err)
+
+	link := storage.Link{
+		Alias:        "launc",
+		URL:          "https://example.com/launch",
+		OriginalURL:  "HTTPS://Example.com:443/launch",
+		Owner:        "alice",
+		Title:        "Launch",
+		Preview:      true,
+		OpenGraph:    storage.OpenGraph{Title: "We launched", Description: "Read all about it", Image: "https://example.com/og.png"},
+		MergeQuery:   true,
+		AppendPath:   true,
+		Template:     &storage.Template{ID: templateID, Name: "spring", Source: "newsletter"},
+		PasswordHash: "hash",
+		MaxClicks:    3,
+		ClicksLeft:   3,
+		NotBefore:    time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC),
+		FallbackURL:  "https://example.com/soon",
+		Rules: []storage.Rule{
+			{OS: "ios", Target: "https://apps.apple.com/app/id1"},
+			{Device: "desktop", Target: "https://example.com/desktop"},
+		},
+	}
+
+	variants := []storage.Variant{
+		{Target: "https://example.com/a", Weight: 1},
+		{Target: "https://example.com/b", Weight: 2},
+	}
+	link.Variants = variants
+	link.Locales = map[string]string{"de": "https://example.com/de", "pt-BR": "https://example.com/pt"}
+	link.Countries = map[string]string{"FR": "https://example.fr/launch"}
+
+	id, err := s.SaveLink(link)
+	require.NoError(t, err)
+	link.ID = id
+
+	saved, err := s.GetLink(storage.DefaultWorkspace, "", "launc")
+	require.NoError(t, err)
+	require.Len(t, saved.Variants, 2)
+	for i := range variants {
+		variants[i].ID = saved.Variants[i].ID
+	}
+	assert.Equal(t, link, saved)
+
+	require.NoError(t, s.CountVariantClick(saved.Variants[1].ID))
+	saved, err = s.GetLink(storage.DefaultWorkspace, "", "launc")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), saved.Variants[0].Clicks)
+	assert.Equal(t, int64(1), saved.Variants[1].Clicks)
+
+	require.NoError(t, s.CountCountryClick(id, "FR"))
+	require.NoError(t, s.CountCountryClick(id, "FR"))
+	require.NoError(t, s.CountCountryClick(id, "US"))
+	saved, err = s.GetLink(storage.DefaultWorkspace, "", "launc")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"FR": 2, "US": 1}, saved.CountryClicks)
+
+	_, err = s.SaveLink(link)
+	assert.ErrorIs(t, err, storage.ErrURLAlreadyExists)
+
+	_, err = s.GetLink(storage.DefaultWorkspace, "", "missing")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestUpdateAlias(t *testing.T) {
+	s := newTestStorage(t)
+	_, err := s.SaveLink(storage.Link{Alias: "first", URL: "https://example.com/1"})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "secnd", URL: "https://example.com/2"})
+	require.NoError(t, err)
+
+	err = s.UpdateAlias(storage.DefaultWorkspace, "", "first", "secnd")
+	assert.ErrorIs(t, err, storage.ErrURLAlreadyExists)
+
+	require.NoError(t, s.UpdateAlias(storage.DefaultWorkspace, "", "first", "third"))
+	link, err := s.GetLink(storage.DefaultWorkspace, "", "third")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/1", link.URL)
+
+	err = s.UpdateAlias(storage.DefaultWorkspace, "", "first", "fourth")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	err = s.UpdateAlias(int64(7), "", "third", "fourth")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound, "links of other workspaces are not renamed")
+}
+
+func TestListLinks_Details(t *testing.T) {
+	s := newTestStorage(t)
+
+	first, err := s.SaveLink(storage.Link{
+		Alias: "first",
+		URL:   "https://example.com/1",
+		Rules: []storage.Rule{{OS: "ios", Target: "https://apps.apple.com/app/id1"}, {OS: "android", Target: "https://play.google.com"}},
+		Tags:  []string{"spring", "launch"},
+	})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "plain", URL: "https://example.com/plain"})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{
+		Alias:    "split",
+		URL:      "https://example.com/split",
+		Variants: []storage.Variant{{Target: "https://example.com/a", Weight: 1}, {Target: "https://example.com/b", Weight: 3}},
+		Tags:     []string{"launch"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.CountCountryClick(first, "DE"))
+
+	links, err := s.ListLinks(storage.LinkFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "plain", "split"}, aliases(links))
+
+	assert.Equal(t, []storage.Rule{{OS: "ios", Target: "https://apps.apple.com/app/id1"}, {OS: "android", Target: "https://play.google.com"}}, links[0].Rules)
+	assert.Equal(t, []string{"launch", "spring"}, links[0].Tags)
+	assert.Equal(t, map[string]int64{"DE": 1}, links[0].CountryClicks)
+	assert.Nil(t, links[0].Variants)
+
+	assert.Nil(t, links[1].Rules)
+	assert.Nil(t, links[1].Tags)
+	assert.Nil(t, links[1].CountryClicks)
+
+	require.Len(t, links[2].Variants, 2)
+	assert.Equal(t, "https://example.com/a", links[2].Variants[0].Target)
+	assert.Equal(t, 3, links[2].Variants[1].Weight)
+	assert.Equal(t, []string{"launch"}, links[2].Tags)
+}
+
+func TestSetRules(t *testing.T) {
+	s := newTestStorage(t)
+	_, err := s.SaveLink(storage.Link{Alias: "myapp", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	rules := []storage.Rule{{OS: "android", Device: "mobile", Target: "https://play.google.com"}}
+	require.NoError(t, s.SetRules(storage.DefaultWorkspace, "", "myapp", rules))
+
+	link, err := s.GetLink(storage.DefaultWorkspace, "", "myapp")
+	require.NoError(t, err)
+	assert.Equal(t, rules, link.Rules)
+
+	require.NoError(t, s.SetRules(storage.DefaultWorkspace, "", "myapp", nil))
+	link, err = s.GetLink(storage.DefaultWorkspace, "", "myapp")
+	require.NoError(t, err)
+	assert.Empty(t, link.Rules)
+
+	err = s.SetRules(storage.DefaultWorkspace, "", "missing", rules)
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestFindLinkByURL(t *testing.T) {
+	s := newTestStorage(t)
+
+	for _, link := range []storage.Link{
+		{Alias: "older", URL: "https://example.com/page", Owner: "alice"},
+		{Alias: "newer", URL: "https://example.com/page", Owner: "alice"},
+		{Alias: "other", URL: "https://example.com/page", Owner: "bob"},
+	} {
+		_, err := s.SaveLink(link)
+		require.NoError(t, err)
+	}
+
+	link, err := s.FindLinkByURL(storage.DefaultWorkspace, "", "alice", "https://example.com/page")
+	require.NoError(t, err)
+	assert.Equal(t, "newer", link.Alias)
+	assert.Equal(t, "alice", link.Owner)
+
+	link, err = s.FindLinkByURL(storage.DefaultWorkspace, "", "bob", "https://example.com/page")
+	require.NoError(t, err)
+	assert.Equal(t, "other", link.Alias)
+
+	_, err = s.FindLinkByURL(storage.DefaultWorkspace, "", "carol", "https://example.com/page")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	_, err = s.FindLinkByURL(storage.DefaultWorkspace, "", "alice", "https://example.com/other")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestMigrate_BackfillsURLHashes(t *testing.T) {
+	s := newTestStorage(t)
+
+	_, err := s.SaveLink(storage.Link{Alias: "legacy", URL: "https://example.com/legacy"})
+	require.NoError(t, err)
+	_, err = s.db.Exec(`UPDATE url SET url_hash = ''`)
+	require.NoError(t, err)
+
+	require.NoError(t, migrate(s.db))
+
+	link, err := s.FindLinkByURL(storage.DefaultWorkspace, "", "", "https://example.com/legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", link.Alias)
+}
@@ -0,0 +1,213 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"shorty/internal/storage"
+	"time"
+)
+
+func (s *Storage) SaveTag(tag storage.Tag) (int64, error) {
+	statement, err := s.db.Prepare(`INSERT INTO tag(workspace_id, name, created_at) VALUES(?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: prepare statement: %w", sqliteOperationSaveTag, err)
+	}
+
+	result, err := statement.Exec(tag.WorkspaceID, tag.Name, time.Now().Unix())
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", sqliteOperationSaveTag, storage.ErrTagAlreadyExists)
+		}
+		return 0, fmt.Errorf("%s: %w", sqliteOperationSaveTag, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id %w", sqliteOperationSaveTag, err)
+	}
+
+	return id, nil
+}
+
+// ListTags returns the tags of the workspace by name together with their numbers of links
+func (s *Storage) ListTags(workspaceID int64) ([]storage.Tag, error) {
+	rows, err := s.db.Query(`
+	SELECT g.id, g.workspace_id, g.name, COUNT(lt.url_id)
+	FROM tag g
+	LEFT JOIN link_tag lt ON lt.tag_id = g.id
+	WHERE g.workspace_id = ?
+	GROUP BY g.id
+	ORDER BY g.name`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement %w", sqliteOperationListTags, err)
+	}
+	defer rows.Close()
+
+	var tags []storage.Tag
+	for rows.Next() {
+		var tag storage.Tag
+		err = rows.Scan(&tag.ID, &tag.WorkspaceID, &tag.Name, &tag.Links)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row %w", sqliteOperationListTags, err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows %w", sqliteOperationListTags, err)
+	}
+
+	return tags, nil
+}
+
+// RenameTag renames a tag of the workspace, the links keep the tag under its new name
+func (s *Storage) RenameTag(workspaceID int64, name string, newName string) error {
+	statement, err := s.db.Prepare(`UPDATE tag SET name = ? WHERE workspace_id = ? AND name = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", sqliteOperationRenameTag, err)
+	}
+
+	result, err := statement.Exec(newName, workspaceID, name)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return fmt.Errorf("%s: %w", sqliteOperationRenameTag, storage.ErrTagAlreadyExists)
+		}
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationRenameTag, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationRenameTag, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationRenameTag, storage.ErrTagNotFound)
+	}
+
+	return nil
+}
+
+// DeleteTag removes a tag and takes it off all links that have it
+func (s *Storage) DeleteTag(workspaceID int64, name string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", sqliteOperationDeleteTag, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM link_tag WHERE tag_id = (SELECT id FROM tag WHERE workspace_id = ? AND name = ?)`, workspaceID, name)
+	if err != nil {
+		return fmt.Errorf("%s: detach links %w", sqliteOperationDeleteTag, err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM tag WHERE workspace_id = ? AND name = ?`, workspaceID, name)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationDeleteTag, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationDeleteTag, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationDeleteTag, storage.ErrTagNotFound)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", sqliteOperationDeleteTag, err)
+	}
+
+	return nil
+}
+
+// SetTags replaces the tags of a link, missing tags are created
+func (s *Storage) SetTags(workspaceID int64, domain string, alias string, tags []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", sqliteOperationSetTags, err)
+	}
+	defer tx.Rollback()
+
+	var urlID int64
+	err = tx.QueryRow(`SELECT id FROM url WHERE workspace_id = ? AND domain = ? AND alias = ?`, workspaceID, domain, alias).Scan(&urlID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%s: %w", sqliteOperationSetTags, storage.ErrURLNotFound)
+		}
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationSetTags, err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM link_tag WHERE url_id = ?`, urlID)
+	if err != nil {
+		return fmt.Errorf("%s: delete tags %w", sqliteOperationSetTags, err)
+	}
+
+	err = insertTags(tx, workspaceID, urlID, tags)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sqliteOperationSetTags, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", sqliteOperationSetTags, err)
+	}
+
+	return nil
+}
+
+// insertTags attaches tags of the workspace to a link, missing tags are created
+func insertTags(tx *sql.Tx, workspaceID int64, urlID int64, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	timestamp := time.Now().Unix()
+	for _, name := range tags {
+		_, err := tx.Exec(`INSERT INTO tag(workspace_id, name, created_at) VALUES(?, ?, ?) ON CONFLICT(workspace_id, name) DO NOTHING`,
+			workspaceID, name, timestamp)
+		if err != nil {
+			return fmt.Errorf("create tag: %w", err)
+		}
+
+		_, err = tx.Exec(`
+		INSERT INTO link_tag(url_id, tag_id) SELECT ?, id FROM tag WHERE workspace_id = ? AND name = ?
+		ON CONFLICT(url_id, tag_id) DO NOTHING`, urlID, workspaceID, name)
+		if err != nil {
+			return fmt.Errorf("insert tag: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// tags returns the tag names of the links by link id in alphabetical order
+func (s *Storage) tags(urlIDs []int64) (map[int64][]string, error) {
+	in, args := idList(urlIDs)
+	rows, err := s.db.Query(`SELECT lt.url_id, g.name FROM link_tag lt JOIN tag g ON g.id = lt.tag_id WHERE lt.url_id IN `+in+` ORDER BY lt.url_id, g.name`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[int64][]string)
+	for rows.Next() {
+		var (
+			urlID int64
+			name  string
+		)
+		err = rows.Scan(&urlID, &name)
+		if err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		tags[urlID] = append(tags[urlID], name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate tags: %w", err)
+	}
+
+	return tags, nil
+}
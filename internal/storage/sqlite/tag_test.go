@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	s := newTestStorage(t)
+
+	_, err := s.SaveTag(storage.Tag{Name: "launch"})
+	require.NoError(t, err)
+	_, err = s.SaveTag(storage.Tag{Name: "launch"})
+	assert.ErrorIs(t, err, storage.ErrTagAlreadyExists)
+
+	//missing tags are created with the link
+	_, err = s.SaveLink(storage.Link{Alias: "promo", URL: "https://example.com/promo", Tags: []string{"launch", "spring"}})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{Alias: "blog", URL: "https://example.com/blog", Tags: []string{"spring"}})
+	require.NoError(t, err)
+
+	link, err := s.GetLink(storage.DefaultWorkspace, "", "promo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"launch", "spring"}, link.Tags)
+
+	tags, err := s.ListTags(storage.DefaultWorkspace)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.Equal(t, "launch", tags[0].Name)
+	assert.Equal(t, int64(1), tags[0].Links)
+	assert.Equal(t, int64(2), tags[1].Links)
+
+	links, err := s.ListLinks(storage.LinkFilter{Limit: 10, Tags: []string{"spring", "launch"}})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "promo", links[0].Alias)
+
+	require.NoError(t, s.SetTags(storage.DefaultWorkspace, "", "blog", []string{"news"}))
+	assert.ErrorIs(t, s.SetTags(storage.DefaultWorkspace, "", "other", nil), storage.ErrURLNotFound)
+
+	assert.ErrorIs(t, s.RenameTag(storage.DefaultWorkspace, "spring", "news"), storage.ErrTagAlreadyExists)
+	require.NoError(t, s.RenameTag(storage.DefaultWorkspace, "spring", "spring-2030"))
+	assert.ErrorIs(t, s.RenameTag(storage.DefaultWorkspace, "spring", "summer"), storage.ErrTagNotFound)
+
+	require.NoError(t, s.DeleteTag(storage.DefaultWorkspace, "launch"))
+	assert.ErrorIs(t, s.DeleteTag(storage.DefaultWorkspace, "launch"), storage.ErrTagNotFound)
+
+	link, err = s.GetLink(storage.DefaultWorkspace, "", "promo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"spring-2030"}, link.Tags)
+
+	//tags of deleted links are released
+	require.NoError(t, s.DeleteURL(storage.DefaultWorkspace, "", "promo"))
+	tags, err = s.ListTags(storage.DefaultWorkspace)
+	require.NoError(t, err)
+	for _, tag := range tags {
+		if tag.Name == "spring-2030" {
+			assert.Zero(t, tag.Links)
+		}
+	}
+}
+
+func TestTags_WorkspaceScope(t *testing.T) {
+	s := newTestStorage(t)
+
+	marketing, err := s.SaveWorkspace(storage.Workspace{Name: "marketing"})
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Alias: "promo", URL: "https://example.com", Tags: []string{"launch"}})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{WorkspaceID: marketing, Alias: "promo", URL: "https://example.com", Tags: []string{"launch"}})
+	require.NoError(t, err)
+
+	tags, err := s.ListTags(marketing)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, int64(1), tags[0].Links)
+
+	links, err := s.ListLinks(storage.LinkFilter{WorkspaceID: marketing, Limit: 10, Tags: []string{"launch"}})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, marketing, links[0].WorkspaceID)
+}
@@ -0,0 +1,172 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"shorty/internal/storage"
+	"time"
+)
+
+// nullTemplate scans template columns of an outer join
+type nullTemplate struct {
+	id       sql.NullInt64
+	name     sql.NullString
+	source   sql.NullString
+	medium   sql.NullString
+	campaign sql.NullString
+	term     sql.NullString
+	content  sql.NullString
+}
+
+func (t nullTemplate) toTemplate() *storage.Template {
+	if !t.id.Valid {
+		return nil
+	}
+
+	return &storage.Template{
+		ID:       t.id.Int64,
+		Name:     t.name.String,
+		Source:   t.source.String,
+		Medium:   t.medium.String,
+		Campaign: t.campaign.String,
+		Term:     t.term.String,
+		Content:  t.content.String,
+	}
+}
+
+func (s *Storage) SaveTemplate(template storage.Template) (int64, error) {
+	statement, err := s.db.Prepare(`
+	INSERT INTO template(workspace_id, name, utm_source, utm_medium, utm_campaign, utm_term, utm_content, created_at, updated_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: prepare statement: %w", sqliteOperationSaveTemplate, err)
+	}
+
+	timestamp := time.Now().Unix()
+	result, err := statement.Exec(template.WorkspaceID, template.Name, template.Source, template.Medium, template.Campaign, template.Term, template.Content, timestamp, timestamp)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", sqliteOperationSaveTemplate, storage.ErrTemplateAlreadyExists)
+		}
+		return 0, fmt.Errorf("%s: %w", sqliteOperationSaveTemplate, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id %w", sqliteOperationSaveTemplate, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetTemplate(workspaceID int64, name string) (storage.Template, error) {
+	var template storage.Template
+
+	statement, err := s.db.Prepare(`
+	SELECT id, workspace_id, name, utm_source, utm_medium, utm_campaign, utm_term, utm_content
+	FROM template WHERE workspace_id = ? AND name = ?`)
+	if err != nil {
+		return storage.Template{}, fmt.Errorf("%s: prepare statement: %w", sqliteOperationGetTemplate, err)
+	}
+
+	err = statement.QueryRow(workspaceID, name).Scan(
+		&template.ID, &template.WorkspaceID, &template.Name, &template.Source, &template.Medium, &template.Campaign, &template.Term, &template.Content,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.Template{}, storage.ErrTemplateNotFound
+		}
+		return storage.Template{}, fmt.Errorf("%s: execute statement %w", sqliteOperationGetTemplate, err)
+	}
+
+	return template, nil
+}
+
+func (s *Storage) ListTemplates(workspaceID int64) ([]storage.Template, error) {
+	rows, err := s.db.Query(`
+	SELECT id, workspace_id, name, utm_source, utm_medium, utm_campaign, utm_term, utm_content
+	FROM template WHERE workspace_id = ? ORDER BY name`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement %w", sqliteOperationListTemplates, err)
+	}
+	defer rows.Close()
+
+	var templates []storage.Template
+	for rows.Next() {
+		var template storage.Template
+		err = rows.Scan(&template.ID, &template.WorkspaceID, &template.Name, &template.Source, &template.Medium, &template.Campaign, &template.Term, &template.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row %w", sqliteOperationListTemplates, err)
+		}
+		templates = append(templates, template)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows %w", sqliteOperationListTemplates, err)
+	}
+
+	return templates, nil
+}
+
+func (s *Storage) UpdateTemplate(template storage.Template) error {
+	statement, err := s.db.Prepare(`
+	UPDATE template
+	SET utm_source = ?, utm_medium = ?, utm_campaign = ?, utm_term = ?, utm_content = ?, updated_at = ?
+	WHERE workspace_id = ? AND name = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", sqliteOperationUpdateTemplate, err)
+	}
+
+	timestamp := time.Now().Unix()
+	result, err := statement.Exec(template.Source, template.Medium, template.Campaign, template.Term, template.Content, timestamp, template.WorkspaceID, template.Name)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationUpdateTemplate, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationUpdateTemplate, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationUpdateTemplate, storage.ErrTemplateNotFound)
+	}
+
+	return nil
+}
+
+// DeleteTemplate removes a template and detaches it from all links that use it
+func (s *Storage) DeleteTemplate(workspaceID int64, name string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", sqliteOperationDeleteTemplate, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE url SET template_id = NULL WHERE template_id = (SELECT id FROM template WHERE workspace_id = ? AND name = ?)`, workspaceID, name)
+	if err != nil {
+		return fmt.Errorf("%s: detach links %w", sqliteOperationDeleteTemplate, err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM template WHERE workspace_id = ? AND name = ?`, workspaceID, name)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationDeleteTemplate, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationDeleteTemplate, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationDeleteTemplate, storage.ErrTemplateNotFound)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", sqliteOperationDeleteTemplate, err)
+	}
+
+	return nil
+}
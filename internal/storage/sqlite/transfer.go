@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"shorty/internal/storage"
+)
+
+// exportPageSize is the number of links read at once, the database is not held while a page is handled
+const exportPageSize = 500
+
+// EachLink calls fn for every link of the workspace in the order of creation, it stops at the first error of fn
+func (s *Storage) EachLink(workspaceID int64, fn func(link storage.Link) error) error {
+	var lastID int64
+	for {
+		page, err := s.linkPage(workspaceID, lastID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sqliteOperationEach, err)
+		}
+
+		err = s.pageDetails(page)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sqliteOperationEach, err)
+		}
+
+		for _, link := range page {
+			err = fn(link)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(page) < exportPageSize {
+			return nil
+		}
+		lastID = page[len(page)-1].ID
+	}
+}
+
+// linkPage returns the links of the workspace created after the link with the given id
+func (s *Storage) linkPage(workspaceID int64, afterID int64) ([]storage.Link, error) {
+	return s.queryLinks(linkQuery+` WHERE u.workspace_id = ? AND u.id > ? ORDER BY u.id LIMIT ?`, workspaceID, afterID, exportPageSize)
+}
+
+// queryLinks reads the links selected by a linkQuery without their details
+func (s *Storage) queryLinks(query string, args ...any) ([]storage.Link, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []storage.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan link: %w", err)
+		}
+		links = append(links, link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate links: %w", err)
+	}
+
+	return links, nil
+}
+
+// ImportLinks saves links in a single transaction and resolves taken aliases with the policy.
+// Templates are matched by name and domains must be registered in the workspace of the link, clicks left are kept for limited links
+func (s *Storage) ImportLinks(links []storage.Link, policy storage.ConflictPolicy) (storage.ImportResult, error) {
+	var result storage.ImportResult
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return result, fmt.Errorf("%s: begin transaction: %w", sqliteOperationImport, err)
+	}
+	defer tx.Rollback()
+
+	for _, link := range links {
+		if link.Domain != "" {
+			var domainID int64
+			err = tx.QueryRow(`SELECT id FROM domain WHERE workspace_id = ? AND host = ?`, link.WorkspaceID, link.Domain).Scan(&domainID)
+			if errors.Is(err, sql.ErrNoRows) {
+				return storage.ImportResult{}, fmt.Errorf("%s: %w", sqliteOperationImport, &storage.LinkError{Alias: link.Alias, Err: storage.ErrDomainNotFound})
+			}
+			if err != nil {
+				return storage.ImportResult{}, fmt.Errorf("%s: %w", sqliteOperationImport, &storage.LinkError{Alias: link.Alias, Err: fmt.Errorf("find domain: %w", err)})
+			}
+		}
+
+		if link.Template != nil {
+			var templateID int64
+			err = tx.QueryRow(`SELECT id FROM template WHERE workspace_id = ? AND name = ?`, link.WorkspaceID, link.Template.Name).Scan(&templateID)
+			if errors.Is(err, sql.ErrNoRows) {
+				return storage.ImportResult{}, fmt.Errorf("%s: %w", sqliteOperationImport, &storage.LinkError{Alias: link.Alias, Err: storage.ErrTemplateNotFound})
+			}
+			if err != nil {
+				return storage.ImportResult{}, fmt.Errorf("%s: %w", sqliteOperationImport, &storage.LinkError{Alias: link.Alias, Err: fmt.Errorf("find template: %w", err)})
+			}
+			link.Template = &storage.Template{ID: templateID, WorkspaceID: link.WorkspaceID, Name: link.Template.Name}
+		}
+
+		id, err := saveLink(tx, link)
+		if errors.Is(err, storage.ErrURLAlreadyExists) {
+			switch policy {
+			case storage.ConflictSkip:
+				result.Skipped++
+
+				continue
+			case storage.ConflictOverwrite:
+				err = deleteLink(tx, link.WorkspaceID, link.Domain, link.Alias)
+				if err != nil {
+					return storage.ImportResult{}, fmt.Errorf("%s: %w", sqliteOperationImport, &storage.LinkError{Alias: link.Alias, Err: err})
+				}
+
+				id, err = saveLink(tx, link)
+				result.Overwritten++
+			default:
+				return storage.ImportResult{}, fmt.Errorf("%s: %w", sqliteOperationImport, &storage.LinkError{Alias: link.Alias, Err: err})
+			}
+		} else if err == nil {
+			result.Created++
+		}
+
+		if err != nil {
+			return storage.ImportResult{}, fmt.Errorf("%s: %w", sqliteOperationImport, &storage.LinkError{Alias: link.Alias, Err: err})
+		}
+
+		//saveLink starts limited links with all clicks left
+		if link.MaxClicks > 0 && link.ClicksLeft < link.MaxClicks {
+			_, err = tx.Exec(`UPDATE url SET clicks_left = ? WHERE id = ?`, link.ClicksLeft, id)
+			if err != nil {
+				return storage.ImportResult{}, fmt.Errorf("%s: %w", sqliteOperationImport, &storage.LinkError{Alias: link.Alias, Err: fmt.Errorf("set clicks left: %w", err)})
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return storage.ImportResult{}, fmt.Errorf("%s: commit transaction: %w", sqliteOperationImport, err)
+	}
+
+	return result, nil
+}
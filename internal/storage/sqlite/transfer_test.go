@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shorty/internal/storage"
+	"testing"
+)
+
+func TestEachLink(t *testing.T) {
+	s := newTestStorage(t)
+
+	//more than one page
+	total := exportPageSize + 3
+	links := make([]storage.Link, total)
+	for i := range links {
+		links[i] = storage.Link{Alias: fmt.Sprintf("l%04d", i), URL: "https://example.com"}
+	}
+	links[exportPageSize].Rules = []storage.Rule{{OS: "ios", Target: "https://apps.apple.com"}}
+
+	_, err := s.SaveLinks(links, true)
+	require.NoError(t, err)
+
+	var aliases []string
+	err = s.EachLink(storage.DefaultWorkspace, func(link storage.Link) error {
+		aliases = append(aliases, link.Alias)
+		if link.Alias == links[exportPageSize].Alias {
+			assert.Len(t, link.Rules, 1)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, aliases, total)
+	assert.Equal(t, "l0000", aliases[0])
+	assert.Equal(t, fmt.Sprintf("l%04d", total-1), aliases[total-1])
+
+	stop := errors.New("client gone")
+	calls := 0
+	err = s.EachLink(storage.DefaultWorkspace, func(link storage.Link) error {
+		calls++
+
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestImportLinks(t *testing.T) {
+	s := newTestStorage(t)
+
+	_, err := s.SaveTemplate(storage.Template{Name: "spring", Source: "newsletter"})
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Alias: "taken", URL: "https://example.com/old", Variants: []storage.Variant{
+		{Target: "https://example.com/a", Weight: 1},
+		{Target: "https://example.com/b", Weight: 1},
+	}})
+	require.NoError(t, err)
+
+	links := []storage.Link{
+		{Alias: "fresh", URL: "https://example.com/fresh", Template: &storage.Template{Name: "spring"}, MaxClicks: 5, ClicksLeft: 2},
+		{Alias: "taken", URL: "https://example.com/new"},
+	}
+
+	t.Run("Fail", func(t *testing.T) {
+		_, err := s.ImportLinks(links, storage.ConflictFail)
+		require.ErrorIs(t, err, storage.ErrURLAlreadyExists)
+		var linkErr *storage.LinkError
+		require.ErrorAs(t, err, &linkErr)
+		assert.Equal(t, "taken", linkErr.Alias)
+
+		_, err = s.GetLink(storage.DefaultWorkspace, "", "fresh")
+		assert.ErrorIs(t, err, storage.ErrURLNotFound)
+	})
+
+	t.Run("Missing template", func(t *testing.T) {
+		_, err := s.ImportLinks([]storage.Link{{Alias: "other", URL: "https://example.com", Template: &storage.Template{Name: "winter"}}}, storage.ConflictFail)
+		assert.ErrorIs(t, err, storage.ErrTemplateNotFound)
+	})
+
+	t.Run("Missing domain", func(t *testing.T) {
+		_, err := s.ImportLinks([]storage.Link{{Domain: "go.brand.com", Alias: "other", URL: "https://example.com"}}, storage.ConflictFail)
+		assert.ErrorIs(t, err, storage.ErrDomainNotFound)
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		result, err := s.ImportLinks(links, storage.ConflictSkip)
+		require.NoError(t, err)
+		assert.Equal(t, storage.ImportResult{Created: 1, Skipped: 1}, result)
+
+		fresh, err := s.GetLink(storage.DefaultWorkspace, "", "fresh")
+		require.NoError(t, err)
+		require.NotNil(t, fresh.Template)
+		assert.Equal(t, "newsletter", fresh.Template.Source)
+		assert.Equal(t, int64(2), fresh.ClicksLeft)
+
+		taken, err := s.GetLink(storage.DefaultWorkspace, "", "taken")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/old", taken.URL)
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		result, err := s.ImportLinks(links[1:], storage.ConflictOverwrite)
+		require.NoError(t, err)
+		assert.Equal(t, storage.ImportResult{Overwritten: 1}, result)
+
+		taken, err := s.GetLink(storage.DefaultWorkspace, "", "taken")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/new", taken.URL)
+		assert.Empty(t, taken.Variants)
+	})
+}
@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"shorty/internal/storage"
+	"time"
+)
+
+func (s *Storage) SaveUser(user storage.User) (int64, error) {
+	statement, err := s.db.Prepare(`INSERT INTO api_user(workspace_id, name, password_hash, created_at) VALUES(?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: prepare statement: %w", sqliteOperationSaveUser, err)
+	}
+
+	result, err := statement.Exec(user.WorkspaceID, user.Name, user.PasswordHash, time.Now().Unix())
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", sqliteOperationSaveUser, storage.ErrUserAlreadyExists)
+		}
+		return 0, fmt.Errorf("%s: %w", sqliteOperationSaveUser, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id %w", sqliteOperationSaveUser, err)
+	}
+
+	return id, nil
+}
+
+// GetUser returns the user with the name in any workspace, it authenticates api requests
+func (s *Storage) GetUser(name string) (storage.User, error) {
+	var user storage.User
+
+	statement, err := s.db.Prepare(`SELECT id, workspace_id, name, password_hash FROM api_user WHERE name = ?`)
+	if err != nil {
+		return storage.User{}, fmt.Errorf("%s: prepare statement: %w", sqliteOperationGetUser, err)
+	}
+
+	err = statement.QueryRow(name).Scan(&user.ID, &user.WorkspaceID, &user.Name, &user.PasswordHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.User{}, storage.ErrUserNotFound
+		}
+		return storage.User{}, fmt.Errorf("%s: execute statement %w", sqliteOperationGetUser, err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) ListUsers(workspaceID int64) ([]storage.User, error) {
+	rows, err := s.db.Query(`SELECT id, workspace_id, name, password_hash FROM api_user WHERE workspace_id = ? ORDER BY name`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement %w", sqliteOperationListUsers, err)
+	}
+	defer rows.Close()
+
+	var users []storage.User
+	for rows.Next() {
+		var user storage.User
+		err = rows.Scan(&user.ID, &user.WorkspaceID, &user.Name, &user.PasswordHash)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row %w", sqliteOperationListUsers, err)
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows %w", sqliteOperationListUsers, err)
+	}
+
+	return users, nil
+}
+
+func (s *Storage) DeleteUser(workspaceID int64, name string) error {
+	result, err := s.db.Exec(`DELETE FROM api_user WHERE workspace_id = ? AND name = ?`, workspaceID, name)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationDeleteUser, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationDeleteUser, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationDeleteUser, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
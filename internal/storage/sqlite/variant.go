@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"shorty/internal/storage"
+)
+
+// CountVariantClick records a redirect to a variant of an A/B split link
+func (s *Storage) CountVariantClick(variantID int64) error {
+	_, err := s.db.Exec(`UPDATE variant SET clicks = clicks + 1 WHERE id = ?`, variantID)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationVariant, err)
+	}
+
+	return nil
+}
+
+func insertVariants(tx *sql.Tx, urlID int64, variants []storage.Variant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	statement, err := tx.Prepare(`INSERT INTO variant(url_id, position, target, weight) VALUES(?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare statement: %w", err)
+	}
+	defer statement.Close()
+
+	for position, variant := range variants {
+		_, err = statement.Exec(urlID, position, variant.Target, variant.Weight)
+		if err != nil {
+			return fmt.Errorf("insert variant: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// variants returns the variants of the links by link id in their original order
+func (s *Storage) variants(urlIDs []int64) (map[int64][]storage.Variant, error) {
+	in, args := idList(urlIDs)
+	rows, err := s.db.Query(`SELECT url_id, id, target, weight, clicks FROM variant WHERE url_id IN `+in+` ORDER BY url_id, position`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query variants: %w", err)
+	}
+	defer rows.Close()
+
+	variants := make(map[int64][]storage.Variant)
+	for rows.Next() {
+		var (
+			urlID   int64
+			variant storage.Variant
+		)
+		err = rows.Scan(&urlID, &variant.ID, &variant.Target, &variant.Weight, &variant.Clicks)
+		if err != nil {
+			return nil, fmt.Errorf("scan variant: %w", err)
+		}
+		variants[urlID] = append(variants[urlID], variant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate variants: %w", err)
+	}
+
+	return variants, nil
+}
@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"shorty/internal/storage"
+	"time"
+)
+
+func (s *Storage) SaveWorkspace(workspace storage.Workspace) (int64, error) {
+	statement, err := s.db.Prepare(`
+	INSERT INTO workspace(name, max_links, max_requests, preview_all, created_at, updated_at)
+	VALUES(?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: prepare statement: %w", sqliteOperationSaveWorkspace, err)
+	}
+
+	timestamp := time.Now().Unix()
+	result, err := statement.Exec(workspace.Name, workspace.MaxLinks, workspace.MaxRequests, workspace.PreviewAll, timestamp, timestamp)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", sqliteOperationSaveWorkspace, storage.ErrWorkspaceAlreadyExists)
+		}
+		return 0, fmt.Errorf("%s: %w", sqliteOperationSaveWorkspace, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id %w", sqliteOperationSaveWorkspace, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetWorkspace(id int64) (storage.Workspace, error) {
+	var workspace storage.Workspace
+
+	statement, err := s.db.Prepare(`SELECT id, name, max_links, max_requests, preview_all FROM workspace WHERE id = ?`)
+	if err != nil {
+		return storage.Workspace{}, fmt.Errorf("%s: prepare statement: %w", sqliteOperationGetWorkspace, err)
+	}
+
+	err = statement.QueryRow(id).Scan(&workspace.ID, &workspace.Name, &workspace.MaxLinks, &workspace.MaxRequests, &workspace.PreviewAll)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.Workspace{}, storage.ErrWorkspaceNotFound
+		}
+		return storage.Workspace{}, fmt.Errorf("%s: execute statement %w", sqliteOperationGetWorkspace, err)
+	}
+
+	return workspace, nil
+}
+
+// ListWorkspaces returns the stored workspaces, the default workspace is not stored
+func (s *Storage) ListWorkspaces() ([]storage.Workspace, error) {
+	rows, err := s.db.Query(`SELECT id, name, max_links, max_requests, preview_all FROM workspace ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement %w", sqliteOperationListWorkspaces, err)
+	}
+	defer rows.Close()
+
+	var workspaces []storage.Workspace
+	for rows.Next() {
+		var workspace storage.Workspace
+		err = rows.Scan(&workspace.ID, &workspace.Name, &workspace.MaxLinks, &workspace.MaxRequests, &workspace.PreviewAll)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row %w", sqliteOperationListWorkspaces, err)
+		}
+		workspaces = append(workspaces, workspace)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows %w", sqliteOperationListWorkspaces, err)
+	}
+
+	return workspaces, nil
+}
+
+func (s *Storage) UpdateWorkspace(workspace storage.Workspace) error {
+	statement, err := s.db.Prepare(`
+	UPDATE workspace SET name = ?, max_links = ?, max_requests = ?, preview_all = ?, updated_at = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", sqliteOperationUpdateWorkspace, err)
+	}
+
+	result, err := statement.Exec(workspace.Name, workspace.MaxLinks, workspace.MaxRequests, workspace.PreviewAll, time.Now().Unix(), workspace.ID)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return fmt.Errorf("%s: %w", sqliteOperationUpdateWorkspace, storage.ErrWorkspaceAlreadyExists)
+		}
+		return fmt.Errorf("%s: execute statement %w", sqliteOperationUpdateWorkspace, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: failed to get affected rows %w", sqliteOperationUpdateWorkspace, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", sqliteOperationUpdateWorkspace, storage.ErrWorkspaceNotFound)
+	}
+
+	return nil
+}
+
+// CountRequest records an api request of the workspace and returns the number of its requests on the day of now
+func (s *Storage) CountRequest(workspaceID int64, now time.Time) (int64, error) {
+	var requests int64
+	err := s.db.QueryRow(`
+	INSERT INTO workspace_request(workspace_id, day, requests) VALUES(?, ?, 1)
+	ON CONFLICT(workspace_id, day) DO UPDATE SET requests = requests + 1
+	RETURNING requests`, workspaceID, now.UTC().Format(time.DateOnly)).Scan(&requests)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement %w", sqliteOperationCountRequest, err)
+	}
+
+	return requests, nil
+}
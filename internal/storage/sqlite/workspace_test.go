@@ -0,0 +1,159 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shorty/internal/storage"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkspaces(t *testing.T) {
+	s := newTestStorage(t)
+
+	id, err := s.SaveWorkspace(storage.Workspace{Name: "marketing", MaxLinks: 10})
+	require.NoError(t, err)
+	_, err = s.SaveWorkspace(storage.Workspace{Name: "marketing"})
+	assert.ErrorIs(t, err, storage.ErrWorkspaceAlreadyExists)
+
+	require.NoError(t, s.UpdateWorkspace(storage.Workspace{ID: id, Name: "marketing", MaxRequests: 100, PreviewAll: true}))
+	assert.ErrorIs(t, s.UpdateWorkspace(storage.Workspace{ID: id + 1, Name: "sales"}), storage.ErrWorkspaceNotFound)
+
+	workspace, err := s.GetWorkspace(id)
+	require.NoError(t, err)
+	assert.Equal(t, storage.Workspace{ID: id, Name: "marketing", MaxRequests: 100, PreviewAll: true}, workspace)
+
+	_, err = s.GetWorkspace(storage.DefaultWorkspace)
+	assert.ErrorIs(t, err, storage.ErrWorkspaceNotFound)
+
+	workspaces, err := s.ListWorkspaces()
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Workspace{workspace}, workspaces)
+}
+
+func TestUsers(t *testing.T) {
+	s := newTestStorage(t)
+
+	marketing, err := s.SaveWorkspace(storage.Workspace{Name: "marketing"})
+	require.NoError(t, err)
+	sales, err := s.SaveWorkspace(storage.Workspace{Name: "sales"})
+	require.NoError(t, err)
+
+	_, err = s.SaveUser(storage.User{WorkspaceID: marketing, Name: "alice", PasswordHash: "hash"})
+	require.NoError(t, err)
+	//names are unique across workspaces, they identify the workspace of a request
+	_, err = s.SaveUser(storage.User{WorkspaceID: sales, Name: "alice", PasswordHash: "hash"})
+	assert.ErrorIs(t, err, storage.ErrUserAlreadyExists)
+
+	user, err := s.GetUser("alice")
+	require.NoError(t, err)
+	assert.Equal(t, marketing, user.WorkspaceID)
+	assert.Equal(t, "hash", user.PasswordHash)
+
+	users, err := s.ListUsers(sales)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	assert.ErrorIs(t, s.DeleteUser(sales, "alice"), storage.ErrUserNotFound)
+	require.NoError(t, s.DeleteUser(marketing, "alice"))
+	_, err = s.GetUser("alice")
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+}
+
+func TestSaveLink_WorkspaceScope(t *testing.T) {
+	s := newTestStorage(t)
+
+	marketing, err := s.SaveWorkspace(storage.Workspace{Name: "marketing", MaxLinks: 2})
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{Alias: "promo", URL: "https://example.com/default"})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{WorkspaceID: marketing, Alias: "promo", URL: "https://example.com/marketing"})
+	require.NoError(t, err)
+
+	link, err := s.GetLink(marketing, "", "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/marketing", link.URL)
+	assert.Equal(t, marketing, link.WorkspaceID)
+
+	links, err := s.ListLinks(storage.LinkFilter{WorkspaceID: storage.DefaultWorkspace, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "https://example.com/default", links[0].URL)
+
+	_, err = s.SaveLink(storage.Link{WorkspaceID: marketing, Alias: "promo", URL: "https://example.com/again"})
+	assert.ErrorIs(t, err, storage.ErrURLAlreadyExists)
+
+	_, err = s.SaveLink(storage.Link{WorkspaceID: marketing, Alias: "sprng", URL: "https://example.com/spring"})
+	require.NoError(t, err)
+	_, err = s.SaveLink(storage.Link{WorkspaceID: marketing, Alias: "summr", URL: "https://example.com/summer"})
+	assert.ErrorIs(t, err, storage.ErrLinkQuotaExceeded)
+	//the default workspace has no quota
+	_, err = s.SaveLink(storage.Link{Alias: "summr", URL: "https://example.com/summer"})
+	require.NoError(t, err)
+
+	_, err = s.SaveLink(storage.Link{WorkspaceID: marketing + 1, Alias: "other", URL: "https://example.com"})
+	assert.ErrorIs(t, err, storage.ErrWorkspaceNotFound)
+
+	require.NoError(t, s.DeleteURL(storage.DefaultWorkspace, "", "promo"))
+	_, err = s.GetLink(marketing, "", "promo")
+	require.NoError(t, err)
+}
+
+func TestSaveLink_QuotaConcurrent(t *testing.T) {
+	const maxLinks = 25
+
+	s := newTestStorage(t)
+	marketing, err := s.SaveWorkspace(storage.Workspace{Name: "marketing", MaxLinks: maxLinks})
+	require.NoError(t, err)
+
+	var (
+		wg       sync.WaitGroup
+		saved    atomic.Int64
+		exceeded atomic.Int64
+	)
+
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := s.SaveLink(storage.Link{WorkspaceID: marketing, Alias: fmt.Sprintf("link%d", i), URL: "https://example.com"})
+			switch {
+			case err == nil:
+				saved.Add(1)
+			case errors.Is(err, storage.ErrLinkQuotaExceeded):
+				exceeded.Add(1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(maxLinks), saved.Load())
+	assert.Equal(t, int64(40-maxLinks), exceeded.Load())
+}
+
+func TestCountRequest(t *testing.T) {
+	s := newTestStorage(t)
+
+	day := time.Date(2030, 1, 1, 23, 0, 0, 0, time.UTC)
+	for want := int64(1); want <= 3; want++ {
+		requests, err := s.CountRequest(1, day)
+		require.NoError(t, err)
+		assert.Equal(t, want, requests)
+	}
+
+	requests, err := s.CountRequest(2, day)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), requests)
+
+	requests, err = s.CountRequest(1, day.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), requests)
+}
@@ -1,8 +1,280 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	ErrURLNotFound      = errors.New("url not found")
 	ErrURLAlreadyExists = errors.New("url already exists")
+	ErrLinkExhausted    = errors.New("link click limit reached")
+	ErrBatchAborted     = errors.New("batch aborted")
+
+	ErrTemplateNotFound      = errors.New("template not found")
+	ErrTemplateAlreadyExists = errors.New("template already exists")
+
+	ErrIdempotencyKeyExists = errors.New("idempotency key already used")
+
+	ErrDomainNotFound      = errors.New("domain not found")
+	ErrDomainAlreadyExists = errors.New("domain already exists")
+	ErrDomainInUse         = errors.New("domain has links")
+
+	ErrWorkspaceNotFound      = errors.New("workspace not found")
+	ErrWorkspaceAlreadyExists = errors.New("workspace already exists")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrUserAlreadyExists      = errors.New("user already exists")
+	ErrLinkQuotaExceeded      = errors.New("workspace link quota exceeded")
+
+	ErrUserQuotaExceeded = errors.New("user link quota exceeded")
+
+	ErrTagNotFound         = errors.New("tag not found")
+	ErrTagAlreadyExists    = errors.New("tag already exists")
+	ErrFolderNotFound      = errors.New("folder not found")
+	ErrFolderAlreadyExists = errors.New("folder already exists")
+	ErrFolderNotEmpty      = errors.New("folder has links or subfolders")
+	ErrFolderCycle         = errors.New("folder can not be moved into itself")
 )
+
+// DefaultWorkspace owns the links saved before workspaces existed and the links of the configured api user,
+// it is not stored and has no quotas
+const DefaultWorkspace int64 = 0
+
+// SaveResult is the outcome of saving one link of a batch
+type SaveResult struct {
+	ID  int64
+	Err error
+}
+
+// ConflictPolicy decides what an import does with a link whose alias is already taken
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing link
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing link with the imported one
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictFail rolls the whole import back
+	ConflictFail ConflictPolicy = "fail"
+)
+
+// ImportResult counts the outcomes of an import
+type ImportResult struct {
+	Created     int
+	Overwritten int
+	Skipped     int
+}
+
+// LinkError reports the link that failed an operation on several links
+type LinkError struct {
+	Alias string
+	Err   error
+}
+
+func (e *LinkError) Error() string {
+	return fmt.Sprintf("link %q: %v", e.Alias, e.Err)
+}
+
+func (e *LinkError) Unwrap() error {
+	return e.Err
+}
+
+// IdempotencyRecord is a link creation request remembered under its Idempotency-Key
+type IdempotencyRecord struct {
+	Key string
+	// Fingerprint identifies the request body the key was first used with
+	Fingerprint string
+	// Response is the saved response, nil while the first request is in progress
+	Response  []byte
+	CreatedAt time.Time
+}
+
+// Link is a short link together with its per-link redirect options
+type Link struct {
+	ID          int64
+	WorkspaceID int64
+	// Domain is the host of the custom domain the alias belongs to, empty for the default domain.
+	// Aliases are unique per domain
+	Domain string
+	Alias  string
+	URL    string
+	// OriginalURL is the target url as it was given before normalization
+	OriginalURL string
+	// Owner is the api user that created the link
+	Owner string
+	// Title is a human-readable name of the link shown on its preview page
+	Title string
+	// Preview shows visitors a page with the destination instead of redirecting them immediately
+	Preview bool
+	// OpenGraph overrides the preview of the link built by chats and social networks
+	OpenGraph OpenGraph
+	// Tags are the names of the tags of the link in alphabetical order, missing tags are created with the link
+	Tags []string
+	// FolderID is the folder the link is filed in, 0 if the link is not in a folder
+	FolderID int64
+
+	// MergeQuery forwards the query string of a redirect request to the target url
+	MergeQuery bool
+	// AppendPath appends the path following the alias to the target url path
+	AppendPath bool
+	// Template holds utm parameters applied to the target url, nil if the link has no template
+	Template *Template
+	// PasswordHash is a bcrypt hash of the link password, empty if the link is not protected
+	PasswordHash string
+	// MaxClicks is the number of redirects the link serves, 0 if the link is unlimited
+	MaxClicks int64
+	// ClicksLeft is the number of redirects left for a limited link
+	ClicksLeft int64
+	// NotBefore and NotAfter limit the time the link is active, zero values leave the window open
+	NotBefore time.Time
+	NotAfter  time.Time
+	// FallbackURL is the target outside the active window, empty if such requests are not found
+	FallbackURL string
+	// Rules send visitors to platform-specific targets, the first matching rule wins
+	Rules []Rule
+	// Variants split visitors between weighted targets, the link url is not used for redirects if set
+	Variants []Variant
+	// Locales map canonical language tags to targets negotiated with Accept-Language
+	Locales map[string]string
+	// Countries map ISO 3166-1 alpha-2 codes to targets of visitors from these countries
+	Countries map[string]string
+	// CountryClicks counts redirects per visitor country, only known countries are counted
+	CountryClicks map[string]int64
+	// Health is the result of the last liveness check of the link url, zero if the link was not checked
+	Health Health
+}
+
+// Health is the result of a liveness check of a link target
+type Health struct {
+	// Status is the http status of the response, 0 if no response was received
+	Status    int
+	Latency   time.Duration
+	CheckedAt time.Time
+	// Error describes why no response was received
+	Error string
+}
+
+// Broken reports whether the last check found the target unreachable or failing
+func (h Health) Broken() bool {
+	return !h.CheckedAt.IsZero() && (h.Error != "" || h.Status >= 400)
+}
+
+// LinkFilter selects a page of links, links are ordered by creation
+type LinkFilter struct {
+	WorkspaceID int64
+	Offset      int
+	Limit       int
+	// Broken selects only links whose last liveness check failed
+	Broken bool
+	// Tags selects only links that have all of the tags
+	Tags []string
+	// FolderID selects only links filed directly in the folder, 0 selects links of all folders
+	FolderID int64
+}
+
+// OpenGraph is the metadata served to link preview crawlers instead of a redirect, empty fields are omitted
+type OpenGraph struct {
+	Title       string
+	Description string
+	Image       string
+}
+
+// IsZero reports whether no metadata is set
+func (og OpenGraph) IsZero() bool {
+	return og == OpenGraph{}
+}
+
+// Rule redirects visitors of a platform to its own target, empty OS or Device match any value
+type Rule struct {
+	OS     string
+	Device string
+	Target string
+}
+
+// Variant is one of the weighted targets of an A/B split link
+type Variant struct {
+	ID     int64
+	Target string
+	Weight int
+	Clicks int64
+}
+
+// Domain is a custom host short links are served under in addition to the default one
+type Domain struct {
+	ID          int64
+	WorkspaceID int64
+	// Host is unique across workspaces, requests to it are served by the workspace of the domain
+	Host string
+	// DefaultURL is the target of the root and of unknown aliases of the domain, empty if they are not found
+	DefaultURL string
+}
+
+// Workspace separates the links, domains, templates and users of a department sharing the instance
+type Workspace struct {
+	ID   int64
+	Name string
+	// MaxLinks caps the number of links of the workspace, 0 if unlimited
+	MaxLinks int64
+	// MaxRequests caps the api requests of the workspace users per day, 0 if unlimited
+	MaxRequests int64
+	// PreviewAll shows the preview page before the redirect of every link of the workspace
+	PreviewAll bool
+}
+
+// User is an api user of a workspace, users authenticate with their name and password
+type User struct {
+	ID          int64
+	WorkspaceID int64
+	// Name is unique across workspaces
+	Name string
+	// PasswordHash is a bcrypt hash of the password
+	PasswordHash string
+}
+
+// LinkQuota caps the links an api user creates, zero values leave the caps open
+type LinkQuota struct {
+	// Daily is the number of links per day, days start at midnight UTC
+	Daily int64
+	Total int64
+}
+
+// QuotaUsage is the number of links an api user has created on the day and in total,
+// deleted links are still counted
+type QuotaUsage struct {
+	Daily int64
+	Total int64
+}
+
+// Tag labels links of a workspace, a link can have many tags and a tag many links
+type Tag struct {
+	ID          int64
+	WorkspaceID int64
+	// Name is unique in the workspace
+	Name string
+	// Links is the number of links with the tag
+	Links int64
+}
+
+// Folder files links of a workspace, folders are nested under their parents
+type Folder struct {
+	ID          int64
+	WorkspaceID int64
+	// ParentID is the folder the folder is nested in, 0 for a top level folder
+	ParentID int64
+	// Name is unique among the subfolders of the parent
+	Name string
+}
+
+// Template is a named set of utm parameters that can be attached to links
+type Template struct {
+	ID          int64
+	WorkspaceID int64
+	// Name is unique in the workspace
+	Name     string
+	Source   string
+	Medium   string
+	Campaign string
+	Term     string
+	Content  string
+}
@@ -0,0 +1,342 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"shorty/internal/storage"
+	"strconv"
+	"time"
+)
+
+// columns of the csv format in the order of export
+var columns = []string{
+	"alias", "url", "original_url", "merge_query", "append_path", "template", "password_hash", "max_clicks", "clicks_left",
+	"not_before", "not_after", "fallback_url", "rules", "variants", "locales", "countries", "title", "preview",
+	"og_title", "og_description", "og_image", "domain", "tags",
+}
+
+// Writer encodes links in one of the formats
+type Writer struct {
+	csv    *csv.Writer
+	json   *json.Encoder
+	header bool
+	//passwordHashes keeps the password hashes of protected links in the export
+	passwordHashes bool
+}
+
+func NewWriter(w io.Writer, format Format) *Writer {
+	if format == CSV {
+		return &Writer{csv: csv.NewWriter(w)}
+	}
+
+	return &Writer{json: json.NewEncoder(w)}
+}
+
+// IncludePasswordHashes exports the password hashes of protected links, they are left out by default
+func (w *Writer) IncludePasswordHashes() {
+	w.passwordHashes = true
+}
+
+// Write encodes a link, csv rows are buffered until Flush
+func (w *Writer) Write(link storage.Link) error {
+	record := FromLink(link)
+	if !w.passwordHashes {
+		record.PasswordHash = ""
+	}
+	if w.json != nil {
+		return w.json.Encode(record)
+	}
+
+	if !w.header {
+		err := w.csv.Write(columns)
+		if err != nil {
+			return fmt.Errorf("write header: %w", err)
+		}
+		w.header = true
+	}
+
+	row, err := record.row()
+	if err != nil {
+		return err
+	}
+
+	return w.csv.Write(row)
+}
+
+// Flush writes buffered csv rows, an empty csv export still gets its header
+func (w *Writer) Flush() error {
+	if w.csv == nil {
+		return nil
+	}
+
+	if !w.header {
+		err := w.csv.Write(columns)
+		if err != nil {
+			return fmt.Errorf("write header: %w", err)
+		}
+		w.header = true
+	}
+
+	w.csv.Flush()
+
+	return w.csv.Error()
+}
+
+// Reader decodes links in one of the formats
+type Reader struct {
+	csv  *csv.Reader
+	json *json.Decoder
+	//index of every csv column found in the header
+	columns map[string]int
+	records int
+}
+
+func NewReader(r io.Reader, format Format) *Reader {
+	if format == CSV {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+
+		return &Reader{csv: reader}
+	}
+
+	return &Reader{json: json.NewDecoder(r)}
+}
+
+// Read decodes and validates the next link, it returns io.EOF when no records are left
+func (r *Reader) Read() (storage.Link, error) {
+	var (
+		record Record
+		err    error
+	)
+
+	if r.json != nil {
+		err = r.json.Decode(&record)
+	} else {
+		record, err = r.readRow()
+	}
+
+	if errors.Is(err, io.EOF) {
+		return storage.Link{}, io.EOF
+	}
+
+	r.records++
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%w %d: %w", ErrInvalidRecord, r.records, err)
+	}
+
+	link, err := record.Link()
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("record %d: %w", r.records, err)
+	}
+
+	return link, nil
+}
+
+// ReadAll decodes every link of a stream
+func ReadAll(r io.Reader, format Format) ([]storage.Link, error) {
+	reader := NewReader(r, format)
+
+	var links []storage.Link
+	for {
+		link, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return links, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, link)
+	}
+}
+
+func (r *Reader) readRow() (Record, error) {
+	if r.columns == nil {
+		header, err := r.csv.Read()
+		if err != nil {
+			return Record{}, err
+		}
+
+		r.columns = make(map[string]int, len(header))
+		for i, column := range header {
+			r.columns[column] = i
+		}
+	}
+
+	row, err := r.csv.Read()
+	if err != nil {
+		return Record{}, err
+	}
+
+	cell := func(column string) string {
+		i, ok := r.columns[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+
+		return row[i]
+	}
+
+	record := Record{
+		Alias:        cell("alias"),
+		URL:          cell("url"),
+		OriginalURL:  cell("original_url"),
+		Template:     cell("template"),
+		PasswordHash: cell("password_hash"),
+		FallbackURL:  cell("fallback_url"),
+		Title:        cell("title"),
+
+		OGTitle:       cell("og_title"),
+		OGDescription: cell("og_description"),
+		OGImage:       cell("og_image"),
+
+		Domain: cell("domain"),
+	}
+
+	if record.MergeQuery, err = parseBool(cell("merge_query")); err != nil {
+		return Record{}, fmt.Errorf("merge_query: %w", err)
+	}
+
+	if record.AppendPath, err = parseBool(cell("append_path")); err != nil {
+		return Record{}, fmt.Errorf("append_path: %w", err)
+	}
+
+	if record.Preview, err = parseBool(cell("preview")); err != nil {
+		return Record{}, fmt.Errorf("preview: %w", err)
+	}
+
+	if record.MaxClicks, err = parseInt(cell("max_clicks")); err != nil {
+		return Record{}, fmt.Errorf("max_clicks: %w", err)
+	}
+
+	if value := cell("clicks_left"); value != "" {
+		clicksLeft, err := parseInt(value)
+		if err != nil {
+			return Record{}, fmt.Errorf("clicks_left: %w", err)
+		}
+		record.ClicksLeft = &clicksLeft
+	}
+
+	if record.NotBefore, err = parseTime(cell("not_before")); err != nil {
+		return Record{}, fmt.Errorf("not_before: %w", err)
+	}
+
+	if record.NotAfter, err = parseTime(cell("not_after")); err != nil {
+		return Record{}, fmt.Errorf("not_after: %w", err)
+	}
+
+	nested := map[string]any{
+		"rules":     &record.Rules,
+		"variants":  &record.Variants,
+		"locales":   &record.Locales,
+		"countries": &record.Countries,
+		"tags":      &record.Tags,
+	}
+	for column, value := range nested {
+		encoded := cell(column)
+		if encoded == "" {
+			continue
+		}
+
+		err = json.Unmarshal([]byte(encoded), value)
+		if err != nil {
+			return Record{}, fmt.Errorf("%s: %w", column, err)
+		}
+	}
+
+	return record, nil
+}
+
+// row encodes the record as csv cells in the order of columns
+func (rec Record) row() ([]string, error) {
+	var clicksLeft, notBefore, notAfter string
+	if rec.ClicksLeft != nil {
+		clicksLeft = strconv.FormatInt(*rec.ClicksLeft, 10)
+	}
+
+	if rec.NotBefore != nil {
+		notBefore = rec.NotBefore.Format(time.RFC3339)
+	}
+
+	if rec.NotAfter != nil {
+		notAfter = rec.NotAfter.Format(time.RFC3339)
+	}
+
+	nested := []struct {
+		column string
+		value  any
+		empty  bool
+	}{
+		{column: "rules", value: rec.Rules, empty: len(rec.Rules) == 0},
+		{column: "variants", value: rec.Variants, empty: len(rec.Variants) == 0},
+		{column: "locales", value: rec.Locales, empty: len(rec.Locales) == 0},
+		{column: "countries", value: rec.Countries, empty: len(rec.Countries) == 0},
+	}
+
+	row := []string{
+		rec.Alias, rec.URL, rec.OriginalURL, strconv.FormatBool(rec.MergeQuery), strconv.FormatBool(rec.AppendPath), rec.Template,
+		rec.PasswordHash, strconv.FormatInt(rec.MaxClicks, 10), clicksLeft, notBefore, notAfter, rec.FallbackURL,
+	}
+	for _, field := range nested {
+		if field.empty {
+			row = append(row, "")
+
+			continue
+		}
+
+		encoded, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s: %w", field.column, err)
+		}
+		row = append(row, string(encoded))
+	}
+	row = append(row, rec.Title, strconv.FormatBool(rec.Preview), rec.OGTitle, rec.OGDescription, rec.OGImage, rec.Domain)
+
+	tags := ""
+	if len(rec.Tags) > 0 {
+		encoded, err := json.Marshal(rec.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("encode tags: %w", err)
+		}
+		tags = string(encoded)
+	}
+	row = append(row, tags)
+
+	return row, nil
+}
+
+// parseBool treats an empty cell as false
+func parseBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(value)
+}
+
+// parseInt treats an empty cell as zero
+func parseInt(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// parseTime treats an empty cell as no time
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
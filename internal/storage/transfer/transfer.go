@@ -0,0 +1,227 @@
+// Package transfer encodes links as CSV or NDJSON records for import and export
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	resp "shorty/internal/pkg/api/response"
+	"shorty/internal/pkg/tagname"
+	"shorty/internal/storage"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrInvalidRecord = errors.New("invalid record")
+	ErrUnknownPolicy = errors.New("unknown conflict policy")
+)
+
+// Format is an encoding of link records
+type Format string
+
+const (
+	// CSV has a header row, nested fields are json-encoded cells
+	CSV Format = "csv"
+	// NDJSON has one json object per line
+	NDJSON Format = "ndjson"
+)
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(name); format {
+	case CSV, NDJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+	}
+}
+
+// ParseConflictPolicy returns the import conflict policy with the given name
+func ParseConflictPolicy(name string) (storage.ConflictPolicy, error) {
+	switch policy := storage.ConflictPolicy(name); policy {
+	case storage.ConflictSkip, storage.ConflictOverwrite, storage.ConflictFail:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownPolicy, name)
+	}
+}
+
+// ContentType returns the media type of the format
+func (f Format) ContentType() string {
+	if f == CSV {
+		return "text/csv"
+	}
+
+	return "application/x-ndjson"
+}
+
+// Record is a portable link, templates and tags are referenced by name, folders and click counters are not transferred
+type Record struct {
+	//Domain is the host of the custom domain of the link, empty for the default domain
+	Domain      string `json:"domain,omitempty"`
+	Alias       string `json:"alias" validate:"required"`
+	URL         string `json:"url" validate:"required"`
+	OriginalURL string `json:"original_url,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Preview     bool   `json:"preview,omitempty"`
+
+	OGTitle       string `json:"og_title,omitempty"`
+	OGDescription string `json:"og_description,omitempty"`
+	OGImage       string `json:"og_image,omitempty"`
+
+	MergeQuery   bool   `json:"merge_query,omitempty"`
+	AppendPath   bool   `json:"append_path,omitempty"`
+	Template     string `json:"template,omitempty"`
+	PasswordHash string `json:"password_hash,omitempty"`
+	MaxClicks    int64  `json:"max_clicks,omitempty"`
+	//ClicksLeft defaults to MaxClicks if omitted
+	ClicksLeft *int64 `json:"clicks_left,omitempty" validate:"omitempty,gte=0"`
+
+	NotBefore   *time.Time `json:"not_before,omitempty"`
+	NotAfter    *time.Time `json:"not_after,omitempty"`
+	FallbackURL string     `json:"fallback_url,omitempty"`
+
+	Rules     []Rule            `json:"rules,omitempty"`
+	Variants  []Variant         `json:"variants,omitempty"`
+	Locales   map[string]string `json:"locales,omitempty"`
+	Countries map[string]string `json:"countries,omitempty"`
+	//Tags are created in the workspace of the import if they are missing
+	Tags []string `json:"tags,omitempty"`
+}
+
+type Rule struct {
+	OS     string `json:"os,omitempty"`
+	Device string `json:"device,omitempty"`
+	Target string `json:"target"`
+}
+
+type Variant struct {
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+// FromLink converts a stored link to a record
+func FromLink(link storage.Link) Record {
+	record := Record{
+		Domain:      link.Domain,
+		Alias:       link.Alias,
+		URL:         link.URL,
+		OriginalURL: link.OriginalURL,
+		Title:       link.Title,
+		Preview:     link.Preview,
+		MergeQuery:  link.MergeQuery,
+
+		OGTitle:       link.OpenGraph.Title,
+		OGDescription: link.OpenGraph.Description,
+		OGImage:       link.OpenGraph.Image,
+
+		AppendPath:   link.AppendPath,
+		PasswordHash: link.PasswordHash,
+		MaxClicks:    link.MaxClicks,
+		FallbackURL:  link.FallbackURL,
+		Locales:      link.Locales,
+		Countries:    link.Countries,
+		Tags:         link.Tags,
+	}
+
+	if link.Template != nil {
+		record.Template = link.Template.Name
+	}
+
+	if link.MaxClicks > 0 {
+		clicksLeft := link.ClicksLeft
+		record.ClicksLeft = &clicksLeft
+	}
+
+	if !link.NotBefore.IsZero() {
+		notBefore := link.NotBefore
+		record.NotBefore = &notBefore
+	}
+
+	if !link.NotAfter.IsZero() {
+		notAfter := link.NotAfter
+		record.NotAfter = &notAfter
+	}
+
+	for _, rule := range link.Rules {
+		record.Rules = append(record.Rules, Rule{OS: rule.OS, Device: rule.Device, Target: rule.Target})
+	}
+
+	for _, variant := range link.Variants {
+		record.Variants = append(record.Variants, Variant{Target: variant.Target, Weight: variant.Weight})
+	}
+
+	return record
+}
+
+// Link checks that the record identifies a link and converts it to a link to be saved,
+// the fields of the link are validated by the import like the ones of a created link
+func (rec Record) Link() (storage.Link, error) {
+	rec.Tags = tagname.NormalizeAll(rec.Tags)
+	err := validator.New().Struct(rec)
+	if err != nil {
+		var validateErr validator.ValidationErrors
+		if errors.As(err, &validateErr) {
+			return storage.Link{}, fmt.Errorf("%w: %s", ErrInvalidRecord, resp.ValidationError(validateErr).Error)
+		}
+
+		return storage.Link{}, fmt.Errorf("%w: %w", ErrInvalidRecord, err)
+	}
+
+	link := storage.Link{
+		Domain:      strings.ToLower(rec.Domain),
+		Alias:       rec.Alias,
+		URL:         rec.URL,
+		OriginalURL: rec.OriginalURL,
+		Title:       rec.Title,
+		Preview:     rec.Preview,
+		MergeQuery:  rec.MergeQuery,
+
+		OpenGraph: storage.OpenGraph{Title: rec.OGTitle, Description: rec.OGDescription, Image: rec.OGImage},
+
+		AppendPath:   rec.AppendPath,
+		PasswordHash: rec.PasswordHash,
+		MaxClicks:    rec.MaxClicks,
+		ClicksLeft:   rec.MaxClicks,
+		FallbackURL:  rec.FallbackURL,
+		Locales:      rec.Locales,
+	}
+
+	//country lookups return upper case codes
+	for country, target := range rec.Countries {
+		if link.Countries == nil {
+			link.Countries = make(map[string]string, len(rec.Countries))
+		}
+		link.Countries[strings.ToUpper(country)] = target
+	}
+
+	if rec.Template != "" {
+		link.Template = &storage.Template{Name: rec.Template}
+	}
+
+	if rec.ClicksLeft != nil && *rec.ClicksLeft < rec.MaxClicks {
+		link.ClicksLeft = *rec.ClicksLeft
+	}
+
+	if rec.NotBefore != nil {
+		link.NotBefore = *rec.NotBefore
+	}
+
+	if rec.NotAfter != nil {
+		link.NotAfter = *rec.NotAfter
+	}
+
+	for _, rule := range rec.Rules {
+		link.Rules = append(link.Rules, storage.Rule{OS: rule.OS, Device: rule.Device, Target: rule.Target})
+	}
+
+	for _, variant := range rec.Variants {
+		link.Variants = append(link.Variants, storage.Variant{Target: variant.Target, Weight: variant.Weight})
+	}
+
+	link.Tags = rec.Tags
+
+	return link, nil
+}
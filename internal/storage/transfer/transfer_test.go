@@ -0,0 +1,159 @@
+package transfer
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shorty/internal/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLinks() []storage.Link {
+	return []storage.Link{
+		{
+			Alias:        "launc",
+			URL:          "https://example.com/launch",
+			Title:        "Launch, \"beta\"",
+			Preview:      true,
+			OpenGraph:    storage.OpenGraph{Title: "Launch", Description: "Line one\nline two", Image: "https://example.com/og.png"},
+			MergeQuery:   true,
+			AppendPath:   true,
+			Template:     &storage.Template{Name: "spring"},
+			PasswordHash: "$2a$10$hash",
+			MaxClicks:    3,
+			ClicksLeft:   1,
+			NotBefore:    time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC),
+			NotAfter:     time.Date(2030, 2, 1, 10, 0, 0, 0, time.UTC),
+			FallbackURL:  "https://example.com/soon",
+			Rules:        []storage.Rule{{OS: "ios", Target: "https://apps.apple.com/app/id1"}},
+			Variants: []storage.Variant{
+				{Target: "https://example.com/a", Weight: 1},
+				{Target: "https://example.com/b", Weight: 2},
+			},
+			Locales:   map[string]string{"de": "https://example.com/de"},
+			Countries: map[string]string{"FR": "https://example.fr"},
+			Tags:      []string{"launch", "q3 campaign"},
+		},
+		{
+			Domain:    "go.brand.com",
+			Alias:     "plain",
+			URL:       "https://example.com/plain?a=1,2",
+			MaxClicks: 0,
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []Format{CSV, NDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriter(&buf, format)
+			writer.IncludePasswordHashes()
+			for _, link := range testLinks() {
+				require.NoError(t, writer.Write(link))
+			}
+			require.NoError(t, writer.Flush())
+
+			links, err := ReadAll(&buf, format)
+			require.NoError(t, err)
+			assert.Equal(t, testLinks(), links)
+		})
+	}
+}
+
+func TestWriter_PasswordHashes(t *testing.T) {
+	for _, format := range []Format{CSV, NDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriter(&buf, format)
+			require.NoError(t, writer.Write(testLinks()[0]))
+			require.NoError(t, writer.Flush())
+			assert.NotContains(t, buf.String(), "$2a$10$hash")
+
+			links, err := ReadAll(&buf, format)
+			require.NoError(t, err)
+			require.Len(t, links, 1)
+			assert.Empty(t, links[0].PasswordHash)
+		})
+	}
+}
+
+func TestWriter_EmptyCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewWriter(&buf, CSV).Flush())
+	assert.Equal(t, strings.Join(columns, ",")+"\n", buf.String())
+}
+
+func TestReader(t *testing.T) {
+	tests := map[string]struct {
+		format   Format
+		input    string
+		expected []storage.Link
+		wantErr  string
+	}{
+		"CSV: column subset": {
+			format:   CSV,
+			input:    "url,alias,max_clicks,countries\nhttps://example.com,short,2,\"{\"\"de\"\":\"\"https://example.de\"\"}\"\n",
+			expected: []storage.Link{{Alias: "short", URL: "https://example.com", MaxClicks: 2, ClicksLeft: 2, Countries: map[string]string{"DE": "https://example.de"}}},
+		},
+		"CSV: missing url": {
+			format:  CSV,
+			input:   "alias,url\nshort,https://example.com\nbroken,\n",
+			wantErr: `record 2: invalid record: "URL" field is mandatory`,
+		},
+		"CSV: invalid number": {
+			format:  CSV,
+			input:   "alias,url,max_clicks\nshort,https://example.com,many\n",
+			wantErr: `invalid record 1: max_clicks: strconv.ParseInt: parsing "many": invalid syntax`,
+		},
+		"NDJSON": {
+			format:   NDJSON,
+			input:    `{"alias":"a","url":"https://example.com/a"}` + "\n" + `{"alias":"b","url":"https://example.com/b","template":"spring"}`,
+			expected: []storage.Link{{Alias: "a", URL: "https://example.com/a"}, {Alias: "b", URL: "https://example.com/b", Template: &storage.Template{Name: "spring"}}},
+		},
+		"NDJSON: missing alias": {
+			format:  NDJSON,
+			input:   `{"url":"https://example.com/a"}`,
+			wantErr: `record 1: invalid record: "Alias" field is mandatory`,
+		},
+		"NDJSON: tags": {
+			format:   NDJSON,
+			input:    `{"alias":"a","url":"https://example.com/a","tags":[" Launch","launch","Spring"]}`,
+			expected: []storage.Link{{Alias: "a", URL: "https://example.com/a", Tags: []string{"launch", "spring"}}},
+		},
+		"NDJSON: malformed": {
+			format:  NDJSON,
+			input:   `{"alias":`,
+			wantErr: "invalid record 1: unexpected EOF",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			links, err := ReadAll(strings.NewReader(tc.input), tc.format)
+			if tc.wantErr != "" {
+				require.ErrorIs(t, err, ErrInvalidRecord)
+				assert.EqualError(t, err, tc.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, links)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("csv")
+	require.NoError(t, err)
+	assert.Equal(t, CSV, format)
+
+	_, err = ParseFormat("xlsx")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	_, err = ParseConflictPolicy("merge")
+	assert.ErrorIs(t, err, ErrUnknownPolicy)
+}